@@ -170,6 +170,8 @@ func (a *Actor) Self() *pid.ProtectedPID {
 func (a *Actor) handleTermination() {
 	// close Actor's mailbox done channel so it can't accept any further messages
 	pid.ExtractPID(a.self).Mailbox().Dispose()
+	// remote nodes can't reach us anymore
+	defer pid.Release(pid.ExtractPID(a.self))
 
 	// check if we got a panic or just a normal return
 	switch r := recover().(type) {
@@ -181,7 +183,7 @@ func (a *Actor) handleTermination() {
 	case sysmsg.Shutdown:
 		// there's a case where user trap exit and receives the sysmsg.Shutdown msg then panics with the same msg
 		exit := sysmsg.Exit{
-			Who:      a.self,
+			Who:      pid.ExtractPID(a.self),
 			Parent:   r.Parent,
 			Reason:   sysmsg.Reason{Type: sysmsg.Kill, Details: "shutdown cmd received from supervisor"},
 		}
@@ -0,0 +1,104 @@
+package actor
+
+import (
+	"context"
+	"errors"
+	"github.com/hedisam/goactor/codec"
+	"github.com/hedisam/goactor/internal/mailbox"
+	"github.com/hedisam/goactor/internal/pid"
+	"github.com/hedisam/goactor/sysmsg"
+	"github.com/rs/xid"
+)
+
+var (
+	// ErrTimeout is returned when the response doesn't arrive before the deadline
+	ErrTimeout = errors.New("actor: timed out waiting for the response")
+	// ErrTargetDown is returned when the target terminates before responding
+	ErrTargetDown = errors.New("actor: target terminated before sending a response")
+	// ErrCancelled is returned by Ask when its context is cancelled before the response arrives
+	ErrCancelled = errors.New("actor: request cancelled")
+	// ErrDisposed is returned by a future's Recv when the future has been disposed
+	ErrDisposed = errors.New("actor: future disposed")
+)
+
+func init() {
+	codec.Register(Request{})
+	codec.Register(Response{})
+}
+
+// Request is the message sent by Ask, the target answers it with Reply
+type Request struct {
+	// ID correlates the request with its response
+	ID      string
+	ReplyTo *pid.ProtectedPID
+	Message interface{}
+}
+
+// Response is the answer to a Request, it carries the request's id
+type Response struct {
+	ID     string
+	Result interface{}
+}
+
+// Reply sends the result to the sender of the request
+func Reply(request Request, result interface{}) {
+	Send(request.ReplyTo, Response{ID: request.ID, Result: result})
+}
+
+// Ask sends the message to the actor wrapped in a Request and waits for the result of its Reply, until the context
+// is done. it fails with ErrTimeout once the context's deadline is exceeded, ErrCancelled if it's cancelled or
+// ErrTargetDown if the actor terminates first. the actor must answer with Reply: responses to other requests and any
+// other message are discarded, and the temporary mailbox the response is received in is disposed on return, so late
+// responses are dropped.
+func Ask(ctx context.Context, ppid *pid.ProtectedPID, message interface{}) (interface{}, error) {
+	if err := contextErr(ctx); err != nil {
+		return nil, err
+	}
+	future := NewFutureActor()
+	defer future.Dispose()
+	request := Request{ID: xid.New().String(), ReplyTo: future.Self(), Message: message}
+	future.Send(ppid, request)
+
+	receiver := future.pid.Mailbox().(interface {
+		ReceiveUntil(done <-chan struct{}, handler mailbox.MessageHandler)
+	})
+	for {
+		var result interface{}
+		var err error
+		done := true
+		receiver.ReceiveUntil(ctx.Done(), func(message interface{}) (loop bool) {
+			switch msg := message.(type) {
+			case Response:
+				if msg.ID != request.ID {
+					done = false
+					return false
+				}
+				result = msg.Result
+			case sysmsg.Exit:
+				err = ErrTargetDown
+			case sysmsg.Timeout:
+				err = contextErr(ctx)
+			case mailbox.ErrDisposed:
+				err = ErrCancelled
+			default:
+				// not an answer to the request
+				done = false
+			}
+			return false
+		})
+		if done {
+			return result, err
+		}
+	}
+}
+
+func contextErr(ctx context.Context) error {
+	switch ctx.Err() {
+	case nil:
+		return nil
+	case context.DeadlineExceeded:
+		return ErrTimeout
+	default:
+		return ErrCancelled
+	}
+}
@@ -0,0 +1,69 @@
+package actor
+
+import (
+	"context"
+	"errors"
+	"github.com/hedisam/goactor/internal/pid"
+	"testing"
+	"time"
+)
+
+func TestAskIgnoresStrayMessages(t *testing.T) {
+	// the target sends a stray message to the future and a response to another request before replying.
+	// a future has room for a single message, each one is sent once the previous has been received.
+	target := Spawn(func(actor *Actor) {
+		actor.Receive(func(message interface{}) (loop bool) {
+			request := message.(Request)
+			send := func(message interface{}) {
+				Send(request.ReplyTo, message)
+				for pid.ExtractPID(request.ReplyTo).Mailbox().Len() != 0 {
+					time.Sleep(time.Millisecond)
+				}
+			}
+			send("stray")
+			send(Response{ID: "another", Result: "wrong"})
+			Reply(request, "pong")
+			return false
+		})
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	result, err := Ask(ctx, target, "ping")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result != "pong" {
+		t.Fatalf("expected pong, got %v", result)
+	}
+}
+
+func TestAskPlainReplyTimesOut(t *testing.T) {
+	target := Spawn(func(actor *Actor) {
+		actor.Receive(func(message interface{}) (loop bool) {
+			Send(message.(Request).ReplyTo, "pong")
+			return true
+		})
+	})
+	defer Send(target, "stop")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if _, err := Ask(ctx, target, "ping"); err != ErrTimeout {
+		t.Fatalf("expected ErrTimeout, got %v", err)
+	}
+}
+
+func TestRecvDisposedFuture(t *testing.T) {
+	future := NewFutureActor()
+	future.Dispose()
+	if _, err := future.Recv(); !errors.Is(err, ErrDisposed) {
+		t.Fatalf("expected ErrDisposed, got %v", err)
+	}
+
+	future = NewFutureActor()
+	future.Dispose()
+	if _, err := future.RecvWithTimeout(time.Second); !errors.Is(err, ErrDisposed) {
+		t.Fatalf("expected ErrDisposed, got %v", err)
+	}
+}
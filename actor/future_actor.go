@@ -1,7 +1,6 @@
 package actor
 
 import (
-	"fmt"
 	"github.com/hedisam/goactor/internal/mailbox"
 	"github.com/hedisam/goactor/internal/pid"
 	"github.com/hedisam/goactor/sysmsg"
@@ -10,6 +9,8 @@ import (
 
 type futureActor struct {
 	pid pid.PID
+	// target is the actor monitored by Send, nil if there's none
+	target *pid.ProtectedPID
 }
 
 func NewFutureActor() *futureActor {
@@ -23,22 +24,48 @@ func (f *futureActor) Self() *pid.ProtectedPID {
 }
 
 func (f *futureActor) monitor(_pid *pid.ProtectedPID) {
-	request := sysmsg.Monitor{Parent: pid.ExtractPID(_pid)}
+	f.target = _pid
+	request := sysmsg.Monitor{Parent: f.pid}
 	sendSystemMessage(_pid, request)
 }
 
+func (f *futureActor) demonitor() {
+	if f.target == nil {
+		return
+	}
+	request := sysmsg.Monitor{Parent: f.pid, Revert: true}
+	sendSystemMessage(f.target, request)
+	f.target = nil
+}
+
+// received demonitors the target and releases the future, a future sent to another node is exported until then
+func (f *futureActor) received() {
+	f.demonitor()
+	pid.Release(f.pid)
+}
+
+// Dispose demonitors the target and disposes the mailbox, the messages sent to the future are dropped after
+func (f *futureActor) Dispose() {
+	f.demonitor()
+	f.pid.Mailbox().Dispose()
+	pid.Release(f.pid)
+}
+
 func (f *futureActor) Send(pid *pid.ProtectedPID, message interface{}) {
 	f.monitor(pid)
 	Send(pid, message)
 }
 
+// Recv waits for the response. it fails with ErrTargetDown if the actor monitored by Send terminates first, or
+// ErrDisposed if the future has been disposed.
 func (f *futureActor) Recv() (response interface{}, err error) {
+	defer f.received()
 	f.pid.Mailbox().Receive(func(message interface{}) (loop bool) {
 		switch msg := message.(type) {
 		case sysmsg.Exit:
-			err = fmt.Errorf("target Actor terminated before sending a response")
+			err = ErrTargetDown
 		case mailbox.ErrDisposed:
-			err = fmt.Errorf("%v", msg)
+			err = ErrDisposed
 		default:
 			response = msg
 		}
@@ -48,15 +75,17 @@ func (f *futureActor) Recv() (response interface{}, err error) {
 	return
 }
 
+// RecvWithTimeout waits for the response up to the duration, failing with ErrTimeout after
 func (f *futureActor) RecvWithTimeout(duration time.Duration) (response interface{}, err error) {
+	defer f.received()
 	f.pid.Mailbox().ReceiveWithTimeout(duration, func(message interface{}) (loop bool) {
 		switch msg := message.(type) {
 		case sysmsg.Exit:
-			err = fmt.Errorf("target Actor terminated before sending a response")
+			err = ErrTargetDown
 		case mailbox.ErrDisposed:
-			err = fmt.Errorf("%v", msg)
+			err = ErrDisposed
 		case sysmsg.Timeout:
-			err = fmt.Errorf("timeout")
+			err = ErrTimeout
 		default:
 			response = msg
 		}
@@ -0,0 +1,224 @@
+package actor
+
+import (
+	"fmt"
+	"github.com/hedisam/goactor/codec"
+	"github.com/hedisam/goactor/internal/mailbox"
+	"github.com/hedisam/goactor/internal/persist"
+	"github.com/hedisam/goactor/internal/pid"
+	"io"
+	"os"
+	"sort"
+	"sync"
+)
+
+// MailboxJournal is the log of a journaled mailbox. user messages are appended to it before they're queued and
+// consumed once the handler has returned, so when an actor crashes, the messages it's left unhandled are fed to
+// the next actor spawned with the same journal before any new message. system messages aren't journaled.
+type MailboxJournal = mailbox.Journal
+
+// JournalRecord is a message of a mailbox journal
+type JournalRecord = mailbox.Record
+
+// SpawnJournaled spawns the actor with a journaled mailbox. a journal must only be used by one actor at a time.
+func SpawnJournaled(journal MailboxJournal, fn Func, args ...interface{}) *pid.ProtectedPID {
+	actor := createJournaledActor(journal, args...)
+	spawn(fn, actor)
+	return actor.Self()
+}
+
+// SpawnLinkJournaled spawns the actor with a journaled mailbox, linked to the actor
+func (a *Actor) SpawnLinkJournaled(journal MailboxJournal, fn Func, args ...interface{}) *pid.ProtectedPID {
+	actor := createJournaledActor(journal, args...)
+	actor.link(pid.ExtractPID(a.self))
+	spawn(fn, actor)
+	a.link(pid.ExtractPID(actor.Self()))
+	return actor.Self()
+}
+
+func createJournaledActor(journal MailboxJournal, args ...interface{}) *Actor {
+	return createActorWith(func(utils *mailbox.ActorUtils) pid.PID {
+		return pid.NewJournaledPID(utils, journal)
+	}, args...)
+}
+
+// compactAfter is the number of consumed messages after which the journal file is rewritten
+const compactAfter = 1000
+
+// journalEntry is a record of the journal file, either a message, the consumption of one or its redelivery
+type journalEntry struct {
+	Seq         uint64
+	Consumed    bool
+	Redelivered bool
+	Message     interface{}
+	// Redeliveries carries the redeliveries of a message over a compaction
+	Redeliveries int
+}
+
+func init() {
+	codec.Register(journalEntry{})
+}
+
+// FileMailboxJournal journals the messages to an append only file. messages are encoded with codec.Gob, so their
+// types must be registered with codec.Register. appended messages are synced to disk, consumptions and
+// redeliveries aren't, so a crash might feed a handled message once more, never lose one.
+type FileMailboxJournal struct {
+	path string
+
+	mu         sync.Mutex
+	file       *os.File
+	next       uint64
+	unconsumed map[uint64]interface{}
+	// redeliveries of the unconsumed messages that have been redelivered
+	redeliveries map[uint64]int
+	consumed     int
+}
+
+// OpenFileMailboxJournal opens the journal file, creating it if it doesn't exist. the file is truncated after its
+// last complete record.
+func OpenFileMailboxJournal(path string) (*FileMailboxJournal, error) {
+	j := &FileMailboxJournal{
+		path:         path,
+		next:         1,
+		unconsumed:   make(map[uint64]interface{}),
+		redeliveries: make(map[uint64]int),
+	}
+	size, err := j.load()
+	if err != nil {
+		return nil, fmt.Errorf("actor: could not read mailbox journal %s: %v", path, err)
+	}
+	j.file, err = os.OpenFile(path, os.O_WRONLY|os.O_CREATE, 0644)
+	if err != nil {
+		return nil, err
+	}
+	if err := j.file.Truncate(size); err != nil {
+		_ = j.file.Close()
+		return nil, err
+	}
+	if _, err := j.file.Seek(size, io.SeekStart); err != nil {
+		_ = j.file.Close()
+		return nil, err
+	}
+	return j, nil
+}
+
+func (j *FileMailboxJournal) Append(message interface{}) (uint64, error) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	seq := j.next
+	if err := j.write(journalEntry{Seq: seq, Message: message}, true); err != nil {
+		return 0, err
+	}
+	j.next++
+	j.unconsumed[seq] = message
+	return seq, nil
+}
+
+func (j *FileMailboxJournal) Consume(seq uint64) error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if _, ok := j.unconsumed[seq]; !ok {
+		return nil
+	}
+	delete(j.unconsumed, seq)
+	delete(j.redeliveries, seq)
+	j.consumed++
+	if j.consumed >= compactAfter {
+		return j.compact()
+	}
+	return j.write(journalEntry{Seq: seq, Consumed: true}, false)
+}
+
+func (j *FileMailboxJournal) Redeliver(seq uint64) (int, error) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if _, ok := j.unconsumed[seq]; !ok {
+		return 0, nil
+	}
+	j.redeliveries[seq]++
+	return j.redeliveries[seq], j.write(journalEntry{Seq: seq, Redelivered: true}, false)
+}
+
+func (j *FileMailboxJournal) Unconsumed() ([]JournalRecord, error) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return j.records(), nil
+}
+
+// Close closes the journal file
+func (j *FileMailboxJournal) Close() error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return j.file.Close()
+}
+
+// records must be called with the lock held
+func (j *FileMailboxJournal) records() []JournalRecord {
+	records := make([]JournalRecord, 0, len(j.unconsumed))
+	for seq, message := range j.unconsumed {
+		records = append(records, JournalRecord{Seq: seq, Message: message})
+	}
+	sort.Slice(records, func(a, b int) bool {
+		return records[a].Seq < records[b].Seq
+	})
+	return records
+}
+
+// write must be called with the lock held
+func (j *FileMailboxJournal) write(entry journalEntry, sync bool) error {
+	if err := persist.WriteRecord(j.file, entry); err != nil {
+		return err
+	}
+	if !sync {
+		return nil
+	}
+	return j.file.Sync()
+}
+
+// compact rewrites the file with the unconsumed messages only, replacing it atomically.
+// it must be called with the lock held.
+func (j *FileMailboxJournal) compact() error {
+	f, err := persist.Rewrite(j.path, func(w io.Writer) error {
+		for _, r := range j.records() {
+			entry := journalEntry{Seq: r.Seq, Message: r.Message, Redeliveries: j.redeliveries[r.Seq]}
+			if err := persist.WriteRecord(w, entry); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		// keep on appending to the old file, the consumptions since the last compaction are written next time
+		return err
+	}
+	_ = j.file.Close()
+	j.file = f
+	j.consumed = 0
+	return nil
+}
+
+// load reads the file and returns the size of its complete records
+func (j *FileMailboxJournal) load() (int64, error) {
+	return persist.Scan(j.path, func(value interface{}) error {
+		entry, ok := value.(journalEntry)
+		if !ok {
+			return fmt.Errorf("invalid record")
+		}
+		switch {
+		case entry.Consumed:
+			delete(j.unconsumed, entry.Seq)
+			delete(j.redeliveries, entry.Seq)
+		case entry.Redelivered:
+			j.redeliveries[entry.Seq]++
+		default:
+			j.unconsumed[entry.Seq] = entry.Message
+			if entry.Redeliveries > 0 {
+				j.redeliveries[entry.Seq] = entry.Redeliveries
+			}
+		}
+		if entry.Seq >= j.next {
+			j.next = entry.Seq + 1
+		}
+		return nil
+	})
+}
@@ -0,0 +1,80 @@
+package actor
+
+import (
+	"github.com/hedisam/goactor/internal/pid"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// terminated returns the pid of an actor that has terminated and can't be resolved anymore
+func terminated(t *testing.T) *pid.ProtectedPID {
+	target := Spawn(func(actor *Actor) {})
+	future := NewFutureActor()
+	future.Send(target, "ping")
+	if _, err := future.RecvWithTimeout(time.Second); err != ErrTargetDown {
+		t.Fatalf("expected the actor to terminate, got %v", err)
+	}
+	id := pid.EncodeRef(target).ID
+	deadline := time.Now().Add(time.Second)
+	for {
+		if _, ok := pid.Lookup(id); !ok {
+			return target
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("the terminated actor's pid has not been released")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestFileMailboxJournalReopenWithDeadPID(t *testing.T) {
+	dir, err := ioutil.TempDir("", "journal")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "mailbox")
+
+	dead := terminated(t)
+	journal, err := OpenFileMailboxJournal(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := journal.Append(Request{ID: "1", ReplyTo: dead, Message: "ping"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := journal.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	journal, err = OpenFileMailboxJournal(path)
+	if err != nil {
+		t.Fatalf("could not reopen the journal: %v", err)
+	}
+	defer journal.Close()
+	records, err := journal.Unconsumed()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 1 {
+		t.Fatalf("expected 1 unconsumed record, got %d", len(records))
+	}
+	request, ok := records[0].Message.(Request)
+	if !ok || request.ID != "1" || request.ReplyTo == nil {
+		t.Fatalf("unexpected record %#v", records[0].Message)
+	}
+	if got, want := pid.EncodeRef(request.ReplyTo), pid.EncodeRef(dead); got != want {
+		t.Fatalf("the dead pid is encoded as %v, expected %v", got, want)
+	}
+
+	// replying to it is dropped, monitoring it fails right away
+	Reply(request, "pong")
+	future := NewFutureActor()
+	future.Send(request.ReplyTo, "ping")
+	if _, err := future.RecvWithTimeout(time.Second); err != ErrTargetDown {
+		t.Fatalf("expected ErrTargetDown from the dead pid, got %v", err)
+	}
+}
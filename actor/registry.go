@@ -1,23 +1,342 @@
 package actor
 
-import "github.com/hedisam/goactor/internal/pid"
+import (
+	"fmt"
+	"github.com/hedisam/goactor/internal/pid"
+	"hash/fnv"
+	"runtime"
+	"sync"
+)
 
-var myPID *pid.ProtectedPID
+const (
+	// UniqueKeys registries allow one actor per key
+	UniqueKeys RegistryKeys = iota
+	// DuplicateKeys registries allow any number of actors per key
+	DuplicateKeys
+)
 
-type registryMap map[string]*pid.ProtectedPID
+var (
+	// defaultRegistry is the registry used by Register, Unregister and WhereIs
+	defaultRegistry *Registry
+	// watcher watches the registered actors of every registry
+	watcher *pid.ProtectedPID
+)
 
-type cmdRegister struct {
-	name string
-	pid  *pid.ProtectedPID
+type RegistryKeys int32
+
+// RegistryEntry is an actor registered under a key along with its value
+type RegistryEntry struct {
+	Key   string
+	PID   *pid.ProtectedPID
+	Value interface{}
 }
-type cmdUnregister struct {
-	name string
+
+// Registry is a local key-value process storage. keys are spread across partitions, each one guarded by its own
+// read-write lock, so lookups run concurrently and never go through an actor's mailbox.
+// entries are removed automatically when their actor terminates.
+type Registry struct {
+	keys       RegistryKeys
+	partitions []*partition
 }
-type cmdGet struct {
-	name   string
-	sender *pid.ProtectedPID
+
+type entry struct {
+	pid   pid.PID
+	value interface{}
+}
+
+type partition struct {
+	sync.RWMutex
+	entries map[string][]entry
+	// byPID indexes the keys each actor has registered in this partition
+	byPID map[pid.PID]map[string]struct{}
 }
 
 func init() {
-	myPID = Spawn(registry)
+	watcher = Spawn(registryWatcher)
+	defaultRegistry = NewRegistry(UniqueKeys, runtime.NumCPU())
+}
+
+// DefaultRegistry returns the unique keys registry that backs Register, Unregister and WhereIs
+func DefaultRegistry() *Registry {
+	return defaultRegistry
+}
+
+// NewRegistry creates a registry with the given keys mode. partitions less than one defaults to one.
+func NewRegistry(keys RegistryKeys, partitions int) *Registry {
+	if partitions < 1 {
+		partitions = 1
+	}
+	r := &Registry{
+		keys:       keys,
+		partitions: make([]*partition, partitions),
+	}
+	for i := range r.partitions {
+		r.partitions[i] = &partition{
+			entries: make(map[string][]entry),
+			byPID:   make(map[pid.PID]map[string]struct{}),
+		}
+	}
+	return r
+}
+
+// Keys returns the registry's keys mode
+func (r *Registry) Keys() RegistryKeys {
+	return r.keys
+}
+
+// Register registers the actor under the key with the given value.
+// in a unique keys registry it fails if the key is already taken by another actor.
+// in a duplicate keys registry registering the same actor under the same key again replaces its value.
+func (r *Registry) Register(key string, ppid *pid.ProtectedPID, value interface{}) error {
+	_pid := pid.ExtractPID(ppid)
+	p := r.partition(key)
+
+	p.Lock()
+	entries := p.entries[key]
+	switch r.keys {
+	case UniqueKeys:
+		if len(entries) != 0 && entries[0].pid != _pid {
+			p.Unlock()
+			return fmt.Errorf("key %s already registered", key)
+		}
+		p.entries[key] = []entry{{pid: _pid, value: value}}
+	default:
+		replaced := false
+		for i := range entries {
+			if entries[i].pid == _pid {
+				entries[i].value = value
+				replaced = true
+				break
+			}
+		}
+		if !replaced {
+			p.entries[key] = append(entries, entry{pid: _pid, value: value})
+		}
+	}
+	keys, ok := p.byPID[_pid]
+	if !ok {
+		keys = make(map[string]struct{})
+		p.byPID[_pid] = keys
+	}
+	keys[key] = struct{}{}
+	p.Unlock()
+
+	Send(watcher, cmdCheck{registry: r, pid: _pid})
+	return nil
+}
+
+// Replace registers the actor under the key with the given value. in a unique keys registry it replaces the
+// actor registered under the key, in a duplicate keys registry it's the same as Register.
+func (r *Registry) Replace(key string, ppid *pid.ProtectedPID, value interface{}) {
+	_pid := pid.ExtractPID(ppid)
+	if r.keys != UniqueKeys {
+		_ = r.Register(key, ppid, value)
+		return
+	}
+	p := r.partition(key)
+
+	p.Lock()
+	var replaced pid.PID
+	if entries := p.entries[key]; len(entries) != 0 && entries[0].pid != _pid {
+		replaced = entries[0].pid
+		p.unindex(key, replaced)
+	}
+	p.entries[key] = []entry{{pid: _pid, value: value}}
+	keys, ok := p.byPID[_pid]
+	if !ok {
+		keys = make(map[string]struct{})
+		p.byPID[_pid] = keys
+	}
+	keys[key] = struct{}{}
+	p.Unlock()
+
+	Send(watcher, cmdCheck{registry: r, pid: _pid})
+	if replaced != nil {
+		Send(watcher, cmdCheck{registry: r, pid: replaced})
+	}
+}
+
+// Unregister removes the actor's entry under the key
+func (r *Registry) Unregister(key string, ppid *pid.ProtectedPID) {
+	_pid := pid.ExtractPID(ppid)
+	p := r.partition(key)
+	p.Lock()
+	p.remove(key, _pid)
+	p.Unlock()
+	Send(watcher, cmdCheck{registry: r, pid: _pid})
+}
+
+// UpdateValue replaces the value of the actor's entry under the key
+func (r *Registry) UpdateValue(key string, ppid *pid.ProtectedPID, update func(value interface{}) interface{}) error {
+	_pid := pid.ExtractPID(ppid)
+	p := r.partition(key)
+
+	p.Lock()
+	defer p.Unlock()
+	entries := p.entries[key]
+	for i := range entries {
+		if entries[i].pid == _pid {
+			entries[i].value = update(entries[i].value)
+			return nil
+		}
+	}
+	return fmt.Errorf("key %s is not registered by the actor", key)
+}
+
+// Lookup returns the entries registered under the key
+func (r *Registry) Lookup(key string) []RegistryEntry {
+	return r.Match(key, nil)
+}
+
+// Match returns the entries under the key whose value is matched. a nil match function matches every value.
+func (r *Registry) Match(key string, match func(value interface{}) bool) []RegistryEntry {
+	p := r.partition(key)
+	p.RLock()
+	defer p.RUnlock()
+
+	entries := p.entries[key]
+	result := make([]RegistryEntry, 0, len(entries))
+	for _, e := range entries {
+		if match == nil || match(e.value) {
+			result = append(result, RegistryEntry{Key: key, PID: pid.NewProtectedPID(e.pid), Value: e.value})
+		}
+	}
+	return result
+}
+
+// Select returns all the entries, across every key, that are selected by the selector
+func (r *Registry) Select(selector func(entry RegistryEntry) bool) []RegistryEntry {
+	var result []RegistryEntry
+	for _, p := range r.partitions {
+		p.RLock()
+		for key, entries := range p.entries {
+			for _, e := range entries {
+				re := RegistryEntry{Key: key, PID: pid.NewProtectedPID(e.pid), Value: e.value}
+				if selector(re) {
+					result = append(result, re)
+				}
+			}
+		}
+		p.RUnlock()
+	}
+	return result
+}
+
+// Dispatch invokes fn with the entries registered under the key. fn is not called if there are no entries.
+// it's useful for sending a message to every actor under a key of a duplicate keys registry.
+func (r *Registry) Dispatch(key string, fn func(entries []RegistryEntry)) {
+	entries := r.Lookup(key)
+	if len(entries) == 0 {
+		return
+	}
+	fn(entries)
+}
+
+// KeysOf returns the keys the actor is registered under
+func (r *Registry) KeysOf(ppid *pid.ProtectedPID) []string {
+	_pid := pid.ExtractPID(ppid)
+	var keys []string
+	for _, p := range r.partitions {
+		p.RLock()
+		for key := range p.byPID[_pid] {
+			keys = append(keys, key)
+		}
+		p.RUnlock()
+	}
+	return keys
+}
+
+// Count returns the number of entries in the registry
+func (r *Registry) Count() int {
+	count := 0
+	for _, p := range r.partitions {
+		p.RLock()
+		for _, entries := range p.entries {
+			count += len(entries)
+		}
+		p.RUnlock()
+	}
+	return count
+}
+
+// Close stops watching the registry's actors. entries of actors terminating afterwards are not removed.
+func (r *Registry) Close() {
+	Send(watcher, cmdForget{registry: r})
+}
+
+// unregisterKey removes every entry under the key
+func (r *Registry) unregisterKey(key string) {
+	p := r.partition(key)
+	p.Lock()
+	entries := p.entries[key]
+	for _, e := range entries {
+		p.unindex(key, e.pid)
+	}
+	delete(p.entries, key)
+	p.Unlock()
+	for _, e := range entries {
+		Send(watcher, cmdCheck{registry: r, pid: e.pid})
+	}
+}
+
+// registered reports whether the actor has any entry in the registry
+func (r *Registry) registered(_pid pid.PID) bool {
+	for _, p := range r.partitions {
+		p.RLock()
+		_, ok := p.byPID[_pid]
+		p.RUnlock()
+		if ok {
+			return true
+		}
+	}
+	return false
+}
+
+// unregisterPID removes every entry of a terminated actor
+func (r *Registry) unregisterPID(_pid pid.PID) {
+	for _, p := range r.partitions {
+		p.Lock()
+		for key := range p.byPID[_pid] {
+			p.remove(key, _pid)
+		}
+		p.Unlock()
+	}
+}
+
+func (r *Registry) partition(key string) *partition {
+	if len(r.partitions) == 1 {
+		return r.partitions[0]
+	}
+	h := fnv.New32a()
+	_, _ = h.Write([]byte(key))
+	return r.partitions[h.Sum32()%uint32(len(r.partitions))]
+}
+
+// remove must be called while holding the lock
+func (p *partition) remove(key string, _pid pid.PID) {
+	entries := p.entries[key]
+	for i := range entries {
+		if entries[i].pid == _pid {
+			entries = append(entries[:i], entries[i+1:]...)
+			break
+		}
+	}
+	if len(entries) == 0 {
+		delete(p.entries, key)
+	} else {
+		p.entries[key] = entries
+	}
+	p.unindex(key, _pid)
+}
+
+// unindex must be called while holding the lock
+func (p *partition) unindex(key string, _pid pid.PID) {
+	keys, ok := p.byPID[_pid]
+	if !ok {
+		return
+	}
+	delete(keys, key)
+	if len(keys) == 0 {
+		delete(p.byPID, _pid)
+	}
 }
@@ -2,35 +2,88 @@ package actor
 
 import (
 	"github.com/hedisam/goactor/internal/pid"
+	"github.com/hedisam/goactor/sysmsg"
 )
 
-func Register(name string, pid *pid.ProtectedPID) {
-	Send(myPID, cmdRegister{name: name, pid: pid})
+// cmdCheck tells the watcher to monitor the actor if it's registered in the registry, or to stop monitoring it for
+// the registry if it's not. it's checked rather than passed along, so the order the checks arrive in doesn't matter.
+type cmdCheck struct {
+	registry *Registry
+	pid      pid.PID
 }
 
+// cmdForget stops watching the actors of a closed registry
+type cmdForget struct {
+	registry *Registry
+}
+
+// Register registers the actor under the name in the default registry.
+// it fails if the name is already taken by another actor, ForceRegister takes the name over instead.
+func Register(name string, ppid *pid.ProtectedPID) error {
+	return defaultRegistry.Register(name, ppid, nil)
+}
+
+// ForceRegister registers the actor under the name in the default registry, replacing the actor registered
+// under it if there's one
+func ForceRegister(name string, ppid *pid.ProtectedPID) {
+	defaultRegistry.Replace(name, ppid, nil)
+}
+
+// Unregister removes the name from the default registry
 func Unregister(name string) {
-	Send(myPID, cmdUnregister{name: name})
+	defaultRegistry.unregisterKey(name)
 }
 
-func WhereIs(name string) (ppid *pid.ProtectedPID) {
-	future := NewFutureActor()
-	Send(myPID, cmdGet{name: name, sender: future.Self()})
-	result, _ := future.Recv()
-	ppid, _ = result.(*pid.ProtectedPID)
-	return
+// WhereIs returns the actor registered under the name in the default registry, nil if there's none
+func WhereIs(name string) *pid.ProtectedPID {
+	entries := defaultRegistry.Lookup(name)
+	if len(entries) == 0 {
+		return nil
+	}
+	return entries[0].PID
 }
 
-func registry(act *Actor) {
-	repo := registryMap{}
+// registryWatcher monitors the registered actors and removes their entries from the registries they're
+// registered in when they terminate. one watcher serves every registry, so a registry doesn't cost an actor.
+func registryWatcher(act *Actor) {
+	// the registries by actor
+	watching := make(map[pid.PID]map[*Registry]struct{})
 
 	act.Receive(func(message interface{}) (loop bool) {
-		switch cmd := message.(type) {
-		case cmdRegister:
-			repo[cmd.name] = cmd.pid
-		case cmdUnregister:
-			repo[cmd.name] = nil
-		case cmdGet:
-			Send(cmd.sender, repo[cmd.name])
+		switch msg := message.(type) {
+		case cmdCheck:
+			registries, ok := watching[msg.pid]
+			if msg.registry.registered(msg.pid) {
+				if !ok {
+					registries = make(map[*Registry]struct{})
+					watching[msg.pid] = registries
+					act.Monitor(pid.NewProtectedPID(msg.pid))
+				}
+				registries[msg.registry] = struct{}{}
+				return true
+			}
+			delete(registries, msg.registry)
+			if ok && len(registries) == 0 {
+				delete(watching, msg.pid)
+				act.Demonitor(pid.NewProtectedPID(msg.pid))
+			}
+		case sysmsg.Exit:
+			who, ok := msg.Who.(pid.PID)
+			if !ok {
+				return true
+			}
+			for registry := range watching[who] {
+				registry.unregisterPID(who)
+			}
+			delete(watching, who)
+		case cmdForget:
+			for p, registries := range watching {
+				delete(registries, msg.registry)
+				if len(registries) == 0 {
+					delete(watching, p)
+					act.Demonitor(pid.NewProtectedPID(p))
+				}
+			}
 		}
 		return true
 	})
@@ -0,0 +1,149 @@
+package actor
+
+import (
+	"github.com/hedisam/goactor/internal/pid"
+	"testing"
+	"time"
+)
+
+// spawnIdle spawns an actor answering a chan int with the number of actors monitoring it, anything else stops it
+func spawnIdle() *pid.ProtectedPID {
+	return Spawn(func(actor *Actor) {
+		actor.Receive(func(message interface{}) (loop bool) {
+			monitors, ok := message.(chan int)
+			if ok {
+				monitors <- len(actor.monitorActors)
+			}
+			return ok
+		})
+	})
+}
+
+func monitorsOf(ppid *pid.ProtectedPID) int {
+	monitors := make(chan int, 1)
+	Send(ppid, monitors)
+	return <-monitors
+}
+
+func waitFor(t *testing.T, what string, condition func() bool) {
+	deadline := time.Now().Add(time.Second)
+	for !condition() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestUniqueKeys(t *testing.T) {
+	r := NewRegistry(UniqueKeys, 4)
+	defer r.Close()
+	a, b := spawnIdle(), spawnIdle()
+	defer Send(a, "stop")
+	defer Send(b, "stop")
+
+	if err := r.Register("key", a, 1); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Register("key", b, 2); err == nil {
+		t.Fatal("registered a second actor under a unique key")
+	}
+	if err := r.Register("key", a, 3); err != nil {
+		t.Fatalf("could not register the same actor again: %v", err)
+	}
+	entries := r.Lookup("key")
+	if len(entries) != 1 || pid.ExtractPID(entries[0].PID) != pid.ExtractPID(a) || entries[0].Value != 3 {
+		t.Fatalf("unexpected entries %+v", entries)
+	}
+
+	r.Replace("key", b, 4)
+	entries = r.Lookup("key")
+	if len(entries) != 1 || pid.ExtractPID(entries[0].PID) != pid.ExtractPID(b) || entries[0].Value != 4 {
+		t.Fatalf("unexpected entries after the replacement %+v", entries)
+	}
+	if keys := r.KeysOf(a); len(keys) != 0 {
+		t.Fatalf("the replaced actor still has the keys %v", keys)
+	}
+}
+
+func TestDuplicateKeys(t *testing.T) {
+	r := NewRegistry(DuplicateKeys, 4)
+	defer r.Close()
+	a, b := spawnIdle(), spawnIdle()
+	defer Send(a, "stop")
+	defer Send(b, "stop")
+
+	for _, ppid := range []*pid.ProtectedPID{a, b, a} {
+		if err := r.Register("key", ppid, nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if entries := r.Lookup("key"); len(entries) != 2 {
+		t.Fatalf("expected 2 entries, got %+v", entries)
+	}
+	r.Unregister("key", a)
+	entries := r.Lookup("key")
+	if len(entries) != 1 || pid.ExtractPID(entries[0].PID) != pid.ExtractPID(b) {
+		t.Fatalf("unexpected entries after unregistering %+v", entries)
+	}
+}
+
+func TestRemovalOnExit(t *testing.T) {
+	unique, duplicate := NewRegistry(UniqueKeys, 4), NewRegistry(DuplicateKeys, 4)
+	defer unique.Close()
+	defer duplicate.Close()
+	a := spawnIdle()
+
+	for _, key := range []string{"a", "b", "c"} {
+		if err := unique.Register(key, a, nil); err != nil {
+			t.Fatal(err)
+		}
+		if err := duplicate.Register(key, a, nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+	Send(a, "stop")
+	waitFor(t, "the entries of the terminated actor to be removed", func() bool {
+		return unique.Count() == 0 && duplicate.Count() == 0
+	})
+}
+
+func TestUnregisterDemonitors(t *testing.T) {
+	r := NewRegistry(DuplicateKeys, 4)
+	defer r.Close()
+	a := spawnIdle()
+	defer Send(a, "stop")
+
+	for _, key := range []string{"a", "b"} {
+		if err := r.Register(key, a, nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+	waitFor(t, "the actor to be monitored", func() bool {
+		return monitorsOf(a) == 1
+	})
+	r.Unregister("a", a)
+	time.Sleep(10 * time.Millisecond)
+	if monitorsOf(a) != 1 {
+		t.Fatal("the actor is not monitored anymore while it's still registered")
+	}
+	r.Unregister("b", a)
+	waitFor(t, "the actor not to be monitored", func() bool {
+		return monitorsOf(a) == 0
+	})
+}
+
+func TestForceRegister(t *testing.T) {
+	a, b := spawnIdle(), spawnIdle()
+	defer Send(a, "stop")
+	defer Send(b, "stop")
+	defer Unregister("force")
+
+	if err := Register("force", a); err != nil {
+		t.Fatal(err)
+	}
+	ForceRegister("force", b)
+	if pid.ExtractPID(WhereIs("force")) != pid.ExtractPID(b) {
+		t.Fatal("the name has not been taken over")
+	}
+}
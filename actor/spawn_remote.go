@@ -0,0 +1,84 @@
+package actor
+
+import (
+	"fmt"
+	"github.com/hedisam/goactor/internal/pid"
+	"github.com/hedisam/goactor/internal/remote"
+	"sync"
+)
+
+// spawn table of the functions other nodes can spawn by name, since functions can't be sent over the network
+var funcs = struct {
+	sync.RWMutex
+	table map[string]Func
+}{table: make(map[string]Func)}
+
+func init() {
+	remote.SpawnLocal = spawnFunc
+}
+
+// RegisterFunc adds the function to the spawn table under the name. every node that's supposed to spawn it
+// must register it.
+func RegisterFunc(name string, fn Func) {
+	funcs.Lock()
+	defer funcs.Unlock()
+	funcs.table[name] = fn
+}
+
+// SpawnOn spawns the function registered under the name on the given node. the args must be registered
+// with the codec.
+func SpawnOn(node string, name string, args ...interface{}) (*pid.ProtectedPID, error) {
+	return spawnOn(node, remote.SpawnRequest{Func: name, Args: args})
+}
+
+// SpawnLinkOn spawns the function on the given node, linked to the actor
+func (a *Actor) SpawnLinkOn(node string, name string, args ...interface{}) (*pid.ProtectedPID, error) {
+	ppid, err := spawnOn(node, remote.SpawnRequest{Func: name, Args: args, Link: pid.ExtractPID(a.self)})
+	if err != nil {
+		return nil, err
+	}
+	a.link(pid.ExtractPID(ppid))
+	return ppid, nil
+}
+
+// SpawnMonitorOn spawns the function on the given node, monitored by the actor
+func (a *Actor) SpawnMonitorOn(node string, name string, args ...interface{}) (*pid.ProtectedPID, error) {
+	return spawnOn(node, remote.SpawnRequest{Func: name, Args: args, Monitor: pid.ExtractPID(a.self)})
+}
+
+func spawnOn(node string, request remote.SpawnRequest) (*pid.ProtectedPID, error) {
+	route, err := remote.Route(node)
+	if err != nil {
+		return nil, err
+	}
+	var p pid.PID
+	if route == nil {
+		p, err = spawnFunc(request)
+	} else {
+		p, err = route.Spawn(node, request)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return pid.NewProtectedPID(p), nil
+}
+
+// spawnFunc spawns a function of the spawn table, with the link or monitor set before it starts
+func spawnFunc(request remote.SpawnRequest) (pid.PID, error) {
+	funcs.RLock()
+	fn, ok := funcs.table[request.Func]
+	funcs.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("function %s is not registered", request.Func)
+	}
+
+	actor := createActor(request.Args...)
+	if request.Link != nil {
+		actor.link(request.Link)
+	}
+	if request.Monitor != nil {
+		actor.monitoredBy(request.Monitor)
+	}
+	spawn(fn, actor)
+	return pid.ExtractPID(actor.Self()), nil
+}
@@ -11,8 +11,10 @@ func Send(ppid *pid.ProtectedPID, message interface{}) {
 	pid.ExtractPID(ppid).Mailbox().SendUserMessage(message)
 }
 
-func SendNamed(name string, message interface{}) {
-	ppid := WhereIs(name)
+// SendNamed sends the message to the actor registered under the name. name is either a string, which is looked up
+// in the default registry, or a Name resolved through its Resolver. the message is dropped if no actor is found.
+func SendNamed(name interface{}, message interface{}) {
+	ppid := Resolve(name)
 	if ppid == nil {return}
 	Send(ppid, message)
 }
@@ -38,8 +40,14 @@ func spawnMonitor(fn Func, by pid.PID, args ...interface{}) *pid.ProtectedPID {
 }
 
 func createActor(args ...interface{}) *Actor {
+	return createActorWith(func(utils *mailbox.ActorUtils) pid.PID {
+		return pid.NewPID(utils)
+	}, args...)
+}
+
+func createActorWith(newPID func(utils *mailbox.ActorUtils) pid.PID, args ...interface{}) *Actor {
 	utils := &mailbox.ActorUtils{}
-	_pid := pid.NewPID(utils)
+	_pid := newPID(utils)
 	ctx := context.NewContext(_pid, args)
 	actor := newActor(ctx, _pid, utils)
 	return actor
@@ -0,0 +1,108 @@
+package actor
+
+import (
+	"github.com/hedisam/goactor/internal/pid"
+	"log"
+)
+
+// Unregistered is a Resolver that never registers anything. use it for actors that shouldn't have a name.
+var Unregistered Resolver = unregistered{}
+
+// Resolver is a pluggable name resolution module, the equivalent of Erlang's {via, Module, Name}.
+// actors can be registered under, looked up by and sent messages to through any Resolver.
+type Resolver interface {
+	RegisterName(name string, ppid *pid.ProtectedPID) error
+	UnregisterName(name string)
+	WhereIsName(name string) *pid.ProtectedPID
+}
+
+// Name is an actor name that is resolved through a Resolver
+type Name struct {
+	// Resolver is the module resolving the name. nil means the default registry.
+	Resolver Resolver
+	Key      string
+}
+
+// LocalName returns a name resolved through the default registry
+func LocalName(key string) Name {
+	return Name{Key: key}
+}
+
+// ViaName returns a name resolved through the given resolver
+func ViaName(resolver Resolver, key string) Name {
+	return Name{Resolver: resolver, Key: key}
+}
+
+func (n Name) resolver() Resolver {
+	if n.Resolver == nil {
+		return defaultRegistry
+	}
+	return n.Resolver
+}
+
+func (n Name) String() string {
+	return n.Key
+}
+
+// WhereIsName returns the actor registered under the name, nil if there's none
+func WhereIsName(name Name) *pid.ProtectedPID {
+	return name.resolver().WhereIsName(name.Key)
+}
+
+// SpawnNamed registers the actor under the name before spawning it. it fails without spawning the actor if
+// the name can't be registered.
+func SpawnNamed(name Name, fn Func, args ...interface{}) (*pid.ProtectedPID, error) {
+	actor := createActor(args...)
+	if err := name.resolver().RegisterName(name.Key, actor.Self()); err != nil {
+		return nil, err
+	}
+	spawn(fn, actor)
+	return actor.Self(), nil
+}
+
+// RegisterName implements Resolver. it should be used with unique keys registries.
+func (r *Registry) RegisterName(name string, ppid *pid.ProtectedPID) error {
+	return r.Register(name, ppid, nil)
+}
+
+// UnregisterName implements Resolver
+func (r *Registry) UnregisterName(name string) {
+	r.unregisterKey(name)
+}
+
+// WhereIsName implements Resolver. it returns the first actor registered under the name.
+func (r *Registry) WhereIsName(name string) *pid.ProtectedPID {
+	entries := r.Lookup(name)
+	if len(entries) == 0 {
+		return nil
+	}
+	return entries[0].PID
+}
+
+// Resolve finds the actor by a name that is either a string, looked up in the default registry, or a Name. it
+// returns nil if there's none.
+func Resolve(name interface{}) *pid.ProtectedPID {
+	switch n := name.(type) {
+	case string:
+		return WhereIs(n)
+	case Name:
+		return WhereIsName(n)
+	case *Name:
+		return WhereIsName(*n)
+	default:
+		log.Printf("actor: invalid name type %T\n", name)
+		return nil
+	}
+}
+
+type unregistered struct{}
+
+func (unregistered) RegisterName(string, *pid.ProtectedPID) error {
+	return nil
+}
+
+func (unregistered) UnregisterName(string) {}
+
+func (unregistered) WhereIsName(string) *pid.ProtectedPID {
+	return nil
+}
@@ -0,0 +1,311 @@
+package breaker
+
+import (
+	"errors"
+	"fmt"
+	"github.com/hedisam/goactor/actor"
+	"github.com/hedisam/goactor/internal/pid"
+	"github.com/hedisam/goactor/node"
+	"github.com/hedisam/goactor/pubsub"
+	"sync"
+	"time"
+)
+
+const (
+	defaultFailureThreshold = 5
+	defaultOpenTimeout      = 10 * time.Second
+	defaultCallTimeout      = 5 * time.Second
+)
+
+var (
+	// ErrOpen is returned without calling the target while the breaker is open
+	ErrOpen = errors.New("breaker: circuit is open")
+	// ErrTimeout is returned when the target doesn't reply in time
+	ErrTimeout = errors.New("breaker: call timed out")
+	// ErrTargetDown is returned when the target isn't running or terminates before replying
+	ErrTargetDown = errors.New("breaker: target is down")
+)
+
+func init() {
+	node.RegisterMessage(StateChanged{})
+}
+
+// State is the state of a breaker
+type State int32
+
+const (
+	// Closed lets the calls through, counting the failures
+	Closed State = iota
+	// Open fails the calls right away until OpenTimeout has passed
+	Open
+	// HalfOpen lets a few probing calls through, their success closes the breaker and a failure opens it again
+	HalfOpen
+)
+
+func (s State) String() string {
+	switch s {
+	case Closed:
+		return "closed"
+	case Open:
+		return "open"
+	case HalfOpen:
+		return "half-open"
+	default:
+		return fmt.Sprintf("state(%d)", int32(s))
+	}
+}
+
+// StateChanged is published when a breaker changes state
+type StateChanged struct {
+	Breaker string
+	From    State
+	To      State
+	At      time.Time
+}
+
+// Counts are the breaker's counters since it's been created
+type Counts struct {
+	Calls     uint64
+	Successes uint64
+	Failures  uint64
+	Timeouts  uint64
+	// Rejected is the number of calls failed fast while the breaker was open
+	Rejected uint64
+	// ConsecutiveFailures is the number of failures since the last success
+	ConsecutiveFailures int
+}
+
+// Config describes a breaker
+type Config struct {
+	Name string
+	// Target is the actor called through the breaker, a pid or a name
+	Target interface{}
+	// FailureThreshold is the number of consecutive failures that opens the breaker, default is 5
+	FailureThreshold int
+	// OpenTimeout is how long the breaker stays open before probing, default is 10s
+	OpenTimeout time.Duration
+	// HalfOpenCalls is the number of probing calls that must succeed to close the breaker, default is 1
+	HalfOpenCalls int
+	// CallTimeout is how long the target has to reply, default is 5s
+	CallTimeout time.Duration
+	// IsFailure tells whether a reply is a failure, by default a reply that's an error is
+	IsFailure func(reply interface{}) bool
+	// OnStateChange is called with the state changes, from the goroutine of the call that caused them. it's optional.
+	OnStateChange func(event StateChanged)
+	// PubSub publishes the state changes on Topic, it's optional
+	PubSub *pubsub.PubSub
+	// Topic is the topic of the state changes, default is "breaker." followed by the name
+	Topic string
+}
+
+// Breaker is a circuit breaker wrapping the calls to an actor. it's safe for concurrent use.
+type Breaker struct {
+	config Config
+
+	mu       sync.Mutex
+	state    State
+	counts   Counts
+	openedAt time.Time
+	// probes let through and succeeded while half open
+	probing   int
+	succeeded int
+	// generation changes with the state, so the results of calls started before a change are ignored
+	generation uint64
+	// state changes to be published once the lock is released
+	changes []StateChanged
+}
+
+// New returns a closed breaker
+func New(config Config) *Breaker {
+	if config.FailureThreshold <= 0 {
+		config.FailureThreshold = defaultFailureThreshold
+	}
+	if config.OpenTimeout <= 0 {
+		config.OpenTimeout = defaultOpenTimeout
+	}
+	if config.HalfOpenCalls <= 0 {
+		config.HalfOpenCalls = 1
+	}
+	if config.CallTimeout <= 0 {
+		config.CallTimeout = defaultCallTimeout
+	}
+	if config.IsFailure == nil {
+		config.IsFailure = func(reply interface{}) bool {
+			_, ok := reply.(error)
+			return ok
+		}
+	}
+	if config.Topic == "" {
+		config.Topic = "breaker." + config.Name
+	}
+	return &Breaker{config: config}
+}
+
+// Call sends the request built with the pid the reply is expected at to the target, and waits for the reply.
+// a failed reply is returned along with an error. it fails with ErrOpen right away while the breaker is open, and
+// with ErrTargetDown as soon as the target terminates.
+func (b *Breaker) Call(request func(replyTo *pid.ProtectedPID) interface{}) (interface{}, error) {
+	generation, err := b.before()
+	if err != nil {
+		return nil, err
+	}
+	target, ok := b.config.Target.(*pid.ProtectedPID)
+	if !ok {
+		target = actor.Resolve(b.config.Target)
+	}
+	if target == nil {
+		b.after(generation, false, false)
+		return nil, ErrTargetDown
+	}
+	future := actor.NewFutureActor()
+	defer future.Dispose()
+	// the target is monitored, so the call fails as soon as it terminates
+	future.Send(target, request(future.Self()))
+	reply, err := future.RecvWithTimeout(b.config.CallTimeout)
+	if err == actor.ErrTargetDown {
+		b.after(generation, false, false)
+		return nil, ErrTargetDown
+	}
+	if err != nil {
+		b.after(generation, false, true)
+		return nil, ErrTimeout
+	}
+	if b.config.IsFailure(reply) {
+		b.after(generation, false, false)
+		if err, ok := reply.(error); ok {
+			return reply, err
+		}
+		return reply, fmt.Errorf("breaker: call to %v failed: %v", b.config.Target, reply)
+	}
+	b.after(generation, true, false)
+	return reply, nil
+}
+
+// Do runs fn through the breaker, an error being a failure. it fails with ErrOpen without running fn while the
+// breaker is open.
+func (b *Breaker) Do(fn func() (interface{}, error)) (interface{}, error) {
+	generation, err := b.before()
+	if err != nil {
+		return nil, err
+	}
+	result, err := fn()
+	b.after(generation, err == nil, err == ErrTimeout)
+	return result, err
+}
+
+// State returns the current state
+func (b *Breaker) State() State {
+	b.mu.Lock()
+	b.expire(time.Now())
+	state := b.state
+	b.unlock()
+	return state
+}
+
+// Counts returns the current counters
+func (b *Breaker) Counts() Counts {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.counts
+}
+
+// Reset closes the breaker
+func (b *Breaker) Reset() {
+	b.mu.Lock()
+	b.counts.ConsecutiveFailures = 0
+	b.setState(Closed, time.Now())
+	b.unlock()
+}
+
+// before lets the call through or fails it fast, returning the generation the call's been let through in
+func (b *Breaker) before() (uint64, error) {
+	b.mu.Lock()
+	defer b.unlock()
+	b.expire(time.Now())
+	switch b.state {
+	case Open:
+		b.counts.Rejected++
+		return 0, ErrOpen
+	case HalfOpen:
+		if b.probing >= b.config.HalfOpenCalls {
+			b.counts.Rejected++
+			return 0, ErrOpen
+		}
+		b.probing++
+	}
+	b.counts.Calls++
+	return b.generation, nil
+}
+
+// after records the result of a call
+func (b *Breaker) after(generation uint64, success bool, timeout bool) {
+	b.mu.Lock()
+	defer b.unlock()
+	now := time.Now()
+	if success {
+		b.counts.Successes++
+		b.counts.ConsecutiveFailures = 0
+	} else {
+		b.counts.Failures++
+		b.counts.ConsecutiveFailures++
+		if timeout {
+			b.counts.Timeouts++
+		}
+	}
+	if generation != b.generation {
+		return
+	}
+
+	switch b.state {
+	case Closed:
+		if b.counts.ConsecutiveFailures >= b.config.FailureThreshold {
+			b.setState(Open, now)
+		}
+	case HalfOpen:
+		if !success {
+			b.setState(Open, now)
+			return
+		}
+		b.succeeded++
+		if b.succeeded >= b.config.HalfOpenCalls {
+			b.setState(Closed, now)
+		}
+	}
+}
+
+// expire half opens the breaker once it's been open long enough, it must be called with the lock held
+func (b *Breaker) expire(now time.Time) {
+	if b.state == Open && now.Sub(b.openedAt) >= b.config.OpenTimeout {
+		b.setState(HalfOpen, now)
+	}
+}
+
+// setState must be called with the lock held
+func (b *Breaker) setState(state State, now time.Time) {
+	if b.state == state {
+		return
+	}
+	event := StateChanged{Breaker: b.config.Name, From: b.state, To: state, At: now}
+	b.state = state
+	b.generation++
+	b.probing, b.succeeded = 0, 0
+	if state == Open {
+		b.openedAt = now
+	}
+	b.changes = append(b.changes, event)
+}
+
+// unlock releases the lock and publishes the state changes made while it was held
+func (b *Breaker) unlock() {
+	changes := b.changes
+	b.changes = nil
+	b.mu.Unlock()
+	for _, event := range changes {
+		if b.config.OnStateChange != nil {
+			b.config.OnStateChange(event)
+		}
+		if b.config.PubSub != nil {
+			b.config.PubSub.Publish(b.config.Topic, event)
+		}
+	}
+}
@@ -0,0 +1,362 @@
+package codec
+
+import (
+	"bytes"
+	"encoding"
+	"encoding/binary"
+	"fmt"
+	"github.com/hedisam/goactor/internal/pid"
+	"io"
+	"math"
+	"reflect"
+)
+
+// binaryCodec is a compact codec. values are written field by field with no field names, integers as varints,
+// so both sides must have the same version of the message types.
+// unexported struct fields are skipped, types implementing encoding.BinaryMarshaler encode themselves.
+type binaryCodec struct{}
+
+var (
+	protectedPIDType      = reflect.TypeOf(&pid.ProtectedPID{})
+	binaryMarshalerType   = reflect.TypeOf((*encoding.BinaryMarshaler)(nil)).Elem()
+	binaryUnmarshalerType = reflect.TypeOf((*encoding.BinaryUnmarshaler)(nil)).Elem()
+)
+
+func (binaryCodec) Name() string {
+	return "binary"
+}
+
+func (binaryCodec) Encode(message interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := encodeInterface(&buf, reflect.ValueOf(&message).Elem()); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (binaryCodec) Decode(data []byte) (interface{}, error) {
+	var message interface{}
+	if err := decodeInterface(bytes.NewReader(data), reflect.ValueOf(&message).Elem()); err != nil {
+		return nil, err
+	}
+	return message, nil
+}
+
+// encodeInterface writes the registered type name of the dynamic value followed by the value
+func encodeInterface(buf *bytes.Buffer, v reflect.Value) error {
+	if v.IsNil() {
+		writeString(buf, "")
+		return nil
+	}
+	elem := v.Elem()
+	name, err := TypeName(elem.Interface())
+	if err != nil {
+		return err
+	}
+	writeString(buf, name)
+	return encodeValue(buf, elem)
+}
+
+func decodeInterface(r *bytes.Reader, v reflect.Value) error {
+	name, err := readString(r)
+	if err != nil || name == "" {
+		return err
+	}
+	value, err := newValue(name)
+	if err != nil {
+		return err
+	}
+	if err := decodeValue(r, value.Elem()); err != nil {
+		return err
+	}
+	if !value.Elem().Type().AssignableTo(v.Type()) {
+		return fmt.Errorf("codec: %s is not assignable to %s", name, v.Type())
+	}
+	v.Set(value.Elem())
+	return nil
+}
+
+func encodeValue(buf *bytes.Buffer, v reflect.Value) error {
+	t := v.Type()
+	if t == protectedPIDType {
+		if v.IsNil() {
+			buf.WriteByte(0)
+			return nil
+		}
+		buf.WriteByte(1)
+		ref := pid.EncodeRef(v.Interface().(*pid.ProtectedPID))
+		writeString(buf, ref.Node)
+		writeString(buf, ref.ID)
+		return nil
+	}
+	if selfMarshaling(t) {
+		data, err := v.Interface().(encoding.BinaryMarshaler).MarshalBinary()
+		if err != nil {
+			return err
+		}
+		writeBytes(buf, data)
+		return nil
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		if v.Bool() {
+			buf.WriteByte(1)
+		} else {
+			buf.WriteByte(0)
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		writeVarint(buf, v.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		writeUvarint(buf, v.Uint())
+	case reflect.Float32, reflect.Float64:
+		var b [8]byte
+		binary.BigEndian.PutUint64(b[:], math.Float64bits(v.Float()))
+		buf.Write(b[:])
+	case reflect.String:
+		writeString(buf, v.String())
+	case reflect.Slice:
+		// the length is written plus one, zero stands for a nil slice
+		if v.IsNil() {
+			writeUvarint(buf, 0)
+			return nil
+		}
+		if t.Elem().Kind() == reflect.Uint8 {
+			writeUvarint(buf, uint64(v.Len())+1)
+			buf.Write(v.Bytes())
+			return nil
+		}
+		writeUvarint(buf, uint64(v.Len())+1)
+		for i := 0; i < v.Len(); i++ {
+			if err := encodeValue(buf, v.Index(i)); err != nil {
+				return err
+			}
+		}
+	case reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			if err := encodeValue(buf, v.Index(i)); err != nil {
+				return err
+			}
+		}
+	case reflect.Map:
+		if v.IsNil() {
+			writeUvarint(buf, 0)
+			return nil
+		}
+		writeUvarint(buf, uint64(v.Len())+1)
+		iter := v.MapRange()
+		for iter.Next() {
+			if err := encodeValue(buf, iter.Key()); err != nil {
+				return err
+			}
+			if err := encodeValue(buf, iter.Value()); err != nil {
+				return err
+			}
+		}
+	case reflect.Ptr:
+		if v.IsNil() {
+			buf.WriteByte(0)
+			return nil
+		}
+		buf.WriteByte(1)
+		return encodeValue(buf, v.Elem())
+	case reflect.Struct:
+		for i := 0; i < t.NumField(); i++ {
+			if t.Field(i).PkgPath != "" {
+				continue
+			}
+			if err := encodeValue(buf, v.Field(i)); err != nil {
+				return err
+			}
+		}
+	case reflect.Interface:
+		return encodeInterface(buf, v)
+	default:
+		return fmt.Errorf("codec: can't encode values of type %s", t)
+	}
+	return nil
+}
+
+func decodeValue(r *bytes.Reader, v reflect.Value) error {
+	t := v.Type()
+	if t == protectedPIDType {
+		present, err := r.ReadByte()
+		if err != nil || present == 0 {
+			return err
+		}
+		var ref pid.Ref
+		if ref.Node, err = readString(r); err != nil {
+			return err
+		}
+		if ref.ID, err = readString(r); err != nil {
+			return err
+		}
+		v.Set(reflect.ValueOf(pid.DecodeRef(ref)))
+		return nil
+	}
+	if selfMarshaling(t) {
+		data, err := readBytes(r)
+		if err != nil {
+			return err
+		}
+		return v.Addr().Interface().(encoding.BinaryUnmarshaler).UnmarshalBinary(data)
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		b, err := r.ReadByte()
+		if err != nil {
+			return err
+		}
+		v.SetBool(b != 0)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		i, err := binary.ReadVarint(r)
+		if err != nil {
+			return err
+		}
+		v.SetInt(i)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		u, err := binary.ReadUvarint(r)
+		if err != nil {
+			return err
+		}
+		v.SetUint(u)
+	case reflect.Float32, reflect.Float64:
+		var b [8]byte
+		if _, err := io.ReadFull(r, b[:]); err != nil {
+			return err
+		}
+		v.SetFloat(math.Float64frombits(binary.BigEndian.Uint64(b[:])))
+	case reflect.String:
+		s, err := readString(r)
+		if err != nil {
+			return err
+		}
+		v.SetString(s)
+	case reflect.Slice:
+		n, err := readLength(r)
+		if err != nil || n == 0 {
+			return err
+		}
+		n--
+		if t.Elem().Kind() == reflect.Uint8 {
+			data := make([]byte, n)
+			if _, err := io.ReadFull(r, data); err != nil {
+				return err
+			}
+			v.SetBytes(data)
+			return nil
+		}
+		slice := reflect.MakeSlice(t, n, n)
+		for i := 0; i < n; i++ {
+			if err := decodeValue(r, slice.Index(i)); err != nil {
+				return err
+			}
+		}
+		v.Set(slice)
+	case reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			if err := decodeValue(r, v.Index(i)); err != nil {
+				return err
+			}
+		}
+	case reflect.Map:
+		n, err := readLength(r)
+		if err != nil || n == 0 {
+			return err
+		}
+		m := reflect.MakeMapWithSize(t, n-1)
+		for i := 0; i < n-1; i++ {
+			key := reflect.New(t.Key()).Elem()
+			if err := decodeValue(r, key); err != nil {
+				return err
+			}
+			value := reflect.New(t.Elem()).Elem()
+			if err := decodeValue(r, value); err != nil {
+				return err
+			}
+			m.SetMapIndex(key, value)
+		}
+		v.Set(m)
+	case reflect.Ptr:
+		present, err := r.ReadByte()
+		if err != nil || present == 0 {
+			return err
+		}
+		elem := reflect.New(t.Elem())
+		if err := decodeValue(r, elem.Elem()); err != nil {
+			return err
+		}
+		v.Set(elem)
+	case reflect.Struct:
+		for i := 0; i < t.NumField(); i++ {
+			if t.Field(i).PkgPath != "" {
+				continue
+			}
+			if err := decodeValue(r, v.Field(i)); err != nil {
+				return err
+			}
+		}
+	case reflect.Interface:
+		return decodeInterface(r, v)
+	default:
+		return fmt.Errorf("codec: can't decode values of type %s", t)
+	}
+	return nil
+}
+
+// selfMarshaling reports whether values of the type encode and decode themselves, like time.Time
+func selfMarshaling(t reflect.Type) bool {
+	if t.Kind() == reflect.Ptr || t.Kind() == reflect.Interface {
+		return false
+	}
+	return t.Implements(binaryMarshalerType) && reflect.PtrTo(t).Implements(binaryUnmarshalerType)
+}
+
+func writeVarint(buf *bytes.Buffer, i int64) {
+	var b [binary.MaxVarintLen64]byte
+	buf.Write(b[:binary.PutVarint(b[:], i)])
+}
+
+func writeUvarint(buf *bytes.Buffer, u uint64) {
+	var b [binary.MaxVarintLen64]byte
+	buf.Write(b[:binary.PutUvarint(b[:], u)])
+}
+
+func writeString(buf *bytes.Buffer, s string) {
+	writeUvarint(buf, uint64(len(s)))
+	buf.WriteString(s)
+}
+
+func writeBytes(buf *bytes.Buffer, data []byte) {
+	writeUvarint(buf, uint64(len(data)))
+	buf.Write(data)
+}
+
+// readLength reads a length and makes sure there's at least that many bytes left, so a corrupt length can't make
+// us allocate a huge slice
+func readLength(r *bytes.Reader) (int, error) {
+	n, err := binary.ReadUvarint(r)
+	if err != nil {
+		return 0, err
+	}
+	if n > uint64(r.Len())+1 {
+		return 0, fmt.Errorf("codec: length %d exceeds the remaining %d bytes", n, r.Len())
+	}
+	return int(n), nil
+}
+
+func readBytes(r *bytes.Reader) ([]byte, error) {
+	n, err := readLength(r)
+	if err != nil {
+		return nil, err
+	}
+	data := make([]byte, n)
+	_, err = io.ReadFull(r, data)
+	return data, err
+}
+
+func readString(r *bytes.Reader) (string, error) {
+	data, err := readBytes(r)
+	return string(data), err
+}
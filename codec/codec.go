@@ -0,0 +1,143 @@
+package codec
+
+import (
+	"encoding/gob"
+	"fmt"
+	"github.com/hedisam/goactor/internal/pid"
+	"reflect"
+	"sync"
+)
+
+// Codec serializes messages. the concrete type of a message must be registered, the type name is written
+// along with the value so it can be decoded back to the same type.
+// the pids inside a decoded message are left unresolved by Decode, a codec returned by WithContext resolves them.
+type Codec interface {
+	Name() string
+	Encode(message interface{}) ([]byte, error)
+	Decode(data []byte) (interface{}, error)
+}
+
+// Context tells the codecs how to resolve the pids found in the decoded messages.
+// the zero Context only resolves the pids of the local process.
+type Context = pid.CodecContext
+
+var (
+	Gob    Codec = gobCodec{}
+	JSON   Codec = jsonCodec{}
+	Binary Codec = binaryCodec{}
+)
+
+var registry = struct {
+	sync.RWMutex
+	types map[string]reflect.Type
+	names map[reflect.Type]string
+	// codecs by name
+	codecs map[string]Codec
+}{
+	types:  make(map[string]reflect.Type),
+	names:  make(map[reflect.Type]string),
+	codecs: make(map[string]Codec),
+}
+
+func init() {
+	for _, c := range []Codec{Gob, JSON, Binary} {
+		RegisterCodec(c)
+	}
+	for _, v := range []interface{}{
+		"", false, 0, int8(0), int16(0), int32(0), int64(0), uint(0), uint8(0), uint16(0), uint32(0), uint64(0),
+		float32(0), float64(0), []byte(nil), []string(nil), []interface{}(nil), map[string]interface{}(nil),
+		&pid.ProtectedPID{},
+	} {
+		Register(v)
+	}
+}
+
+// Register registers the type of the value under its default name, which is the type's package path and name.
+func Register(value interface{}) {
+	t := reflect.TypeOf(value)
+	RegisterName(defaultName(t), value)
+}
+
+// RegisterName registers the type of the value under the given name. it panics if the name or the type is
+// already registered for something else.
+func RegisterName(name string, value interface{}) {
+	if name == "" {
+		panic("codec: registering an empty name")
+	}
+	t := reflect.TypeOf(value)
+	if t == nil {
+		panic("codec: registering a nil value")
+	}
+
+	registry.Lock()
+	defer registry.Unlock()
+	if existing, ok := registry.types[name]; ok && existing != t {
+		panic(fmt.Sprintf("codec: registering duplicate types for %s: %s != %s", name, existing, t))
+	}
+	if existing, ok := registry.names[t]; ok && existing != name {
+		panic(fmt.Sprintf("codec: registering duplicate names for %s: %s != %s", t, existing, name))
+	}
+	registry.types[name] = t
+	registry.names[t] = name
+	// interface values nested inside messages are encoded by gob with the same name
+	gob.RegisterName(name, value)
+}
+
+// TypeName returns the name the message's type is registered under
+func TypeName(message interface{}) (string, error) {
+	t := reflect.TypeOf(message)
+	registry.RLock()
+	defer registry.RUnlock()
+	name, ok := registry.names[t]
+	if !ok {
+		return "", fmt.Errorf("codec: type %v is not registered", t)
+	}
+	return name, nil
+}
+
+// RegisterCodec makes the codec available by its name
+func RegisterCodec(c Codec) {
+	registry.Lock()
+	defer registry.Unlock()
+	registry.codecs[c.Name()] = c
+}
+
+// ByName returns the registered codec with the name
+func ByName(name string) (Codec, error) {
+	registry.RLock()
+	defer registry.RUnlock()
+	c, ok := registry.codecs[name]
+	if !ok {
+		return nil, fmt.Errorf("codec: unknown codec %s", name)
+	}
+	return c, nil
+}
+
+// Unmarshal decodes the message with the codec in the given context
+func Unmarshal(c Codec, ctx Context, data []byte) (interface{}, error) {
+	return WithContext(c, ctx).Decode(data)
+}
+
+// newValue returns a pointer to a new zero value of the type registered under the name
+func newValue(name string) (reflect.Value, error) {
+	registry.RLock()
+	t, ok := registry.types[name]
+	registry.RUnlock()
+	if !ok {
+		return reflect.Value{}, fmt.Errorf("codec: unknown type %s", name)
+	}
+	return reflect.New(t), nil
+}
+
+func defaultName(t reflect.Type) string {
+	if t == nil {
+		return ""
+	}
+	if t.Name() == "" && t.Kind() == reflect.Ptr {
+		return "*" + defaultName(t.Elem())
+	}
+	if t.Name() == "" || t.PkgPath() == "" {
+		return t.String()
+	}
+	return t.PkgPath() + "." + t.Name()
+}
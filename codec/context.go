@@ -0,0 +1,99 @@
+package codec
+
+import (
+	"github.com/hedisam/goactor/internal/pid"
+	"reflect"
+)
+
+// contextCodec resolves the pids of the messages decoded by its codec in its context
+type contextCodec struct {
+	Codec
+	ctx Context
+}
+
+// WithContext returns the codec resolving the pids of the messages it decodes in the given context
+func WithContext(c Codec, ctx Context) Codec {
+	if cc, ok := c.(contextCodec); ok {
+		c = cc.Codec
+	}
+	return contextCodec{Codec: c, ctx: ctx}
+}
+
+func (c contextCodec) Decode(data []byte) (interface{}, error) {
+	message, err := c.Codec.Decode(data)
+	if err != nil {
+		return nil, err
+	}
+	if err := Resolve(c.ctx, message); err != nil {
+		return nil, err
+	}
+	return message, nil
+}
+
+// Resolve resolves the decoded pids inside the value in the given context. only the exported fields of structs
+// are looked into, the codecs don't decode the others.
+func Resolve(ctx Context, value interface{}) error {
+	return resolveValue(ctx, reflect.ValueOf(value))
+}
+
+func resolveValue(ctx Context, v reflect.Value) error {
+	if !v.IsValid() {
+		return nil
+	}
+	t := v.Type()
+	if t == protectedPIDType {
+		if v.IsNil() {
+			return nil
+		}
+		return ctx.ResolvePID(v.Interface().(*pid.ProtectedPID))
+	}
+
+	switch t.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		if v.IsNil() {
+			return nil
+		}
+		return resolveValue(ctx, v.Elem())
+	case reflect.Struct:
+		for i := 0; i < t.NumField(); i++ {
+			if t.Field(i).PkgPath != "" {
+				continue
+			}
+			if err := resolveValue(ctx, v.Field(i)); err != nil {
+				return err
+			}
+		}
+	case reflect.Slice, reflect.Array:
+		if !mayHoldPIDs(t.Elem()) {
+			return nil
+		}
+		for i := 0; i < v.Len(); i++ {
+			if err := resolveValue(ctx, v.Index(i)); err != nil {
+				return err
+			}
+		}
+	case reflect.Map:
+		if !mayHoldPIDs(t.Key()) && !mayHoldPIDs(t.Elem()) {
+			return nil
+		}
+		iter := v.MapRange()
+		for iter.Next() {
+			if err := resolveValue(ctx, iter.Key()); err != nil {
+				return err
+			}
+			if err := resolveValue(ctx, iter.Value()); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// mayHoldPIDs reports whether the values of the type may contain pids, the basic types can't
+func mayHoldPIDs(t reflect.Type) bool {
+	switch t.Kind() {
+	case reflect.Ptr, reflect.Interface, reflect.Struct, reflect.Slice, reflect.Array, reflect.Map:
+		return true
+	}
+	return false
+}
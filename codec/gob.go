@@ -0,0 +1,33 @@
+package codec
+
+import (
+	"bytes"
+	"encoding/gob"
+)
+
+type gobCodec struct{}
+
+// gobEnvelope carries the message as an interface so gob writes its registered type name
+type gobEnvelope struct {
+	Message interface{}
+}
+
+func (gobCodec) Name() string {
+	return "gob"
+}
+
+func (gobCodec) Encode(message interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(&gobEnvelope{Message: message}); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (gobCodec) Decode(data []byte) (interface{}, error) {
+	var envelope gobEnvelope
+	if err := gob.NewDecoder(bytes.NewReader(data)).Decode(&envelope); err != nil {
+		return nil, err
+	}
+	return envelope.Message, nil
+}
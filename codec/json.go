@@ -0,0 +1,51 @@
+package codec
+
+import (
+	"encoding/json"
+)
+
+// jsonCodec is handy for debugging dumps and interop. interface values nested inside a message are decoded as
+// the generic json types, only the message itself gets its registered type back.
+type jsonCodec struct{}
+
+type jsonEnvelope struct {
+	Type    string          `json:"type,omitempty"`
+	Message json.RawMessage `json:"message,omitempty"`
+}
+
+func (jsonCodec) Name() string {
+	return "json"
+}
+
+func (jsonCodec) Encode(message interface{}) ([]byte, error) {
+	if message == nil {
+		return json.Marshal(jsonEnvelope{})
+	}
+	name, err := TypeName(message)
+	if err != nil {
+		return nil, err
+	}
+	data, err := json.Marshal(message)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(jsonEnvelope{Type: name, Message: data})
+}
+
+func (jsonCodec) Decode(data []byte) (interface{}, error) {
+	var envelope jsonEnvelope
+	if err := json.Unmarshal(data, &envelope); err != nil {
+		return nil, err
+	}
+	if envelope.Type == "" {
+		return nil, nil
+	}
+	v, err := newValue(envelope.Type)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(envelope.Message, v.Interface()); err != nil {
+		return nil, err
+	}
+	return v.Elem().Interface(), nil
+}
@@ -0,0 +1,263 @@
+package delivery
+
+import (
+	"errors"
+	"fmt"
+	"github.com/hedisam/goactor/actor"
+	"github.com/hedisam/goactor/codec"
+	"github.com/hedisam/goactor/durable"
+	"github.com/hedisam/goactor/internal/pid"
+	"github.com/rs/xid"
+	"sort"
+	"time"
+)
+
+// ErrTooManyUnconfirmed is returned by Deliver when the sender has reached its limit of unconfirmed messages
+var ErrTooManyUnconfirmed = errors.New("delivery: too many unconfirmed messages")
+
+// Delivery wraps a message delivered at least once. the receiver confirms it by sending a Confirm back to ReplyTo,
+// which Receiver does.
+type Delivery struct {
+	Sender string
+	// Epoch changes when a sender without a store restarts and its ids start over
+	Epoch string
+	ID    uint64
+	// Confirmed is the id up to which every message of the sender has been confirmed, the receiver can forget them
+	Confirmed uint64
+	ReplyTo   *pid.ProtectedPID
+	Message   interface{}
+}
+
+// Confirm confirms a delivery to its sender
+type Confirm struct {
+	Sender string
+	ID     uint64
+}
+
+// redeliver tells the sender to redeliver its unconfirmed messages
+type redeliver struct {
+	Sender string
+}
+
+// Pending is an unconfirmed message
+type Pending struct {
+	ID      uint64
+	To      interface{}
+	Message interface{}
+}
+
+// state is the unconfirmed buffer as it's stored
+type state struct {
+	Epoch   string
+	Next    uint64
+	Pending []Pending
+}
+
+func init() {
+	codec.Register(Delivery{})
+	codec.Register(Confirm{})
+	codec.Register(state{})
+}
+
+// Config describes a sender
+type Config struct {
+	// ID identifies the sender to the receivers, which deduplicate the messages per sender. it must not change
+	// when the sender restarts, and it's the id its unconfirmed messages are stored under.
+	ID string
+	// RedeliverAfter is how long a message waits for its confirmation before it's sent again, default is 1s
+	RedeliverAfter time.Duration
+	// MaxUnconfirmed limits the number of unconfirmed messages, zero means no limit
+	MaxUnconfirmed int
+	// Store keeps the unconfirmed messages so they survive the sender restarting. it's optional. the destinations
+	// of a stored sender should be names, since the pids of the receivers don't survive their restarts, and the
+	// messages must be registered with codec.Register if the store encodes them.
+	Store durable.Store
+}
+
+type pending struct {
+	Pending
+	sent time.Time
+}
+
+// Sender delivers messages at least once on behalf of an actor. it must only be used by the actor it's been
+// created for, which passes its messages to Handle before handling them itself.
+type Sender struct {
+	actor    *actor.Actor
+	config   Config
+	epoch    string
+	next     uint64
+	pending  map[uint64]*pending
+	revision uint64
+	timer    *time.Timer
+	stopped  bool
+}
+
+// NewSender creates the sender of the actor, loading its unconfirmed messages from the store, which are redelivered
+// right away
+func NewSender(a *actor.Actor, config Config) (*Sender, error) {
+	if config.ID == "" {
+		return nil, fmt.Errorf("delivery: sender id is required")
+	}
+	if config.RedeliverAfter <= 0 {
+		config.RedeliverAfter = time.Second
+	}
+	s := &Sender{actor: a, config: config, epoch: xid.New().String(), next: 1, pending: make(map[uint64]*pending)}
+	if config.Store == nil {
+		return s, nil
+	}
+
+	stored, revision, err := config.Store.Get(config.ID)
+	if err != nil {
+		return nil, fmt.Errorf("delivery: could not load %s: %v", config.ID, err)
+	}
+	s.revision = revision
+	if revision == 0 {
+		return s, nil
+	}
+	st, ok := stored.(state)
+	if !ok {
+		return nil, fmt.Errorf("delivery: invalid state of %s", config.ID)
+	}
+	s.epoch, s.next = st.Epoch, st.Next
+	for _, p := range st.Pending {
+		s.pending[p.ID] = &pending{Pending: p}
+		s.send(s.pending[p.ID])
+	}
+	s.schedule()
+	return s, nil
+}
+
+// Deliver sends the message to the destination, a pid or a name, and keeps redelivering it until it's confirmed.
+// it returns the delivery id, or an error if the message can't be stored, in which case it's not sent.
+func (s *Sender) Deliver(to interface{}, message interface{}) (uint64, error) {
+	if s.config.MaxUnconfirmed > 0 && len(s.pending) >= s.config.MaxUnconfirmed {
+		return 0, ErrTooManyUnconfirmed
+	}
+	p := &pending{Pending: Pending{ID: s.next, To: to, Message: message}}
+	s.pending[p.ID] = p
+	s.next++
+	if err := s.save(); err != nil {
+		delete(s.pending, p.ID)
+		s.next--
+		return 0, err
+	}
+	s.send(p)
+	s.schedule()
+	return p.ID, nil
+}
+
+// Handle handles the confirmations and the redelivery ticks of the sender, it returns false for any other message
+func (s *Sender) Handle(message interface{}) bool {
+	switch msg := message.(type) {
+	case Confirm:
+		if msg.Sender != s.config.ID {
+			return false
+		}
+		s.Confirm(msg.ID)
+	case redeliver:
+		if msg.Sender != s.config.ID {
+			return false
+		}
+		s.timer = nil
+		s.redeliver()
+		s.schedule()
+	default:
+		return false
+	}
+	return true
+}
+
+// Confirm confirms the delivery, there's no need to call it for the confirmations passed to Handle.
+// it returns false if the delivery wasn't pending.
+func (s *Sender) Confirm(id uint64) bool {
+	if _, ok := s.pending[id]; !ok {
+		return false
+	}
+	delete(s.pending, id)
+	// if it can't be stored the message is redelivered after a restart, which the receiver can tell
+	_ = s.save()
+	return true
+}
+
+// Unconfirmed returns the unconfirmed messages ordered by id
+func (s *Sender) Unconfirmed() []Pending {
+	unconfirmed := make([]Pending, 0, len(s.pending))
+	for _, p := range s.pending {
+		unconfirmed = append(unconfirmed, p.Pending)
+	}
+	sort.Slice(unconfirmed, func(i, j int) bool {
+		return unconfirmed[i].ID < unconfirmed[j].ID
+	})
+	return unconfirmed
+}
+
+// Stop stops the redelivery timer. the unconfirmed messages are kept in the store, if there's one.
+func (s *Sender) Stop() {
+	s.stopped = true
+	if s.timer != nil {
+		s.timer.Stop()
+		s.timer = nil
+	}
+}
+
+func (s *Sender) send(p *pending) {
+	p.sent = time.Now()
+	d := Delivery{
+		Sender:    s.config.ID,
+		Epoch:     s.epoch,
+		ID:        p.ID,
+		Confirmed: s.confirmed(),
+		ReplyTo:   s.actor.Self(),
+		Message:   p.Message,
+	}
+	if ppid, ok := p.To.(*pid.ProtectedPID); ok {
+		actor.Send(ppid, d)
+		return
+	}
+	actor.SendNamed(p.To, d)
+}
+
+func (s *Sender) redeliver() {
+	now := time.Now()
+	for _, p := range s.Unconfirmed() {
+		if pp := s.pending[p.ID]; now.Sub(pp.sent) >= s.config.RedeliverAfter {
+			s.send(pp)
+		}
+	}
+}
+
+// schedule arms the redelivery timer while there are unconfirmed messages. the timer fires once, so it can't
+// outlive the actor by more than one tick, which is dropped by the disposed mailbox.
+func (s *Sender) schedule() {
+	if s.stopped || s.timer != nil || len(s.pending) == 0 {
+		return
+	}
+	self, tick := s.actor.Self(), redeliver{Sender: s.config.ID}
+	s.timer = time.AfterFunc(s.config.RedeliverAfter, func() {
+		actor.Send(self, tick)
+	})
+}
+
+// confirmed returns the id up to which every message has been confirmed
+func (s *Sender) confirmed() uint64 {
+	confirmed := s.next - 1
+	for id := range s.pending {
+		if id <= confirmed {
+			confirmed = id - 1
+		}
+	}
+	return confirmed
+}
+
+func (s *Sender) save() error {
+	if s.config.Store == nil {
+		return nil
+	}
+	st := state{Epoch: s.epoch, Next: s.next, Pending: s.Unconfirmed()}
+	revision, err := s.config.Store.Upsert(s.config.ID, s.revision, st)
+	if err != nil {
+		return fmt.Errorf("delivery: could not store %s: %v", s.config.ID, err)
+	}
+	s.revision = revision
+	return nil
+}
@@ -0,0 +1,80 @@
+package delivery
+
+import (
+	"github.com/hedisam/goactor/actor"
+	"github.com/hedisam/goactor/durable"
+	"github.com/hedisam/goactor/internal/pid"
+	"github.com/hedisam/goactor/sysmsg"
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestStoredSenderWithDeadReceiver(t *testing.T) {
+	dir, err := ioutil.TempDir("", "delivery")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	store, err := durable.NewFileStore(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	parent, dispose := actor.NewParentActor()
+	defer dispose()
+	sender, err := NewSender(parent, Config{ID: "sender", Store: store, RedeliverAfter: time.Hour})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sender.Stop()
+
+	// the receiver stops on its first delivery without confirming it
+	receiver := parent.SpawnMonitor(func(a *actor.Actor) {
+		a.Receive(func(message interface{}) (loop bool) {
+			return false
+		})
+	})
+	if _, err := sender.Deliver(receiver, "first"); err != nil {
+		t.Fatal(err)
+	}
+	parent.ReceiveWithTimeout(time.Second, func(message interface{}) (loop bool) {
+		if _, ok := message.(sysmsg.Exit); !ok {
+			t.Fatalf("expected the receiver to stop, got %#v", message)
+		}
+		return false
+	})
+	deadline := time.Now().Add(time.Second)
+	for {
+		if _, ok := pid.Lookup(pid.EncodeRef(receiver).ID); !ok {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("the receiver's pid has not been released")
+		}
+		time.Sleep(time.Millisecond)
+	}
+	if _, err := sender.Deliver(receiver, "second"); err != nil {
+		t.Fatalf("could not deliver once the receiver had terminated: %v", err)
+	}
+
+	// a new store on the same directory is what the restarted process opens
+	store, err = durable.NewFileStore(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	restarted, dispose := actor.NewParentActor()
+	defer dispose()
+	sender, err = NewSender(restarted, Config{ID: "sender", Store: store, RedeliverAfter: time.Hour})
+	if err != nil {
+		t.Fatalf("could not load the sender: %v", err)
+	}
+	defer sender.Stop()
+	unconfirmed := sender.Unconfirmed()
+	if len(unconfirmed) != 2 || unconfirmed[1].Message != "second" {
+		t.Fatalf("unexpected unconfirmed messages %v", unconfirmed)
+	}
+	if to := pid.EncodeRef(unconfirmed[0].To.(*pid.ProtectedPID)); to != pid.EncodeRef(receiver) {
+		t.Fatalf("the receiver was loaded as %v, expected %v", to, pid.EncodeRef(receiver))
+	}
+}
@@ -0,0 +1,56 @@
+package delivery
+
+import "github.com/hedisam/goactor/actor"
+
+// Receiver deduplicates the deliveries an actor receives and confirms them. it remembers the deliveries of each
+// sender that haven't been confirmed as of the sender's last message, in memory, so a restarted receiver may
+// handle a message it has already handled once more.
+type Receiver struct {
+	senders map[string]*received
+}
+
+// received are the deliveries received from a sender
+type received struct {
+	epoch     string
+	confirmed uint64
+	ids       map[uint64]struct{}
+}
+
+// NewReceiver returns a receiver that hasn't received anything yet
+func NewReceiver() *Receiver {
+	return &Receiver{senders: make(map[string]*received)}
+}
+
+// Handle calls fn with the delivered message, unless it's a duplicate, and confirms the delivery once fn has
+// returned. a delivery isn't confirmed if fn panics, so it's redelivered to the restarted actor.
+// it returns false if the message isn't a Delivery.
+func (r *Receiver) Handle(message interface{}, fn func(message interface{})) bool {
+	d, ok := message.(Delivery)
+	if !ok {
+		return false
+	}
+	sender := r.senders[d.Sender]
+	if sender == nil || sender.epoch != d.Epoch {
+		sender = &received{epoch: d.Epoch, ids: make(map[uint64]struct{})}
+		r.senders[d.Sender] = sender
+	}
+	if d.Confirmed > sender.confirmed {
+		sender.confirmed = d.Confirmed
+		for id := range sender.ids {
+			if id <= sender.confirmed {
+				delete(sender.ids, id)
+			}
+		}
+	}
+
+	_, duplicate := sender.ids[d.ID]
+	if !duplicate && d.ID > sender.confirmed {
+		fn(d.Message)
+		sender.ids[d.ID] = struct{}{}
+	}
+	// duplicates are confirmed again, the first confirmation might have been lost
+	if d.ReplyTo != nil {
+		actor.Send(d.ReplyTo, Confirm{Sender: d.Sender, ID: d.ID})
+	}
+	return true
+}
@@ -0,0 +1,167 @@
+package discovery
+
+import (
+	"github.com/hedisam/goactor/actor"
+	"github.com/hedisam/goactor/internal/pid"
+	"github.com/hedisam/goactor/node"
+	"github.com/hedisam/goactor/sysmsg"
+	"log"
+	"sort"
+	"sync"
+	"time"
+)
+
+// reconnectInterval is how often the cluster retries connecting to the members it's not connected to
+const reconnectInterval = time.Second
+
+// subscribersKey is the key the subscribers are registered under
+const subscribersKey = "subscribers"
+
+// Strategy discovers the members of a cluster
+type Strategy interface {
+	// Join starts discovering on behalf of the local node. update is called with all the members known to the
+	// strategy, other than the local node, whenever the list changes.
+	Join(self string, update func(members []string)) error
+	// Leave stops discovering, announcing the local node is leaving if the strategy supports it
+	Leave()
+}
+
+// Cluster keeps the node connected to the members found by a strategy. subscribers get a sysmsg.MemberJoined
+// or MemberLeft whenever the members change, they're kept in a duplicate keys registry so they're removed when
+// they terminate.
+type Cluster struct {
+	node        *node.Node
+	strategy    Strategy
+	subscribers *actor.Registry
+
+	mu      sync.RWMutex
+	members map[string]struct{}
+
+	stopOnce sync.Once
+	done     chan struct{}
+}
+
+// Start joins the cluster through the strategy
+func Start(n *node.Node, strategy Strategy) (*Cluster, error) {
+	c := &Cluster{
+		node:        n,
+		strategy:    strategy,
+		subscribers: actor.NewRegistry(actor.DuplicateKeys, 1),
+		members:     make(map[string]struct{}),
+		done:        make(chan struct{}),
+	}
+	if err := strategy.Join(n.Name(), c.update); err != nil {
+		c.subscribers.Close()
+		return nil, err
+	}
+	go c.reconnect()
+	return c, nil
+}
+
+// Members returns the discovered members, sorted
+func (c *Cluster) Members() []string {
+	c.mu.RLock()
+	members := make([]string, 0, len(c.members))
+	for member := range c.members {
+		members = append(members, member)
+	}
+	c.mu.RUnlock()
+	sort.Strings(members)
+	return members
+}
+
+// Subscribe makes the actor receive the membership changes. subscribing more than once has no effect.
+func (c *Cluster) Subscribe(ppid *pid.ProtectedPID) {
+	_ = c.subscribers.Register(subscribersKey, ppid, nil)
+}
+
+// Unsubscribe stops sending the membership changes to the actor
+func (c *Cluster) Unsubscribe(ppid *pid.ProtectedPID) {
+	c.subscribers.Unregister(subscribersKey, ppid)
+}
+
+// Stop leaves the cluster and disconnects from its members
+func (c *Cluster) Stop() {
+	c.stopOnce.Do(func() {
+		close(c.done)
+		c.strategy.Leave()
+		for _, member := range c.Members() {
+			c.node.Disconnect(member)
+		}
+		c.subscribers.Close()
+	})
+}
+
+// update is called by the strategy with the current members
+func (c *Cluster) update(members []string) {
+	current := make(map[string]struct{}, len(members))
+	for _, member := range members {
+		if member != c.node.Name() {
+			current[member] = struct{}{}
+		}
+	}
+
+	var joined, left []string
+	c.mu.Lock()
+	for member := range current {
+		if _, ok := c.members[member]; !ok {
+			joined = append(joined, member)
+		}
+	}
+	for member := range c.members {
+		if _, ok := current[member]; !ok {
+			left = append(left, member)
+		}
+	}
+	c.members = current
+	c.mu.Unlock()
+
+	entries := c.subscribers.Lookup(subscribersKey)
+	subscribers := make([]pid.PID, 0, len(entries))
+	for _, e := range entries {
+		subscribers = append(subscribers, pid.ExtractPID(e.PID))
+	}
+
+	sort.Strings(joined)
+	sort.Strings(left)
+	for _, member := range left {
+		c.node.Disconnect(member)
+		for _, p := range subscribers {
+			p.Mailbox().SendSystemMessage(sysmsg.MemberLeft{Node: member})
+		}
+	}
+	for _, member := range joined {
+		for _, p := range subscribers {
+			p.Mailbox().SendSystemMessage(sysmsg.MemberJoined{Node: member})
+		}
+		go c.connect(member)
+	}
+}
+
+func (c *Cluster) connect(member string) {
+	if err := c.node.Connect(member); err != nil {
+		log.Printf("discovery: could not connect to %s: %v\n", member, err)
+	}
+}
+
+// reconnect retries connecting to the members we've lost or never managed to connect to
+func (c *Cluster) reconnect() {
+	ticker := time.NewTicker(reconnectInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			connected := make(map[string]struct{})
+			for _, name := range c.node.Nodes() {
+				connected[name] = struct{}{}
+			}
+			for _, member := range c.Members() {
+				if _, ok := connected[member]; !ok {
+					_ = c.node.Connect(member)
+				}
+			}
+		case <-c.done:
+			return
+		}
+	}
+}
@@ -0,0 +1,96 @@
+package discovery
+
+import (
+	"bufio"
+	"bytes"
+	"io/ioutil"
+	"log"
+	"os"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+)
+
+const defaultFileInterval = time.Second
+
+// File reads the member nodes from a file, one node name per line, and watches it for changes.
+// blank lines and lines starting with # are ignored.
+type File struct {
+	Path string
+	// Interval is how often the file is checked, a second by default
+	Interval time.Duration
+
+	stopOnce sync.Once
+	done     chan struct{}
+}
+
+// NewFile returns a strategy watching the file
+func NewFile(path string) *File {
+	return &File{Path: path, Interval: defaultFileInterval}
+}
+
+func (f *File) Join(self string, update func(members []string)) error {
+	members, err := f.read()
+	if err != nil {
+		return err
+	}
+	update(members)
+
+	interval := f.Interval
+	if interval <= 0 {
+		interval = defaultFileInterval
+	}
+	f.done = make(chan struct{})
+	go f.watch(interval, members, update)
+	return nil
+}
+
+func (f *File) Leave() {
+	f.stopOnce.Do(func() {
+		if f.done != nil {
+			close(f.done)
+		}
+	})
+}
+
+func (f *File) watch(interval time.Duration, members []string, update func(members []string)) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			current, err := f.read()
+			if err != nil {
+				// keep the last known members, the file might be in the middle of being replaced
+				if !os.IsNotExist(err) {
+					log.Printf("discovery: could not read %s: %v\n", f.Path, err)
+				}
+				continue
+			}
+			if !reflect.DeepEqual(current, members) {
+				members = current
+				update(members)
+			}
+		case <-f.done:
+			return
+		}
+	}
+}
+
+func (f *File) read() ([]string, error) {
+	data, err := ioutil.ReadFile(f.Path)
+	if err != nil {
+		return nil, err
+	}
+	members := []string{}
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		members = append(members, line)
+	}
+	return members, scanner.Err()
+}
@@ -0,0 +1,234 @@
+package discovery
+
+import (
+	"fmt"
+	"log"
+	"net"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	defaultGossipGroup    = "239.255.43.21:45892"
+	defaultGossipInterval = time.Second
+	defaultGossipTimeout  = 5 * time.Second
+
+	gossipPrefix = "goactor"
+	gossipAlive  = "alive"
+	gossipLeave  = "leave"
+)
+
+// Gossip discovers the members through UDP multicast. every node announces itself periodically, a member that
+// hasn't been heard of for Timeout is considered gone.
+type Gossip struct {
+	// Group is the multicast group address, 239.255.43.21:45892 by default
+	Group string
+	// Interface is the name of the network interface to gossip on, the loopback interface by default
+	Interface string
+	// Cluster separates the clusters sharing the same group, only the announcements of the same cluster are heard
+	Cluster string
+	// Interval is how often the node announces itself, a second by default
+	Interval time.Duration
+	// Timeout is how long a member can be silent before it's considered gone, 5 seconds by default
+	Timeout time.Duration
+
+	self     string
+	update   func(members []string)
+	listener *net.UDPConn
+	sender   *net.UDPConn
+
+	// mu is held while the members are passed to update, so a snapshot can't be overtaken by an older one
+	mu      sync.Mutex
+	members map[string]time.Time
+
+	stopOnce sync.Once
+	done     chan struct{}
+}
+
+// NewGossip returns a gossip strategy for the cluster with the default settings
+func NewGossip(cluster string) *Gossip {
+	return &Gossip{
+		Group:    defaultGossipGroup,
+		Cluster:  cluster,
+		Interval: defaultGossipInterval,
+		Timeout:  defaultGossipTimeout,
+	}
+}
+
+func (g *Gossip) Join(self string, update func(members []string)) error {
+	if g.Group == "" {
+		g.Group = defaultGossipGroup
+	}
+	if g.Interval <= 0 {
+		g.Interval = defaultGossipInterval
+	}
+	if g.Timeout <= 0 {
+		g.Timeout = defaultGossipTimeout
+	}
+	group, err := net.ResolveUDPAddr("udp4", g.Group)
+	if err != nil {
+		return err
+	}
+	ifi, local, err := gossipInterface(g.Interface)
+	if err != nil {
+		return err
+	}
+	g.listener, err = net.ListenMulticastUDP("udp4", ifi, group)
+	if err != nil {
+		return err
+	}
+	// binding to the interface's address makes the announcements go out through that interface
+	g.sender, err = net.DialUDP("udp4", &net.UDPAddr{IP: local}, group)
+	if err != nil {
+		_ = g.listener.Close()
+		return err
+	}
+
+	g.self = self
+	g.update = update
+	g.members = make(map[string]time.Time)
+	g.done = make(chan struct{})
+	go g.listen()
+	go g.announce()
+	return nil
+}
+
+func (g *Gossip) Leave() {
+	g.stopOnce.Do(func() {
+		if g.done == nil {
+			return
+		}
+		close(g.done)
+		g.send(gossipLeave)
+		_ = g.listener.Close()
+		_ = g.sender.Close()
+	})
+}
+
+// announce periodically announces the node and expires the silent members
+func (g *Gossip) announce() {
+	ticker := time.NewTicker(g.Interval)
+	defer ticker.Stop()
+	g.send(gossipAlive)
+	for {
+		select {
+		case <-ticker.C:
+			g.send(gossipAlive)
+			g.expire()
+		case <-g.done:
+			return
+		}
+	}
+}
+
+func (g *Gossip) listen() {
+	buf := make([]byte, 1024)
+	for {
+		n, _, err := g.listener.ReadFromUDP(buf)
+		if err != nil {
+			select {
+			case <-g.done:
+			default:
+				log.Println("discovery: gossip read error:", err)
+			}
+			return
+		}
+		kind, member, ok := g.parse(string(buf[:n]))
+		if !ok || member == g.self {
+			continue
+		}
+
+		g.mu.Lock()
+		_, known := g.members[member]
+		switch kind {
+		case gossipAlive:
+			g.members[member] = time.Now()
+		case gossipLeave:
+			delete(g.members, member)
+		}
+		if known != (kind == gossipAlive) {
+			g.update(g.list())
+		}
+		g.mu.Unlock()
+	}
+}
+
+func (g *Gossip) expire() {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	changed := false
+	for member, seen := range g.members {
+		if time.Since(seen) > g.Timeout {
+			delete(g.members, member)
+			changed = true
+		}
+	}
+	if changed {
+		g.update(g.list())
+	}
+}
+
+// list must be called with the lock held
+func (g *Gossip) list() []string {
+	members := make([]string, 0, len(g.members))
+	for member := range g.members {
+		members = append(members, member)
+	}
+	sort.Strings(members)
+	return members
+}
+
+// an announcement is prefix:cluster:kind:node
+func (g *Gossip) send(kind string) {
+	message := strings.Join([]string{gossipPrefix, g.Cluster, kind, g.self}, ":")
+	if _, err := g.sender.Write([]byte(message)); err != nil {
+		select {
+		case <-g.done:
+		default:
+			log.Println("discovery: gossip write error:", err)
+		}
+	}
+}
+
+func (g *Gossip) parse(message string) (kind, member string, ok bool) {
+	parts := strings.SplitN(message, ":", 4)
+	if len(parts) != 4 || parts[0] != gossipPrefix || parts[1] != g.Cluster {
+		return "", "", false
+	}
+	if parts[2] != gossipAlive && parts[2] != gossipLeave {
+		return "", "", false
+	}
+	return parts[2], parts[3], true
+}
+
+// gossipInterface returns the interface with the given name, or the loopback one, along with its IPv4 address
+func gossipInterface(name string) (*net.Interface, net.IP, error) {
+	interfaces, err := net.Interfaces()
+	if err != nil {
+		return nil, nil, err
+	}
+	for i := range interfaces {
+		ifi := &interfaces[i]
+		if name != "" && ifi.Name != name {
+			continue
+		}
+		if name == "" && ifi.Flags&net.FlagLoopback == 0 {
+			continue
+		}
+		addresses, err := ifi.Addrs()
+		if err != nil {
+			return nil, nil, err
+		}
+		for _, address := range addresses {
+			if ip, ok := address.(*net.IPNet); ok && ip.IP.To4() != nil {
+				return ifi, ip.IP.To4(), nil
+			}
+		}
+	}
+	if name == "" {
+		return nil, nil, fmt.Errorf("no loopback interface with an IPv4 address found")
+	}
+	return nil, nil, fmt.Errorf("no interface %s with an IPv4 address found", name)
+}
@@ -0,0 +1,18 @@
+package discovery
+
+// Static is a fixed list of member nodes
+type Static struct {
+	Nodes []string
+}
+
+// NewStatic returns a strategy for the given nodes
+func NewStatic(nodes ...string) *Static {
+	return &Static{Nodes: nodes}
+}
+
+func (s *Static) Join(self string, update func(members []string)) error {
+	update(s.Nodes)
+	return nil
+}
+
+func (s *Static) Leave() {}
@@ -0,0 +1,136 @@
+package durable
+
+import (
+	"errors"
+	"fmt"
+	"github.com/hedisam/goactor/actor"
+	"github.com/hedisam/goactor/internal/persist"
+)
+
+// ErrRevisionConflict is returned by a store when the state has been written since it was read, by another actor
+// with the same persistence id for instance
+var ErrRevisionConflict = errors.New("durable: revision conflict")
+
+// Store stores the whole state of the durable actors. every write bumps the revision of the state, writes are only
+// accepted with the revision of the current state.
+type Store interface {
+	// Get returns the state along with its revision, a zero revision if there's none
+	Get(persistenceID string) (state interface{}, revision uint64, err error)
+	// Upsert writes the state if its current revision matches, and returns the new revision
+	Upsert(persistenceID string, revision uint64, state interface{}) (uint64, error)
+	// Delete deletes the state if its current revision matches
+	Delete(persistenceID string, revision uint64) error
+}
+
+// Config describes a durable state actor. the actor loads its state when it starts, a supervised restart included,
+// and writes the whole state back after each handled command, so a state changed in place is written too.
+type Config struct {
+	// PersistenceID identifies the actor's state in the store. if empty, the actor's first argument is used,
+	// which must be a string.
+	PersistenceID string
+	Store         Store
+	// Init returns the state of an actor that has none stored yet
+	Init func() interface{}
+	// Command handles the messages, changing the state through the context. returning false stops the actor.
+	Command func(c *Context, command interface{}) (loop bool)
+}
+
+// Context is passed to the command handler, it's the actor along with its durable state
+type Context struct {
+	*actor.Actor
+	config        Config
+	persistenceID string
+	state         interface{}
+	revision      uint64
+	deleted       bool
+}
+
+// Func returns the actor func of the durable state actor, to be spawned or used in a supervisor spec.
+// the actor panics if it can't load or write its state, a revision conflict included, so its supervisor
+// restarts it from the stored state.
+func Func(config Config) actor.Func {
+	return func(a *actor.Actor) {
+		c, err := load(a, config)
+		if err != nil {
+			panic(fmt.Errorf("durable: could not load %s: %v", c.persistenceID, err))
+		}
+		a.Receive(func(message interface{}) (loop bool) {
+			loop = config.Command(c, message)
+			if err := c.write(); err != nil {
+				panic(fmt.Errorf("durable: could not write %s: %v", c.persistenceID, err))
+			}
+			return loop
+		})
+	}
+}
+
+// PersistenceID returns the id the actor's state is stored under
+func (c *Context) PersistenceID() string {
+	return c.persistenceID
+}
+
+// State returns the current state
+func (c *Context) State() interface{} {
+	return c.state
+}
+
+// Revision returns the revision of the stored state, zero if it's not been stored yet
+func (c *Context) Revision() uint64 {
+	return c.revision
+}
+
+// SetState replaces the state, it's written to the store once the command has been handled
+func (c *Context) SetState(state interface{}) {
+	c.state = state
+	c.deleted = false
+}
+
+// Delete deletes the stored state once the command has been handled, the actor goes on with the initial state
+func (c *Context) Delete() {
+	c.state = initial(c.config)
+	c.deleted = true
+}
+
+func (c *Context) write() error {
+	if c.deleted {
+		c.deleted = false
+		if c.revision == 0 {
+			return nil
+		}
+		if err := c.config.Store.Delete(c.persistenceID, c.revision); err != nil {
+			return err
+		}
+		c.revision = 0
+		return nil
+	}
+	revision, err := c.config.Store.Upsert(c.persistenceID, c.revision, c.state)
+	if err != nil {
+		return err
+	}
+	c.revision = revision
+	return nil
+}
+
+func load(a *actor.Actor, config Config) (*Context, error) {
+	c := &Context{Actor: a, config: config}
+	var err error
+	if c.persistenceID, err = persist.ID(config.PersistenceID, a.Args()); err != nil {
+		return c, err
+	}
+	state, revision, err := config.Store.Get(c.persistenceID)
+	if err != nil {
+		return c, err
+	}
+	if revision == 0 {
+		state = initial(config)
+	}
+	c.state, c.revision = state, revision
+	return c, nil
+}
+
+func initial(config Config) interface{} {
+	if config.Init == nil {
+		return nil
+	}
+	return config.Init()
+}
@@ -0,0 +1,92 @@
+package durable
+
+import (
+	"github.com/hedisam/goactor/actor"
+	"github.com/hedisam/goactor/codec"
+	"github.com/hedisam/goactor/internal/pid"
+	"github.com/hedisam/goactor/sysmsg"
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+)
+
+// members is a state changed in place by its commands
+type members struct {
+	List []*pid.ProtectedPID
+}
+
+func init() {
+	codec.Register(&members{})
+}
+
+func TestFileStoreWritesEveryCommand(t *testing.T) {
+	dir, err := ioutil.TempDir("", "durable")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	store, err := NewFileStore(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	handled := make(chan struct{})
+	parent, dispose := actor.NewParentActor()
+	defer dispose()
+	ppid := parent.SpawnMonitor(Func(Config{
+		PersistenceID: "group",
+		Store:         store,
+		Init: func() interface{} {
+			return &members{}
+		},
+		Command: func(c *Context, command interface{}) bool {
+			// the state is changed without SetState
+			state := c.State().(*members)
+			state.List = append(state.List, command.(*pid.ProtectedPID))
+			handled <- struct{}{}
+			return len(state.List) < 2
+		},
+	}))
+	for _, id := range []string{"a", "b"} {
+		// the pids of actors from a previous run
+		actor.Send(ppid, pid.DecodeRef(pid.Ref{ID: id}))
+		select {
+		case <-handled:
+		case <-time.After(time.Second):
+			t.Fatal("the command was not handled")
+		}
+	}
+
+	parent.ReceiveWithTimeout(time.Second, func(message interface{}) (loop bool) {
+		if _, ok := message.(sysmsg.Exit); !ok {
+			t.Fatalf("expected the actor to stop, got %#v", message)
+		}
+		return false
+	})
+
+	// a new store on the same directory is what the restarted process opens
+	store, err = NewFileStore(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	state, revision, err := store.Get("group")
+	if err != nil {
+		t.Fatalf("could not read the state: %v", err)
+	}
+	if revision != 2 {
+		t.Fatalf("expected revision 2, got %d", revision)
+	}
+	if list := state.(*members).List; len(list) != 2 || pid.EncodeRef(list[1]).ID != "b" {
+		t.Fatalf("unexpected state %v", list)
+	}
+	if _, err := store.Upsert("group", 1, &members{}); err != ErrRevisionConflict {
+		t.Fatalf("expected a revision conflict, got %v", err)
+	}
+	if err := store.Delete("group", 2); err != nil {
+		t.Fatal(err)
+	}
+	if _, revision, err := store.Get("group"); err != nil || revision != 0 {
+		t.Fatalf("expected the state to be deleted, got revision %d: %v", revision, err)
+	}
+}
@@ -0,0 +1,176 @@
+package durable
+
+import (
+	"fmt"
+	"github.com/hedisam/goactor/codec"
+	"github.com/hedisam/goactor/internal/persist"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// record is a stored state along with its revision
+type record struct {
+	Revision uint64
+	State    interface{}
+}
+
+func init() {
+	codec.Register(record{})
+}
+
+// MemoryStore keeps the states in memory. they're kept as they are, so they shouldn't be modified in place once
+// they've been written.
+type MemoryStore struct {
+	mu      sync.RWMutex
+	records map[string]record
+}
+
+// NewMemoryStore returns an empty memory store
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{records: make(map[string]record)}
+}
+
+func (s *MemoryStore) Get(persistenceID string) (interface{}, uint64, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	r := s.records[persistenceID]
+	return r.State, r.Revision, nil
+}
+
+func (s *MemoryStore) Upsert(persistenceID string, revision uint64, state interface{}) (uint64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.records[persistenceID].Revision != revision {
+		return 0, ErrRevisionConflict
+	}
+	s.records[persistenceID] = record{Revision: revision + 1, State: state}
+	return revision + 1, nil
+}
+
+func (s *MemoryStore) Delete(persistenceID string, revision uint64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.records[persistenceID].Revision != revision {
+		return ErrRevisionConflict
+	}
+	delete(s.records, persistenceID)
+	return nil
+}
+
+// FileStore keeps each state in its own file in a directory, replaced atomically on every write. states are
+// encoded with codec.Gob, so their types must be registered with codec.Register. a file is only read the first
+// time its state is needed, the encoded states are kept in memory after, so the directory must not be shared by
+// two processes.
+type FileStore struct {
+	dir string
+	mu  sync.Mutex
+	// states are the states that have been read or written, a zero revision if there's none stored
+	states map[string]encodedState
+}
+
+// encodedState is a stored state as it's been written to its file
+type encodedState struct {
+	revision uint64
+	// data is the encoded record
+	data []byte
+}
+
+// NewFileStore opens the store in the directory, creating it if it doesn't exist
+func NewFileStore(dir string) (*FileStore, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	return &FileStore{dir: dir, states: make(map[string]encodedState)}, nil
+}
+
+func (s *FileStore) Get(persistenceID string) (interface{}, uint64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	state, err := s.load(persistenceID)
+	if err != nil || state.revision == 0 {
+		return nil, 0, err
+	}
+	r, err := decode(persistenceID, state.data)
+	return r.State, r.Revision, err
+}
+
+func (s *FileStore) Upsert(persistenceID string, revision uint64, state interface{}) (uint64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	current, err := s.load(persistenceID)
+	if err != nil {
+		return 0, err
+	}
+	if current.revision != revision {
+		return 0, ErrRevisionConflict
+	}
+	data, err := codec.Gob.Encode(record{Revision: revision + 1, State: state})
+	if err != nil {
+		return 0, err
+	}
+	if err := persist.Replace(s.path(persistenceID), data); err != nil {
+		return 0, err
+	}
+	s.states[persistenceID] = encodedState{revision: revision + 1, data: data}
+	return revision + 1, nil
+}
+
+func (s *FileStore) Delete(persistenceID string, revision uint64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	current, err := s.load(persistenceID)
+	if err != nil {
+		return err
+	}
+	if current.revision != revision {
+		return ErrRevisionConflict
+	}
+	err = os.Remove(s.path(persistenceID))
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	s.states[persistenceID] = encodedState{}
+	return nil
+}
+
+// load returns the state of the id, reading its file the first time.
+// it must be called with the lock held.
+func (s *FileStore) load(persistenceID string) (encodedState, error) {
+	if state, ok := s.states[persistenceID]; ok {
+		return state, nil
+	}
+	data, err := ioutil.ReadFile(s.path(persistenceID))
+	if os.IsNotExist(err) {
+		s.states[persistenceID] = encodedState{}
+		return encodedState{}, nil
+	}
+	if err != nil {
+		return encodedState{}, err
+	}
+	r, err := decode(persistenceID, data)
+	if err != nil {
+		return encodedState{}, err
+	}
+	state := encodedState{revision: r.Revision, data: data}
+	s.states[persistenceID] = state
+	return state, nil
+}
+
+func (s *FileStore) path(persistenceID string) string {
+	return filepath.Join(s.dir, url.PathEscape(persistenceID)+".state")
+}
+
+func decode(persistenceID string, data []byte) (record, error) {
+	value, err := codec.Unmarshal(codec.Gob, codec.Context{}, data)
+	if err != nil {
+		return record{}, err
+	}
+	r, ok := value.(record)
+	if !ok {
+		return record{}, fmt.Errorf("durable: invalid state file of %s", persistenceID)
+	}
+	return r, nil
+}
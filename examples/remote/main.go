@@ -0,0 +1,78 @@
+package main
+
+import (
+	"fmt"
+	"github.com/hedisam/goactor/actor"
+	"github.com/hedisam/goactor/internal/pid"
+	"github.com/hedisam/goactor/node"
+	"log"
+	"time"
+)
+
+type Ping struct {
+	Sender *pid.ProtectedPID
+	Text   string
+}
+
+type Pong struct {
+	From string
+	Text string
+}
+
+func main() {
+	node.RegisterMessage(Ping{})
+	node.RegisterMessage(Pong{})
+
+	// two nodes running in the same process, talking over tcp
+	a, err := node.Start(node.Config{Name: "a@127.0.0.1:0", Cookie: "secret"})
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer a.Stop()
+	b, err := node.Start(node.Config{Name: "b@127.0.0.1:0", Cookie: "secret"})
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer b.Stop()
+
+	ponger := actor.Spawn(pong, b.Name())
+	if err := actor.Register("ponger", ponger); err != nil {
+		log.Fatal(err)
+	}
+
+	if err := a.Connect(b.Name()); err != nil {
+		log.Fatal(err)
+	}
+	fmt.Println("[+] node a is connected to:", a.Nodes())
+
+	remote, err := a.WhereIs(b.Name(), "ponger")
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	future := actor.NewFutureActor()
+	actor.Send(remote, Ping{Sender: future.Self(), Text: "hello from node a"})
+	reply, err := future.RecvWithTimeout(time.Second)
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Printf("[+] reply: %+v\n", reply)
+
+	future = actor.NewFutureActor()
+	_ = a.SendNamed(b.Name(), "ponger", Ping{Sender: future.Self(), Text: "sent by name"})
+	reply, err = future.RecvWithTimeout(time.Second)
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Printf("[+] reply: %+v\n", reply)
+}
+
+func pong(a *actor.Actor) {
+	nodeName := a.Args()[0].(string)
+	a.Receive(func(message interface{}) (loop bool) {
+		if ping, ok := message.(Ping); ok {
+			actor.Send(ping.Sender, Pong{From: nodeName, Text: ping.Text})
+		}
+		return true
+	})
+}
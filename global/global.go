@@ -0,0 +1,152 @@
+package global
+
+import (
+	"fmt"
+	"github.com/hedisam/goactor/actor"
+	"github.com/hedisam/goactor/internal/pid"
+	"github.com/hedisam/goactor/node"
+	"sort"
+	"sync"
+)
+
+// Conflict is sent by the notifying resolvers to an actor that has lost its name
+type Conflict struct {
+	Name string
+	// Winner is the actor keeping the name, nil if none does
+	Winner *pid.ProtectedPID
+}
+
+func init() {
+	node.RegisterMessage(Conflict{})
+	node.RegisterMessage(registerName{})
+	node.RegisterMessage(unregisterName{})
+	node.RegisterMessage(resolvedName{})
+	node.RegisterMessage(syncNames{})
+}
+
+// entry is a registered name. Node is the node that registered it, the one responsible for it.
+type entry struct {
+	PID  *pid.ProtectedPID
+	Node string
+}
+
+// Registry is a cluster wide registry where a name maps to one actor across all the connected nodes.
+// each node runs its own Registry, the registrations are replicated to the other nodes.
+type Registry struct {
+	node     *node.Node
+	resolver Resolver
+	server   *pid.ProtectedPID
+
+	mu    sync.RWMutex
+	names map[string]entry
+}
+
+// Start starts the global registry of the node. the resolver decides who keeps a name registered on two nodes
+// at the same time, typically after a network partition heals. nil means RandomExit.
+func Start(n *node.Node, resolver Resolver) (*Registry, error) {
+	if resolver == nil {
+		resolver = RandomExit
+	}
+	r := &Registry{
+		node:     n,
+		resolver: resolver,
+		names:    make(map[string]entry),
+	}
+	r.server = actor.Spawn(server, r)
+	if err := actor.Register(serverName(n.Name()), r.server); err != nil {
+		actor.Send(r.server, cmdStop{})
+		return nil, err
+	}
+	return r, nil
+}
+
+// Register registers the actor under the name across the cluster
+func (r *Registry) Register(name string, ppid *pid.ProtectedPID) error {
+	r.mu.Lock()
+	if _, ok := r.names[name]; ok {
+		r.mu.Unlock()
+		return fmt.Errorf("name %s already registered", name)
+	}
+	r.names[name] = entry{PID: ppid, Node: r.node.Name()}
+	r.mu.Unlock()
+
+	actor.Send(r.server, cmdMonitor{pid: ppid})
+	r.broadcast(registerName{Name: name, PID: ppid, Node: r.node.Name()})
+	return nil
+}
+
+// Unregister removes the name across the cluster
+func (r *Registry) Unregister(name string) {
+	r.mu.Lock()
+	e, ok := r.names[name]
+	delete(r.names, name)
+	r.mu.Unlock()
+	if ok {
+		r.broadcast(unregisterName{Name: name, PID: e.PID})
+	}
+}
+
+// WhereIs returns the actor registered under the name, nil if there's none
+func (r *Registry) WhereIs(name string) *pid.ProtectedPID {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.names[name].PID
+}
+
+// Send sends the message to the actor registered under the name. it returns false if there's none.
+func (r *Registry) Send(name string, message interface{}) bool {
+	ppid := r.WhereIs(name)
+	if ppid == nil {
+		return false
+	}
+	actor.Send(ppid, message)
+	return true
+}
+
+// Names returns the registered names, sorted
+func (r *Registry) Names() []string {
+	r.mu.RLock()
+	names := make([]string, 0, len(r.names))
+	for name := range r.names {
+		names = append(names, name)
+	}
+	r.mu.RUnlock()
+	sort.Strings(names)
+	return names
+}
+
+// Stop stops replicating the registrations. the names stay visible on the other nodes until we disconnect.
+func (r *Registry) Stop() {
+	actor.Unregister(serverName(r.node.Name()))
+	actor.Send(r.server, cmdStop{})
+}
+
+// RegisterName implements actor.Resolver
+func (r *Registry) RegisterName(name string, ppid *pid.ProtectedPID) error {
+	return r.Register(name, ppid)
+}
+
+// UnregisterName implements actor.Resolver
+func (r *Registry) UnregisterName(name string) {
+	r.Unregister(name)
+}
+
+// WhereIsName implements actor.Resolver
+func (r *Registry) WhereIsName(name string) *pid.ProtectedPID {
+	return r.WhereIs(name)
+}
+
+// broadcast sends the message to the registry servers of all the connected nodes
+func (r *Registry) broadcast(message interface{}) {
+	for _, peer := range r.node.Nodes() {
+		r.sendTo(peer, message)
+	}
+}
+
+func (r *Registry) sendTo(peer string, message interface{}) {
+	_ = r.node.SendNamed(peer, serverName(peer), message)
+}
+
+func serverName(node string) string {
+	return "goactor.global@" + node
+}
@@ -0,0 +1,49 @@
+package global
+
+import (
+	"github.com/hedisam/goactor/internal/pid"
+	"github.com/hedisam/goactor/sysmsg"
+	"math/rand"
+)
+
+// Resolver decides which of the two actors registered under the same name keeps it. it's also responsible for
+// the loser, which it can notify with a Conflict message or kill. returning nil unregisters the name.
+// the resolver runs on only one of the two nodes that registered the name.
+type Resolver func(name string, a, b *pid.ProtectedPID) *pid.ProtectedPID
+
+var (
+	// RandomExit keeps the name for one of the actors, chosen randomly, and kills the other one
+	RandomExit Resolver = func(name string, a, b *pid.ProtectedPID) *pid.ProtectedPID {
+		winner, loser := random(a, b)
+		Kill(loser)
+		return winner
+	}
+
+	// RandomNotify keeps the name for one of the actors, chosen randomly, and notifies the other one
+	RandomNotify Resolver = func(name string, a, b *pid.ProtectedPID) *pid.ProtectedPID {
+		winner, loser := random(a, b)
+		pid.ExtractPID(loser).Mailbox().SendUserMessage(Conflict{Name: name, Winner: winner})
+		return winner
+	}
+
+	// NotifyAll unregisters the name and notifies both the actors
+	NotifyAll Resolver = func(name string, a, b *pid.ProtectedPID) *pid.ProtectedPID {
+		pid.ExtractPID(a).Mailbox().SendUserMessage(Conflict{Name: name})
+		pid.ExtractPID(b).Mailbox().SendUserMessage(Conflict{Name: name})
+		return nil
+	}
+)
+
+// Kill terminates the actor, local or remote, the same way a supervisor shuts down its children
+func Kill(ppid *pid.ProtectedPID) {
+	p := pid.ExtractPID(ppid)
+	p.Mailbox().SendSystemMessage(sysmsg.Shutdown{})
+	p.ShutdownFn()()
+}
+
+func random(a, b *pid.ProtectedPID) (winner, loser *pid.ProtectedPID) {
+	if rand.Intn(2) == 0 {
+		return a, b
+	}
+	return b, a
+}
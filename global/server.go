@@ -0,0 +1,195 @@
+package global
+
+import (
+	"github.com/hedisam/goactor/actor"
+	"github.com/hedisam/goactor/internal/pid"
+	"github.com/hedisam/goactor/sysmsg"
+	"log"
+	"sort"
+)
+
+// messages exchanged by the registry servers of the nodes
+
+type registerName struct {
+	Name string
+	PID  *pid.ProtectedPID
+	Node string
+}
+
+type unregisterName struct {
+	Name string
+	PID  *pid.ProtectedPID
+}
+
+// resolvedName is the outcome of a conflict, a nil PID means the name has been unregistered
+type resolvedName struct {
+	Name string
+	PID  *pid.ProtectedPID
+	Node string
+}
+
+// syncNames carries the names registered by a node to a node that has just connected
+type syncNames struct {
+	Names []registerName
+}
+
+// local commands
+
+type cmdMonitor struct {
+	pid *pid.ProtectedPID
+}
+
+type cmdStop struct{}
+
+// server replicates the registrations of its node and applies the ones of the other nodes
+func server(a *actor.Actor) {
+	r := a.Args()[0].(*Registry)
+	r.node.MonitorNodes(a.Self())
+	defer r.node.DemonitorNodes(a.Self())
+	monitored := make(map[pid.PID]struct{})
+
+	a.Receive(func(message interface{}) (loop bool) {
+		switch msg := message.(type) {
+		case registerName:
+			r.apply(msg)
+		case unregisterName:
+			r.remove(msg.Name, msg.PID)
+		case resolvedName:
+			r.mu.Lock()
+			if msg.PID == nil {
+				delete(r.names, msg.Name)
+			} else {
+				r.names[msg.Name] = entry{PID: msg.PID, Node: msg.Node}
+			}
+			r.mu.Unlock()
+		case syncNames:
+			for _, name := range msg.Names {
+				r.apply(name)
+			}
+		case cmdMonitor:
+			p := pid.ExtractPID(msg.pid)
+			if _, ok := monitored[p]; !ok {
+				monitored[p] = struct{}{}
+				a.Monitor(msg.pid)
+			}
+		case sysmsg.Exit:
+			p, ok := msg.Who.(pid.PID)
+			if !ok {
+				return true
+			}
+			delete(monitored, p)
+			r.exited(p)
+		case sysmsg.NodeUp:
+			r.sendTo(msg.Node, syncNames{Names: r.owned()})
+		case sysmsg.NodeDown:
+			r.nodeDown(msg.Node)
+		case cmdStop:
+			return false
+		default:
+			log.Printf("global: server received unknown message: %+v\n", message)
+		}
+		return true
+	})
+}
+
+// apply applies a registration made by another node
+func (r *Registry) apply(msg registerName) {
+	r.mu.Lock()
+	existing, ok := r.names[msg.Name]
+	// the same node registering the name again is not a conflict, it has unregistered the previous one
+	if !ok || existing.Node == msg.Node || pid.ExtractPID(existing.PID) == pid.ExtractPID(msg.PID) {
+		r.names[msg.Name] = entry{PID: msg.PID, Node: msg.Node}
+		r.mu.Unlock()
+		return
+	}
+	r.mu.Unlock()
+
+	// a conflict is resolved by the smaller of the two registering nodes, the other nodes keep what they have
+	// until they get the outcome
+	if r.node.Name() != minName(existing.Node, msg.Node) {
+		return
+	}
+	a, b := existing, entry{PID: msg.PID, Node: msg.Node}
+	// the resolver sees the actors in the same order no matter which registration arrived first
+	if b.Node < a.Node {
+		a, b = b, a
+	}
+	winner := r.resolver(msg.Name, a.PID, b.PID)
+
+	resolved := resolvedName{Name: msg.Name}
+	switch {
+	case winner == nil:
+	case pid.ExtractPID(winner) == pid.ExtractPID(a.PID):
+		resolved.PID, resolved.Node = a.PID, a.Node
+	case pid.ExtractPID(winner) == pid.ExtractPID(b.PID):
+		resolved.PID, resolved.Node = b.PID, b.Node
+	default:
+		log.Printf("global: resolver returned an unknown actor for %s, unregistering it\n", msg.Name)
+	}
+	r.mu.Lock()
+	if resolved.PID == nil {
+		delete(r.names, msg.Name)
+	} else {
+		r.names[msg.Name] = entry{PID: resolved.PID, Node: resolved.Node}
+	}
+	r.mu.Unlock()
+	r.broadcast(resolved)
+}
+
+// remove removes the name if it's still registered for the pid
+func (r *Registry) remove(name string, ppid *pid.ProtectedPID) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if e, ok := r.names[name]; ok && pid.ExtractPID(e.PID) == pid.ExtractPID(ppid) {
+		delete(r.names, name)
+	}
+}
+
+// exited unregisters the names of a terminated actor that were registered by us
+func (r *Registry) exited(p pid.PID) {
+	var names []string
+	r.mu.Lock()
+	for name, e := range r.names {
+		if pid.ExtractPID(e.PID) == p && e.Node == r.node.Name() {
+			delete(r.names, name)
+			names = append(names, name)
+		}
+	}
+	r.mu.Unlock()
+
+	for _, name := range names {
+		r.broadcast(unregisterName{Name: name, PID: pid.NewProtectedPID(p)})
+	}
+}
+
+// nodeDown drops the names registered by a disconnected node
+func (r *Registry) nodeDown(node string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for name, e := range r.names {
+		if e.Node == node {
+			delete(r.names, name)
+		}
+	}
+}
+
+// owned returns the names registered by our node
+func (r *Registry) owned() []registerName {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	var names []registerName
+	for name, e := range r.names {
+		if e.Node == r.node.Name() {
+			names = append(names, registerName{Name: name, PID: e.PID, Node: e.Node})
+		}
+	}
+	sort.Slice(names, func(i, j int) bool { return names[i].Name < names[j].Name })
+	return names
+}
+
+func minName(a, b string) string {
+	if a < b {
+		return a
+	}
+	return b
+}
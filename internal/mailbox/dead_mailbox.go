@@ -0,0 +1,40 @@
+package mailbox
+
+import (
+	"time"
+)
+
+// deadMailbox is the mailbox of an actor known only by a pid decoded after it had terminated. user messages are
+// dropped and the monitor/link requests are answered with the NoProc reason.
+type deadMailbox struct {
+	utils *ActorUtils
+}
+
+// NewDeadMailbox returns the mailbox of a terminated actor, utils only need Self
+func NewDeadMailbox(utils *ActorUtils) Mailbox {
+	return &deadMailbox{utils: utils}
+}
+
+func (m *deadMailbox) SendUserMessage(interface{}) {}
+
+func (m *deadMailbox) SendSystemMessage(message interface{}) {
+	handleLateSystemMessage(m, message)
+}
+
+func (m *deadMailbox) Receive(handler MessageHandler) {
+	handler(ErrDisposed("can't receive from a terminated actor's mailbox"))
+}
+
+func (m *deadMailbox) ReceiveWithTimeout(_ time.Duration, handler MessageHandler) {
+	handler(ErrDisposed("can't receive from a terminated actor's mailbox"))
+}
+
+func (m *deadMailbox) Dispose() {}
+
+func (m *deadMailbox) Utils() *ActorUtils {
+	return m.utils
+}
+
+func (m *deadMailbox) Len() int {
+	return 0
+}
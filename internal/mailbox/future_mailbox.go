@@ -2,6 +2,7 @@ package mailbox
 
 import (
 	"github.com/hedisam/goactor/sysmsg"
+	"sync"
 	"time"
 )
 
@@ -10,6 +11,7 @@ type ErrDisposed string
 type future struct {
 	m chan interface{}
 	done chan struct{}
+	disposeOnce sync.Once
 }
 
 func NewFutureMailbox() *future {
@@ -19,10 +21,13 @@ func NewFutureMailbox() *future {
 	}
 }
 
+// SendUserMessage never blocks, a future waits for a single message so the ones it has no room for are dropped.
+// otherwise a late reply or exit sent to an abandoned future would block its sender forever.
 func (f *future) SendUserMessage(message interface{}) {
 	select {
 	case <-f.done:
 	case f.m<- message:
+	default:
 	}
 }
 
@@ -50,8 +55,26 @@ func (f *future) ReceiveWithTimeout(d time.Duration, handler MessageHandler) {
 	}
 }
 
+// ReceiveUntil is Receive giving up with a Timeout once done is closed
+func (f *future) ReceiveUntil(done <-chan struct{}, handler MessageHandler) {
+	select {
+	case msg := <-f.m:
+		handler(msg)
+	case <-done:
+		handler(sysmsg.Timeout{})
+	case <-f.done:
+		handler(ErrDisposed("mailbox's channel is closed"))
+	}
+}
+
+func (f *future) Len() int {
+	return len(f.m)
+}
+
 func (f *future) Dispose() {
-	close(f.done)
+	f.disposeOnce.Do(func() {
+		close(f.done)
+	})
 }
 
 // Utils returns nil. DO NOT call me
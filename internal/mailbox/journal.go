@@ -0,0 +1,107 @@
+package mailbox
+
+import (
+	"github.com/Workiva/go-datastructures/queue"
+	"log"
+)
+
+// Journal is the log of a journaled mailbox. user messages are appended to it before they're queued and consumed
+// once the handler has returned, so the messages left unconsumed by a crashed actor can be fed to the next one.
+// it's called from the senders' goroutines as well as the actor's, so it must be safe for concurrent use.
+type Journal interface {
+	// Append logs the message and returns its sequence number
+	Append(message interface{}) (uint64, error)
+	// Consume marks the message as consumed
+	Consume(seq uint64) error
+	// Redeliver records a redelivery of an unconsumed message and returns the number of times it's been
+	// redelivered, this one included
+	Redeliver(seq uint64) (int, error)
+	// Unconsumed returns the messages that haven't been consumed, ordered by sequence number
+	Unconsumed() ([]Record, error)
+}
+
+// Record is a journaled message
+type Record struct {
+	Seq     uint64
+	Message interface{}
+}
+
+// maxRedeliveries is the number of times an unconsumed message is fed to the next actors before it's dropped. a
+// message that keeps making the handler panic would take the actors down one after the other otherwise.
+const maxRedeliveries = 2
+
+// journaled is a queued message that's consumed in the journal once it's been handled
+type journaled struct {
+	seq     uint64
+	message interface{}
+	// redelivered is true if the message has been left unconsumed by a previous actor
+	redelivered bool
+}
+
+// NewJournaledMailbox returns a ring buffer mailbox that journals its user messages. the unconsumed messages of the
+// journal are queued first, messages sent in the meantime wait for them.
+func NewJournaledMailbox(utils *ActorUtils, journal Journal) Mailbox {
+	m := &queueMailbox{
+		userMailbox: queue.NewRingBuffer(defaultUserMailboxCap),
+		sysMailbox:  queue.NewRingBuffer(defaultSysMailboxCap),
+		done:        make(chan struct{}),
+		status:      mailboxIdle,
+		signal:      make(chan struct{}, 10),
+		utils:       utils,
+		journal:     journal,
+		replayed:    make(chan struct{}),
+	}
+	records, err := journal.Unconsumed()
+	if err != nil {
+		log.Println("journaled mailbox: could not read the unconsumed messages:", err)
+	}
+	// the ring buffer might not fit them all, it's emptied once the actor starts receiving
+	go func() {
+		defer close(m.replayed)
+		for _, r := range records {
+			m.put(journaled{seq: r.Seq, message: r.Message, redelivered: true})
+		}
+	}()
+	return m
+}
+
+// append journals the message. a message that can't be journaled is still delivered, it's just not recovered.
+func (m *queueMailbox) append(message interface{}) interface{} {
+	seq, err := m.journal.Append(message)
+	if err != nil {
+		log.Println("journaled mailbox: could not journal the message:", err)
+		return message
+	}
+	return journaled{seq: seq, message: message}
+}
+
+// handle passes the message to the handler, consuming it in the journal afterwards. a message is consumed even if
+// the handler stops receiving, but not if it panics. a redelivered message is dropped once it's reached the max
+// redeliveries.
+func (m *queueMailbox) handle(handler MessageHandler, message interface{}) bool {
+	j, ok := message.(journaled)
+	if !ok {
+		return handler(message)
+	}
+	if j.redelivered {
+		redeliveries, err := m.journal.Redeliver(j.seq)
+		if err != nil {
+			log.Println("journaled mailbox: could not record the redelivery:", err)
+		}
+		if redeliveries > maxRedeliveries {
+			log.Printf("journaled mailbox: dropped message %d after %d redeliveries: %+v\n", j.seq, maxRedeliveries,
+				j.message)
+			m.consume(j.seq)
+			return true
+		}
+	}
+	keepOn := handler(j.message)
+	m.consume(j.seq)
+	return keepOn
+}
+
+func (m *queueMailbox) consume(seq uint64) {
+	if err := m.journal.Consume(seq); err != nil {
+		log.Println("journaled mailbox: could not consume the message:", err)
+	}
+}
@@ -21,6 +21,8 @@ type Mailbox interface {
 	ReceiveWithTimeout(d time.Duration, handler MessageHandler)
 	Dispose()
 	Utils() *ActorUtils
+	// Len returns the number of messages waiting in the mailbox
+	Len() int
 }
 
 type ActorUtils struct {
@@ -37,9 +37,16 @@ func (m *channelMailbox) SendUserMessage(message interface{}) {
 func (m *channelMailbox) SendSystemMessage(message interface{}) {
 	select {
 	case <-m.done:
+		handleLateSystemMessage(m, message)
 		return
 	case m.sysMailbox <- message:
 	}
+	select {
+	case <-m.done:
+		// we might have lost the race with Dispose's drain. answering twice is better than not answering at all.
+		handleLateSystemMessage(m, message)
+	default:
+	}
 }
 
 func (m *channelMailbox) Receive(handler MessageHandler) {
@@ -101,8 +108,20 @@ loop:
 
 }
 
+func (m *channelMailbox) Len() int {
+	return len(m.userMailbox) + len(m.sysMailbox)
+}
+
 func (m *channelMailbox) Dispose() {
 	close(m.done)
+	for {
+		select {
+		case msg := <-m.sysMailbox:
+			drainSystemMessage(m, msg)
+		default:
+			return
+		}
+	}
 }
 
 func resetTimer(timer *time.Timer, d time.Duration, triggered bool) {
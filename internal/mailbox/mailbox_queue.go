@@ -15,6 +15,10 @@ type queueMailbox struct {
 	status      int32
 	signal      chan struct{}
 	utils       *ActorUtils
+	// journal is nil unless the mailbox is journaled, see NewJournaledMailbox
+	journal Journal
+	// replayed is closed once the unconsumed messages of the journal have been queued
+	replayed chan struct{}
 }
 
 func DefaultRingBufferQueueMailbox(utils *ActorUtils) Mailbox {
@@ -34,11 +38,28 @@ func (m *queueMailbox) Utils() *ActorUtils {
 }
 
 func (m *queueMailbox) SendUserMessage(message interface{}) {
+	if m.journal == nil {
+		m.put(message)
+		return
+	}
+	select {
+	case <-m.done:
+		return
+	case <-m.replayed:
+	}
+	m.put(m.append(message))
+}
+
+func (m *queueMailbox) put(message interface{}) {
 	select {
 	case <-m.done:
 		return
 	default:
 		err := m.userMailbox.Put(message)
+		if err == queue.ErrDisposed {
+			// a journaled mailbox has been disposed while we were waiting for room, the message is left unconsumed
+			return
+		}
 		if err != nil {
 			log.Println("queue_mailbox put error:", err)
 			return
@@ -54,7 +75,19 @@ func (m *queueMailbox) SendUserMessage(message interface{}) {
 }
 
 func (m *queueMailbox) SendSystemMessage(message interface{}) {
-	m.SendUserMessage(message)
+	select {
+	case <-m.done:
+		handleLateSystemMessage(m, message)
+		return
+	default:
+	}
+	m.put(message)
+	select {
+	case <-m.done:
+		// we might have lost the race with Dispose's drain. answering twice is better than not answering at all.
+		handleLateSystemMessage(m, message)
+	default:
+	}
 }
 
 func (m *queueMailbox) Receive(handler MessageHandler) {
@@ -73,19 +106,19 @@ listen:
 				if pass {
 					keepOn := handler(msg)
 					if !keepOn {
-						atomic.StoreInt32(&m.status, mailboxIdle)
+						m.idle()
 						return
 					}
 				}
 			default:
-				keepOn := handler(msg)
+				keepOn := m.handle(handler, msg)
 				if !keepOn {
-					atomic.StoreInt32(&m.status, mailboxIdle)
+					m.idle()
 					return
 				}
 			}
 		}
-		atomic.StoreInt32(&m.status, mailboxIdle)
+		m.idle()
 		goto listen
 	}
 }
@@ -106,19 +139,19 @@ listen:
 				if pass {
 					keepOn := handler(msg)
 					if !keepOn {
-						atomic.StoreInt32(&m.status, mailboxIdle)
+						m.idle()
 						return
 					}
 				}
 			default:
-				keepOn := handler(msg)
+				keepOn := m.handle(handler, msg)
 				if !keepOn {
-					atomic.StoreInt32(&m.status, mailboxIdle)
+					m.idle()
 					return
 				}
 			}
 		}
-		atomic.StoreInt32(&m.status, mailboxIdle)
+		m.idle()
 		resetTimer(timer, d, false)
 		goto listen
 	case <-timer.C:
@@ -131,6 +164,33 @@ listen:
 	}
 }
 
+// idle marks the mailbox as idle. messages that have been left in the queue, either because the handler stopped
+// receiving or because they were put right before going idle, signal again so they don't wait for the next one.
+func (m *queueMailbox) idle() {
+	atomic.StoreInt32(&m.status, mailboxIdle)
+	if m.userMailbox.Len() != 0 && atomic.CompareAndSwapInt32(&m.status, mailboxIdle, mailboxProcessing) {
+		select {
+		case m.signal <- struct{}{}:
+		default:
+		}
+	}
+}
+
+func (m *queueMailbox) Len() int {
+	return int(m.userMailbox.Len())
+}
+
 func (m *queueMailbox) Dispose() {
 	close(m.done)
+	for m.userMailbox.Len() != 0 {
+		msg, err := m.userMailbox.Get()
+		if err != nil {
+			return
+		}
+		drainSystemMessage(m, msg)
+	}
+	if m.journal != nil {
+		// unblocks the replay if it's still queueing, the unconsumed messages are left to the next mailbox
+		m.userMailbox.Dispose()
+	}
 }
@@ -17,7 +17,7 @@ func handleSystemMessage(m Mailbox, message interface{}) (bool, sysmsg.SystemMes
 				return true, msg
 			}
 			switch msg.Reason.Type {
-			case sysmsg.Kill, sysmsg.Panic:
+			case sysmsg.Kill, sysmsg.Panic, sysmsg.NoConnection:
 				panic(sysmsg.Exit{
 					Who:      m.Utils().Self(),
 					Parent:   msg.Who,
@@ -53,12 +53,61 @@ func handleSystemMessage(m Mailbox, message interface{}) (bool, sysmsg.SystemMes
 		} else {
 			m.Utils().Link(msg.To)
 		}
+	case sysmsg.NodeUp:
+		return true, msg
+	case sysmsg.NodeDown:
+		return true, msg
+	case sysmsg.MemberJoined:
+		return true, msg
+	case sysmsg.MemberLeft:
+		return true, msg
 	default:
 		log.Println("mailbox: unknown sys message", msg)
 	}
 	return false, nil
 }
 
+// handleLateSystemMessage handles a monitor/link request that has reached a terminated actor by sending
+// back an Exit with the NoProc reason
+func handleLateSystemMessage(m Mailbox, message interface{}) {
+	var requester interface{}
+	var relation sysmsg.Relation
+	switch msg := message.(type) {
+	case sysmsg.Monitor:
+		if msg.Revert {
+			return
+		}
+		requester, relation = msg.Parent, sysmsg.Monitored
+	case sysmsg.Link:
+		if msg.Revert {
+			return
+		}
+		requester, relation = msg.To, sysmsg.Linked
+	default:
+		return
+	}
+
+	// the requester is a pid.PID which we can't import here
+	owner, ok := requester.(interface{ Mailbox() Mailbox })
+	if !ok {
+		return
+	}
+	owner.Mailbox().SendSystemMessage(sysmsg.Exit{
+		Who:      m.Utils().Self(),
+		Reason:   sysmsg.Reason{Type: sysmsg.NoProc},
+		Relation: relation,
+	})
+}
+
+// drainSystemMessage is called for the messages left in a disposed mailbox. pending monitor/link requests are
+// applied so the terminating actor notifies the requesters with its actual exit reason.
+func drainSystemMessage(m Mailbox, message interface{}) {
+	switch message.(type) {
+	case sysmsg.Monitor, sysmsg.Link:
+		handleSystemMessage(m, message)
+	}
+}
+
 func checkContext(m Mailbox) {
 	select {
 	case <-m.Utils().ContextDone():
@@ -82,7 +131,7 @@ func checkContext(m Mailbox) {
 		} else {
 			// we don't have a ref to the parent supervisor
 			panic(sysmsg.Exit{
-				Who:      m.Utils().Self,
+				Who:      m.Utils().Self(),
 				Parent:   nil,
 				Reason:   sysmsg.Reason{
 					Type:    sysmsg.Kill,
@@ -0,0 +1,134 @@
+package persist
+
+import (
+	"bufio"
+	"encoding/binary"
+	"github.com/hedisam/goactor/codec"
+	"io"
+	"io/ioutil"
+	"os"
+)
+
+// WriteRecord writes the value as a record, the length of its encoding followed by the value encoded with codec.Gob
+func WriteRecord(w io.Writer, value interface{}) error {
+	data, err := codec.Gob.Encode(value)
+	if err != nil {
+		return err
+	}
+	var size [4]byte
+	binary.BigEndian.PutUint32(size[:], uint32(len(data)))
+	if _, err := w.Write(append(size[:], data...)); err != nil {
+		return err
+	}
+	return nil
+}
+
+// ReadRecord reads a record and returns its value along with its size. it fails with io.EOF at the end of the
+// records, or io.ErrUnexpectedEOF if the last record is incomplete.
+// the pids of the actors that have terminated since the record was written, all of them after a restart, are
+// decoded as dead pids.
+func ReadRecord(r io.Reader) (interface{}, int64, error) {
+	var size [4]byte
+	if _, err := io.ReadFull(r, size[:]); err != nil {
+		return nil, 0, err
+	}
+	data := make([]byte, binary.BigEndian.Uint32(size[:]))
+	if _, err := io.ReadFull(r, data); err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return nil, 0, err
+	}
+	value, err := codec.Unmarshal(codec.Gob, codec.Context{}, data)
+	if err != nil {
+		return nil, 0, err
+	}
+	return value, int64(len(size) + len(data)), nil
+}
+
+// Scan calls fn with the values of the file's records and returns the size of its complete records, a missing file
+// has none. the scan stops at an incomplete record, which is what a crash in the middle of an append leaves behind.
+func Scan(path string, fn func(value interface{}) error) (int64, error) {
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+
+	var size int64
+	r := bufio.NewReader(f)
+	for {
+		value, n, err := ReadRecord(r)
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			return size, nil
+		}
+		if err != nil {
+			return size, err
+		}
+		if err := fn(value); err != nil {
+			return size, err
+		}
+		size += n
+	}
+}
+
+// Rewrite writes a new file with write, then atomically replaces the file with it. the new file is returned open
+// for appending.
+func Rewrite(path string, write func(w io.Writer) error) (*os.File, error) {
+	f, err := os.OpenFile(path+".tmp", os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0644)
+	if err != nil {
+		return nil, err
+	}
+	w := bufio.NewWriter(f)
+	err = write(w)
+	if err == nil {
+		err = w.Flush()
+	}
+	if err == nil {
+		err = f.Sync()
+	}
+	if err == nil {
+		err = os.Rename(path+".tmp", path)
+	}
+	if err != nil {
+		_ = f.Close()
+		return nil, err
+	}
+	return f, nil
+}
+
+// Replace atomically replaces the file with the data
+func Replace(path string, data []byte) error {
+	f, err := Rewrite(path, func(w io.Writer) error {
+		_, err := w.Write(data)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	return f.Close()
+}
+
+// WriteValue atomically replaces the file with the value encoded with codec.Gob
+func WriteValue(path string, value interface{}) error {
+	data, err := codec.Gob.Encode(value)
+	if err != nil {
+		return err
+	}
+	return Replace(path, data)
+}
+
+// ReadValue returns the value of a file written by WriteValue, nil if the file doesn't exist
+func ReadValue(path string) (interface{}, error) {
+	data, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return codec.Unmarshal(codec.Gob, codec.Context{}, data)
+}
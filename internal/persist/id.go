@@ -0,0 +1,18 @@
+package persist
+
+import "fmt"
+
+// ID returns the configured persistence id, or the actor's first argument if there's none
+func ID(configured string, args []interface{}) (string, error) {
+	if configured != "" {
+		return configured, nil
+	}
+	if len(args) == 0 {
+		return "", fmt.Errorf("no persistence id")
+	}
+	id, ok := args[0].(string)
+	if !ok {
+		return "", fmt.Errorf("the first argument is not a persistence id")
+	}
+	return id, nil
+}
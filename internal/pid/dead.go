@@ -0,0 +1,43 @@
+package pid
+
+import "github.com/hedisam/goactor/internal/mailbox"
+
+// deadPID is the pid of a decoded ref that can't be resolved, its actor has terminated or it's not reachable
+// from here. messages sent to it are dropped, monitoring or linking it fires a NoProc exit. it's encoded as
+// its ref still.
+type deadPID struct {
+	ref Ref
+	m   mailbox.Mailbox
+}
+
+func newDeadPID(ref Ref) *deadPID {
+	p := &deadPID{ref: ref}
+	p.m = mailbox.NewDeadMailbox(&mailbox.ActorUtils{Self: func() interface{} { return p }})
+	return p
+}
+
+func (p *deadPID) Ref() Ref {
+	return p.ref
+}
+
+func (p *deadPID) Mailbox() mailbox.Mailbox {
+	return p.m
+}
+
+func (p *deadPID) ShutdownFn() func() {
+	return func() {}
+}
+
+func (p *deadPID) SetShutdownFn(func()) {}
+
+func (p *deadPID) SetActorTypeFn(func(int32)) {}
+
+func (p *deadPID) ActorTypeFn() func(int32) {
+	return func(int32) {}
+}
+
+func (p *deadPID) SetSupervisorFn(func(PID)) {}
+
+func (p *deadPID) SupervisorFn() func(PID) {
+	return func(PID) {}
+}
@@ -6,6 +6,7 @@ type futurePID struct {
 	mailbox mailbox.Mailbox
 	shutdown func()
 	actorType func(int32)
+	exported exportInfo
 }
 
 func NewFuturePID() *futurePID {
@@ -42,3 +43,7 @@ func (f *futurePID) SetSupervisorFn(fn func(pid PID)) {
 func (f *futurePID) SupervisorFn() func(pid PID) {
 	return nil
 }
+
+func (f *futurePID) export() *exportInfo {
+	return &f.exported
+}
@@ -7,6 +7,7 @@ type localPID struct {
 	shutdown func()
 	actorType func(int32)
 	supervisorSetter func(PID)
+	exported exportInfo
 }
 
 func NewPID(utils *mailbox.ActorUtils) *localPID {
@@ -15,6 +16,13 @@ func NewPID(utils *mailbox.ActorUtils) *localPID {
 	}
 }
 
+// NewJournaledPID returns the pid of an actor whose mailbox is journaled, see mailbox.NewJournaledMailbox
+func NewJournaledPID(utils *mailbox.ActorUtils, journal mailbox.Journal) *localPID {
+	return &localPID{
+		m: mailbox.NewJournaledMailbox(utils, journal),
+	}
+}
+
 func (pid *localPID) Mailbox() mailbox.Mailbox {
 	return pid.m
 }
@@ -42,3 +50,7 @@ func (pid *localPID) SetSupervisorFn(fn func(pid PID)) {
 func (pid *localPID) SupervisorFn() func(pid PID) {
 	return pid.supervisorSetter
 }
+
+func (pid *localPID) export() *exportInfo {
+	return &pid.exported
+}
@@ -22,6 +22,8 @@ type PID interface {
 
 type ProtectedPID struct {
 	pid PID
+	// ref is the ref of a decoded pid until it's resolved
+	ref *Ref
 }
 
 func NewProtectedPID(pid PID) *ProtectedPID {
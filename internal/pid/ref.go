@@ -0,0 +1,180 @@
+package pid
+
+import (
+	"bytes"
+	"encoding/gob"
+	"encoding/json"
+	"github.com/rs/xid"
+	"sync"
+)
+
+// Ref is the location independent form of a pid, the one a pid is encoded to when it leaves the process
+type Ref struct {
+	// Node is the name of the node the actor lives on, empty if it's not been exported through a node
+	Node string `json:"node"`
+	ID   string `json:"id"`
+}
+
+// RemotePID is implemented by the pids of actors that live on other nodes
+type RemotePID interface {
+	PID
+	Ref() Ref
+}
+
+// CodecContext tells how the decoded pids are resolved. pids are encoded the same way everywhere, a local pid as
+// a ref without a node, so only the decoding side needs one.
+type CodecContext struct {
+	// Peer is the node the decoded values come from, the refs without a node are its pids
+	Peer string
+	// Resolve returns the pid of a decoded ref. a nil Resolve resolves the local pids, a ref that's not one
+	// of them is decoded as the pid of a terminated actor.
+	Resolve func(ref Ref) (PID, error)
+}
+
+var (
+	// exported local pids by their id. a local pid gets an id the first time it's encoded.
+	exportMu sync.RWMutex
+	exported = make(map[string]PID)
+)
+
+func (r Ref) String() string {
+	return r.Node + "/" + r.ID
+}
+
+// RefOf returns the ref of the pid. a local pid is exported under the given local node name.
+func RefOf(local string, pid PID) Ref {
+	if remote, ok := pid.(RemotePID); ok {
+		return remote.Ref()
+	}
+	return Ref{Node: local, ID: export(pid)}
+}
+
+// Lookup returns the exported local pid with the id
+func Lookup(id string) (PID, bool) {
+	exportMu.RLock()
+	defer exportMu.RUnlock()
+	pid, ok := exported[id]
+	return pid, ok
+}
+
+// Release removes a terminated actor from the exported pids. it keeps its id, so it's still encoded as the
+// same ref, it just can't be resolved anymore.
+func Release(pid PID) {
+	e, ok := pid.(exportable)
+	if !ok {
+		return
+	}
+	exportMu.Lock()
+	defer exportMu.Unlock()
+	info := e.export()
+	info.released = true
+	if info.id != "" {
+		delete(exported, info.id)
+	}
+}
+
+// exportable is implemented by the local pids, they keep their own export info
+type exportable interface {
+	export() *exportInfo
+}
+
+type exportInfo struct {
+	id       string
+	released bool
+}
+
+func export(pid PID) string {
+	e, ok := pid.(exportable)
+	if !ok {
+		return ""
+	}
+	exportMu.Lock()
+	defer exportMu.Unlock()
+	info := e.export()
+	if info.id == "" {
+		info.id = xid.New().String()
+		if !info.released {
+			exported[info.id] = pid
+		}
+	}
+	return info.id
+}
+
+// ResolvePID resolves a decoded pid, it does nothing to a pid that's already resolved
+func (ctx CodecContext) ResolvePID(p *ProtectedPID) error {
+	if p.ref == nil {
+		return nil
+	}
+	ref := *p.ref
+	if ref.Node == "" {
+		ref.Node = ctx.Peer
+	}
+	pid, err := ctx.resolve(ref)
+	if err != nil {
+		return err
+	}
+	p.pid, p.ref = pid, nil
+	return nil
+}
+
+func (ctx CodecContext) resolve(ref Ref) (PID, error) {
+	if ctx.Resolve != nil {
+		return ctx.Resolve(ref)
+	}
+	// the values read back by a process outlive its actors, a stored pid is most likely a dead one
+	if ref.Node != "" {
+		return newDeadPID(ref), nil
+	}
+	pid, ok := Lookup(ref.ID)
+	if !ok {
+		return newDeadPID(ref), nil
+	}
+	return pid, nil
+}
+
+// EncodeRef returns the ref the pid is encoded as
+func EncodeRef(p *ProtectedPID) Ref {
+	if p.ref != nil {
+		return *p.ref
+	}
+	return RefOf("", p.pid)
+}
+
+// DecodeRef returns the pid of a decoded ref, it must be resolved with CodecContext.ResolvePID before being used
+func DecodeRef(ref Ref) *ProtectedPID {
+	return &ProtectedPID{ref: &ref}
+}
+
+// GobEncode encodes the pid as its Ref
+func (p *ProtectedPID) GobEncode() ([]byte, error) {
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(EncodeRef(p)); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// GobDecode decodes a Ref, to be resolved to a local or remote pid
+func (p *ProtectedPID) GobDecode(data []byte) error {
+	var ref Ref
+	if err := gob.NewDecoder(bytes.NewReader(data)).Decode(&ref); err != nil {
+		return err
+	}
+	p.pid, p.ref = nil, &ref
+	return nil
+}
+
+// MarshalJSON encodes the pid as its Ref
+func (p *ProtectedPID) MarshalJSON() ([]byte, error) {
+	return json.Marshal(EncodeRef(p))
+}
+
+// UnmarshalJSON decodes a Ref, to be resolved to a local or remote pid
+func (p *ProtectedPID) UnmarshalJSON(data []byte) error {
+	var ref Ref
+	if err := json.Unmarshal(data, &ref); err != nil {
+		return err
+	}
+	p.pid, p.ref = nil, &ref
+	return nil
+}
@@ -0,0 +1,73 @@
+package remote
+
+import (
+	"fmt"
+	"github.com/hedisam/goactor/internal/pid"
+	"sync"
+)
+
+// Node is a local node as seen by the actor package, implemented by the node package. it lets the actor
+// package reach other nodes without importing the node package.
+type Node interface {
+	Name() string
+	Connected(node string) bool
+	Spawn(node string, request SpawnRequest) (pid.PID, error)
+}
+
+// SpawnRequest asks a node to spawn an actor from its spawn table
+type SpawnRequest struct {
+	Func string
+	Args []interface{}
+	// Link is the actor the spawned one is linked to, if any
+	Link pid.PID
+	// Monitor is the actor monitoring the spawned one, if any
+	Monitor pid.PID
+}
+
+// SpawnLocal spawns an actor from the local spawn table. it's set by the actor package.
+var SpawnLocal func(request SpawnRequest) (pid.PID, error)
+
+var nodes = struct {
+	sync.RWMutex
+	list []Node
+}{}
+
+// Register is called by a node when it starts
+func Register(n Node) {
+	nodes.Lock()
+	defer nodes.Unlock()
+	nodes.list = append(nodes.list, n)
+}
+
+// Unregister is called by a node when it stops
+func Unregister(n Node) {
+	nodes.Lock()
+	defer nodes.Unlock()
+	for i, node := range nodes.list {
+		if node == n {
+			nodes.list = append(nodes.list[:i], nodes.list[i+1:]...)
+			return
+		}
+	}
+}
+
+// Route returns the local node to reach the given node through. it's nil if the given node is a local one.
+// a local node already connected to it is preferred.
+func Route(node string) (Node, error) {
+	nodes.RLock()
+	defer nodes.RUnlock()
+	if len(nodes.list) == 0 {
+		return nil, fmt.Errorf("no local node has been started")
+	}
+	for _, n := range nodes.list {
+		if n.Name() == node {
+			return nil, nil
+		}
+	}
+	for _, n := range nodes.list {
+		if n.Connected(node) {
+			return n, nil
+		}
+	}
+	return nodes.list[0], nil
+}
@@ -0,0 +1,229 @@
+package node
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"encoding/gob"
+	"fmt"
+	"github.com/hedisam/goactor/actor"
+	"github.com/hedisam/goactor/codec"
+	"github.com/hedisam/goactor/internal/pid"
+	"io"
+	"log"
+	"net"
+	"sync"
+	"time"
+)
+
+// maxFrameSize protects a node from a peer announcing a huge frame
+const maxFrameSize = 64 << 20
+
+const (
+	frameSend frameKind = iota + 1
+	frameSendNamed
+	frameWhereIs
+	frameWhereIsReply
+	frameShutdown
+	frameSystem
+	frameHeartbeat
+	frameSpawn
+	frameSpawnReply
+)
+
+type frameKind uint8
+
+// frame is the unit of the node-to-node protocol
+type frame struct {
+	Kind frameKind
+	// To is the id of the target actor on the receiving node
+	To string
+	// Name is the registered name of the target actor
+	Name string
+	// Ref correlates a request with its reply
+	Ref    uint64
+	PID    *pid.ProtectedPID
+	Error  string
+	System *systemMessage
+	Spawn  *spawnRequest
+	// Payload is the message encoded by the node's codec
+	Payload []byte
+}
+
+type conn struct {
+	node *Node
+	peer string
+	// outbound is true if we dialed the connection
+	outbound bool
+	nc       net.Conn
+	r        *bufio.Reader
+	// deliveries hands the messages read to our actors
+	deliveries *deliverer
+
+	wmu sync.Mutex
+	w   *bufio.Writer
+
+	closeOnce sync.Once
+	done      chan struct{}
+}
+
+func newConn(n *Node, nc net.Conn, outbound bool) *conn {
+	return &conn{
+		node:       n,
+		outbound:   outbound,
+		nc:         nc,
+		r:          bufio.NewReader(nc),
+		deliveries: newDeliverer(),
+		w:          bufio.NewWriter(nc),
+		done:       make(chan struct{}),
+	}
+}
+
+// send encodes and writes the frame along with the message. the peer takes the pids inside them that have no
+// node for ours.
+func (c *conn) send(f frame, message interface{}) error {
+	var buf bytes.Buffer
+	var err error
+	if message != nil {
+		f.Payload, err = c.node.codec.Encode(message)
+	}
+	if err == nil {
+		err = gob.NewEncoder(&buf).Encode(&f)
+	}
+	if err != nil {
+		return fmt.Errorf("could not encode message for node %s: %v", c.peer, err)
+	}
+	return c.write(buf.Bytes())
+}
+
+func (c *conn) write(data []byte) error {
+	c.wmu.Lock()
+	defer c.wmu.Unlock()
+	var size [4]byte
+	binary.BigEndian.PutUint32(size[:], uint32(len(data)))
+	if _, err := c.w.Write(size[:]); err != nil {
+		c.close()
+		return err
+	}
+	if _, err := c.w.Write(data); err != nil {
+		c.close()
+		return err
+	}
+	if err := c.w.Flush(); err != nil {
+		c.close()
+		return err
+	}
+	return nil
+}
+
+func (c *conn) read() ([]byte, error) {
+	var size [4]byte
+	if _, err := io.ReadFull(c.r, size[:]); err != nil {
+		return nil, err
+	}
+	n := binary.BigEndian.Uint32(size[:])
+	if n > maxFrameSize {
+		return nil, fmt.Errorf("frame too large: %d bytes", n)
+	}
+	data := make([]byte, n)
+	if _, err := io.ReadFull(c.r, data); err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+func (c *conn) readLoop() {
+	defer c.close()
+	// the pids of the frames and the messages are resolved as the peer's
+	ctx := c.node.codecContext(c.peer)
+	messages := codec.WithContext(c.node.codec, ctx)
+	for {
+		// the peer sends heartbeats, so a silent connection is a dead one
+		_ = c.nc.SetReadDeadline(time.Now().Add(c.node.heartbeatTimeout))
+		data, err := c.read()
+		if err != nil {
+			return
+		}
+		var f frame
+		var message interface{}
+		err = gob.NewDecoder(bytes.NewReader(data)).Decode(&f)
+		if err == nil {
+			err = codec.Resolve(ctx, &f)
+		}
+		if err == nil && f.Payload != nil {
+			message, err = messages.Decode(f.Payload)
+		}
+		if err != nil {
+			log.Printf("node: dropped a frame from %s: %v\n", c.peer, err)
+			continue
+		}
+		c.handle(f, message)
+	}
+}
+
+func (c *conn) handle(f frame, message interface{}) {
+	switch f.Kind {
+	case frameSend:
+		if local, ok := pid.Lookup(f.To); ok {
+			c.deliver(local, message)
+		}
+	case frameSendNamed:
+		if ppid := actor.Resolve(f.Name); ppid != nil {
+			c.deliver(pid.ExtractPID(ppid), message)
+		}
+	case frameWhereIs:
+		_ = c.send(frame{Kind: frameWhereIsReply, Ref: f.Ref, PID: actor.WhereIs(f.Name)}, nil)
+	case frameWhereIsReply:
+		c.node.reply(f)
+	case frameSystem:
+		if f.System != nil {
+			c.node.receiveSystem(c, f.To, f.System)
+		}
+	case frameSpawn:
+		if f.Spawn != nil {
+			c.node.receiveSpawn(c, f, message)
+		}
+	case frameSpawnReply:
+		c.node.reply(f)
+	case frameHeartbeat:
+	case frameShutdown:
+		if local, ok := pid.Lookup(f.To); ok {
+			if shutdown := local.ShutdownFn(); shutdown != nil {
+				shutdown()
+			}
+		}
+	default:
+		log.Printf("node: unknown frame kind %d from %s\n", f.Kind, c.peer)
+	}
+}
+
+// deliver sends the message to our actor without blocking the read loop
+func (c *conn) deliver(local pid.PID, message interface{}) {
+	c.deliveries.deliver(local, func() {
+		local.Mailbox().SendUserMessage(message)
+	})
+}
+
+// heartbeat keeps the connection alive on the peer's side while we've got nothing else to send
+func (c *conn) heartbeat() {
+	ticker := time.NewTicker(c.node.heartbeatInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			if err := c.send(frame{Kind: frameHeartbeat}, nil); err != nil {
+				return
+			}
+		case <-c.done:
+			return
+		}
+	}
+}
+
+func (c *conn) close() {
+	c.closeOnce.Do(func() {
+		close(c.done)
+		_ = c.nc.Close()
+		c.node.unregister(c)
+	})
+}
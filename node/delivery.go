@@ -0,0 +1,48 @@
+package node
+
+import (
+	"github.com/hedisam/goactor/internal/pid"
+	"sync"
+)
+
+// deliverer hands the messages read from a connection to our actors without holding up the read loop, a full
+// mailbox would stall every actor on the connection and let its heartbeats time out otherwise. the messages to
+// an actor are delivered in the order they were read, a full mailbox only holds back the ones to its actor.
+type deliverer struct {
+	mu sync.Mutex
+	// queues are the deliveries waiting for each actor, an actor has a queue while it's being drained
+	queues map[pid.PID][]func()
+}
+
+func newDeliverer() *deliverer {
+	return &deliverer{queues: make(map[pid.PID][]func())}
+}
+
+// deliver queues the delivery to the actor
+func (d *deliverer) deliver(to pid.PID, delivery func()) {
+	d.mu.Lock()
+	queue, draining := d.queues[to]
+	d.queues[to] = append(queue, delivery)
+	d.mu.Unlock()
+	if !draining {
+		go d.drain(to)
+	}
+}
+
+func (d *deliverer) drain(to pid.PID) {
+	for {
+		d.mu.Lock()
+		queue := d.queues[to]
+		if len(queue) == 0 {
+			delete(d.queues, to)
+			d.mu.Unlock()
+			return
+		}
+		d.queues[to] = queue[:0:0]
+		d.mu.Unlock()
+
+		for _, delivery := range queue {
+			delivery()
+		}
+	}
+}
@@ -0,0 +1,129 @@
+package node
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/gob"
+	"fmt"
+	"net"
+	"time"
+)
+
+// hello is exchanged during the handshake. each side proves it knows the cookie by signing the other one's
+// challenge.
+//  1. dialer   -> acceptor: name, challenge
+//  2. acceptor -> dialer:   name, challenge, digest of the dialer's challenge
+//  3. dialer   -> acceptor: digest of the acceptor's challenge
+//  4. acceptor -> dialer:   empty hello as the acknowledgement
+type hello struct {
+	Name string
+	// Codec is the name of the codec the node encodes the messages with
+	Codec     string
+	Challenge []byte
+	Digest    []byte
+}
+
+func dialHandshake(n *Node, nc net.Conn, peer string) (*conn, error) {
+	c := newConn(n, nc, true)
+	_ = nc.SetDeadline(time.Now().Add(defaultHandshakeTimeout))
+	defer nc.SetDeadline(time.Time{})
+
+	challenge, err := newChallenge()
+	if err != nil {
+		return nil, err
+	}
+	if err := c.writeHello(hello{Name: n.name, Codec: n.codec.Name(), Challenge: challenge}); err != nil {
+		return nil, err
+	}
+	h, err := c.readHello()
+	if err != nil {
+		return nil, err
+	}
+	if h.Name != peer {
+		return nil, fmt.Errorf("dialed node %s but %s answered", peer, h.Name)
+	}
+	if h.Codec != n.codec.Name() {
+		return nil, fmt.Errorf("node %s uses the %s codec, we use %s", peer, h.Codec, n.codec.Name())
+	}
+	if !hmac.Equal(h.Digest, digest(n.cookie, challenge)) {
+		return nil, fmt.Errorf("node %s has a different cookie", peer)
+	}
+	if err := c.writeHello(hello{Digest: digest(n.cookie, h.Challenge)}); err != nil {
+		return nil, err
+	}
+	if _, err := c.readHello(); err != nil {
+		return nil, fmt.Errorf("node %s rejected the connection: %v", peer, err)
+	}
+	c.peer = peer
+	return c, nil
+}
+
+func acceptHandshake(n *Node, nc net.Conn) (*conn, error) {
+	c := newConn(n, nc, false)
+	_ = nc.SetDeadline(time.Now().Add(defaultHandshakeTimeout))
+	defer nc.SetDeadline(time.Time{})
+
+	h, err := c.readHello()
+	if err != nil {
+		return nil, err
+	}
+	if _, err := addressOf(h.Name); err != nil {
+		return nil, err
+	} else if h.Name == n.name {
+		return nil, fmt.Errorf("node %s connected to itself", h.Name)
+	} else if h.Codec != n.codec.Name() {
+		return nil, fmt.Errorf("node %s uses the %s codec, we use %s", h.Name, h.Codec, n.codec.Name())
+	}
+	challenge, err := newChallenge()
+	if err != nil {
+		return nil, err
+	}
+	err = c.writeHello(hello{Name: n.name, Codec: n.codec.Name(), Challenge: challenge, Digest: digest(n.cookie, h.Challenge)})
+	if err != nil {
+		return nil, err
+	}
+	proof, err := c.readHello()
+	if err != nil {
+		return nil, err
+	}
+	if !hmac.Equal(proof.Digest, digest(n.cookie, challenge)) {
+		return nil, fmt.Errorf("node %s has a different cookie", h.Name)
+	}
+	if err := c.writeHello(hello{}); err != nil {
+		return nil, err
+	}
+	c.peer = h.Name
+	return c, nil
+}
+
+func (c *conn) writeHello(h hello) error {
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(&h); err != nil {
+		return err
+	}
+	return c.write(buf.Bytes())
+}
+
+func (c *conn) readHello() (hello, error) {
+	var h hello
+	data, err := c.read()
+	if err != nil {
+		return h, err
+	}
+	err = gob.NewDecoder(bytes.NewReader(data)).Decode(&h)
+	return h, err
+}
+
+func newChallenge() ([]byte, error) {
+	challenge := make([]byte, 32)
+	_, err := rand.Read(challenge)
+	return challenge, err
+}
+
+func digest(cookie string, challenge []byte) []byte {
+	mac := hmac.New(sha256.New, []byte(cookie))
+	_, _ = mac.Write(challenge)
+	return mac.Sum(nil)
+}
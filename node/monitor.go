@@ -0,0 +1,139 @@
+package node
+
+import (
+	"github.com/hedisam/goactor/actor"
+	"github.com/hedisam/goactor/internal/pid"
+	"github.com/hedisam/goactor/sysmsg"
+)
+
+// checkMonitor tells the watcher to monitor the actor if it's monitoring nodes, or to stop monitoring it if it's not
+type checkMonitor struct {
+	pid pid.PID
+}
+
+// stopWatcher stops the watcher along with the node
+type stopWatcher struct{}
+
+// MonitorNode makes the actor receive a sysmsg.NodeUp/NodeDown whenever the node connects or disconnects.
+// it connects to the node if it's not already connected, a NodeDown is sent right away if that fails.
+func (n *Node) MonitorNode(ppid *pid.ProtectedPID, node string) {
+	n.monitorNode(pid.ExtractPID(ppid), node)
+	if err := n.Connect(node); err != nil {
+		pid.ExtractPID(ppid).Mailbox().SendSystemMessage(sysmsg.NodeDown{Node: node, Reason: err.Error()})
+	}
+}
+
+// DemonitorNode stops the node monitoring started by MonitorNode
+func (n *Node) DemonitorNode(ppid *pid.ProtectedPID, node string) {
+	n.demonitorNode(pid.ExtractPID(ppid), node)
+}
+
+// MonitorNodes makes the actor receive a sysmsg.NodeUp/NodeDown whenever any node connects or disconnects
+func (n *Node) MonitorNodes(ppid *pid.ProtectedPID) {
+	n.monitorNode(pid.ExtractPID(ppid), "")
+}
+
+// DemonitorNodes stops the node monitoring started by MonitorNodes
+func (n *Node) DemonitorNodes(ppid *pid.ProtectedPID) {
+	n.demonitorNode(pid.ExtractPID(ppid), "")
+}
+
+func (n *Node) monitorNode(p pid.PID, node string) {
+	n.mu.Lock()
+	monitors, ok := n.nodeMonitors[node]
+	if !ok {
+		monitors = make(map[pid.PID]struct{})
+		n.nodeMonitors[node] = monitors
+	}
+	monitors[p] = struct{}{}
+	n.mu.Unlock()
+	actor.Send(n.watcher, checkMonitor{pid: p})
+}
+
+func (n *Node) demonitorNode(p pid.PID, node string) {
+	n.mu.Lock()
+	delete(n.nodeMonitors[node], p)
+	if len(n.nodeMonitors[node]) == 0 {
+		delete(n.nodeMonitors, node)
+	}
+	n.mu.Unlock()
+	actor.Send(n.watcher, checkMonitor{pid: p})
+}
+
+// monitorsNodes reports whether the actor is monitoring any node
+func (n *Node) monitorsNodes(p pid.PID) bool {
+	n.mu.RLock()
+	defer n.mu.RUnlock()
+	for _, monitors := range n.nodeMonitors {
+		if _, ok := monitors[p]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+// dropNodeMonitors removes the node monitors of a terminated actor
+func (n *Node) dropNodeMonitors(p pid.PID) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	for node, monitors := range n.nodeMonitors {
+		delete(monitors, p)
+		if len(monitors) == 0 {
+			delete(n.nodeMonitors, node)
+		}
+	}
+}
+
+// watch monitors the actors monitoring nodes, and drops their node monitors when they terminate. the node
+// monitors are checked when they change rather than passed along, so the order the checks arrive in doesn't matter.
+func (n *Node) watch(a *actor.Actor) {
+	watching := make(map[pid.PID]struct{})
+	a.Receive(func(message interface{}) (loop bool) {
+		switch msg := message.(type) {
+		case checkMonitor:
+			_, watched := watching[msg.pid]
+			switch monitoring := n.monitorsNodes(msg.pid); {
+			case monitoring && !watched:
+				watching[msg.pid] = struct{}{}
+				a.Monitor(pid.NewProtectedPID(msg.pid))
+			case !monitoring && watched:
+				delete(watching, msg.pid)
+				a.Demonitor(pid.NewProtectedPID(msg.pid))
+			}
+		case sysmsg.Exit:
+			if who, ok := msg.Who.(pid.PID); ok {
+				delete(watching, who)
+				n.dropNodeMonitors(who)
+			}
+		case stopWatcher:
+			return false
+		}
+		return true
+	})
+}
+
+func (n *Node) nodeUp(node string) {
+	for _, p := range n.nodeMonitorsOf(node) {
+		p.Mailbox().SendSystemMessage(sysmsg.NodeUp{Node: node})
+	}
+}
+
+func (n *Node) nodeDown(node, reason string) {
+	for _, p := range n.nodeMonitorsOf(node) {
+		p.Mailbox().SendSystemMessage(sysmsg.NodeDown{Node: node, Reason: reason})
+	}
+}
+
+// nodeMonitorsOf returns the actors monitoring the node along with the ones monitoring all the nodes
+func (n *Node) nodeMonitorsOf(node string) []pid.PID {
+	n.mu.RLock()
+	defer n.mu.RUnlock()
+	var monitors []pid.PID
+	for p := range n.nodeMonitors[node] {
+		monitors = append(monitors, p)
+	}
+	for p := range n.nodeMonitors[""] {
+		monitors = append(monitors, p)
+	}
+	return monitors
+}
@@ -0,0 +1,81 @@
+package node
+
+import (
+	"github.com/hedisam/goactor/actor"
+	"github.com/hedisam/goactor/internal/pid"
+	"github.com/hedisam/goactor/sysmsg"
+	"testing"
+	"time"
+)
+
+// expectExit waits for the actor to receive an exit with the reason and relation
+func expectExit(t *testing.T, a *actor.Actor, reason string, relation sysmsg.Relation) {
+	var received interface{}
+	a.ReceiveWithTimeout(time.Second, func(message interface{}) (loop bool) {
+		received = message
+		return false
+	})
+	exit, ok := received.(sysmsg.Exit)
+	if !ok {
+		t.Fatalf("expected an exit, got %#v", received)
+	}
+	if exit.Reason.Type != reason || exit.Relation != relation {
+		t.Fatalf("expected a %s exit of a %v actor, got %+v", reason, relation, exit)
+	}
+}
+
+func TestConnectionLost(t *testing.T) {
+	a := startNode(t, "a", "secret")
+	defer a.Stop()
+	b := startNode(t, "b", "secret")
+	defer b.Stop()
+	_, stop := spawnEcho(t, "lost-echo")
+	defer stop()
+	remote, err := a.WhereIs(b.Name(), "lost-echo")
+	if err != nil || remote == nil {
+		t.Fatalf("the echo actor was not found: %v", err)
+	}
+
+	monitor, disposeMonitor := actor.NewParentActor()
+	defer disposeMonitor()
+	monitor.Monitor(remote)
+	linked, disposeLinked := actor.NewParentActor()
+	defer disposeLinked()
+	linked.TrapExit(true)
+	linked.Link(remote)
+
+	a.Disconnect(b.Name())
+	expectExit(t, monitor, sysmsg.NoConnection, sysmsg.Monitored)
+	expectExit(t, linked, sysmsg.NoConnection, sysmsg.Linked)
+}
+
+func TestNodeMonitorDroppedOnExit(t *testing.T) {
+	a := startNode(t, "a", "secret")
+	defer a.Stop()
+	b := startNode(t, "b", "secret")
+	defer b.Stop()
+	monitoring := func(p *pid.ProtectedPID) bool {
+		return a.monitorsNodes(pid.ExtractPID(p))
+	}
+
+	subscriber := actor.Spawn(func(sub *actor.Actor) {
+		sub.Receive(func(message interface{}) (loop bool) {
+			_, up := message.(sysmsg.NodeUp)
+			return up
+		})
+	})
+	a.MonitorNode(subscriber, b.Name())
+	if !monitoring(subscriber) {
+		t.Fatal("the subscriber is not monitoring the node")
+	}
+	// the NodeUp keeps it going, anything else stops it
+	actor.Send(subscriber, "stop")
+	waitFor(t, "the terminated subscriber's node monitor to be dropped", func() bool {
+		return !monitoring(subscriber)
+	})
+
+	a.MonitorNodes(subscriber)
+	waitFor(t, "the node monitor of a terminated subscriber to be dropped", func() bool {
+		return !monitoring(subscriber)
+	})
+}
@@ -0,0 +1,438 @@
+package node
+
+import (
+	"fmt"
+	"github.com/hedisam/goactor/actor"
+	"github.com/hedisam/goactor/codec"
+	"github.com/hedisam/goactor/internal/pid"
+	"github.com/hedisam/goactor/internal/remote"
+	"log"
+	"net"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	defaultHandshakeTimeout  = 5 * time.Second
+	defaultCallTimeout       = 5 * time.Second
+	defaultHeartbeatInterval = time.Second
+	defaultHeartbeatTimeout  = 5 * time.Second
+)
+
+type Config struct {
+	// Name is the node's name in the name@host:port form. the node listens on host:port.
+	// port 0 picks a free port and the name is updated with it.
+	Name string
+	// Cookie is the shared secret nodes must have in common to connect
+	Cookie string
+	// Codec serializes the messages, codec.Gob by default. connected nodes must use the same codec.
+	Codec codec.Codec
+	// HeartbeatInterval is how often a heartbeat is sent to the connected nodes, a second by default
+	HeartbeatInterval time.Duration
+	// HeartbeatTimeout is how long a connection can be silent before it's considered lost, 5 seconds by default
+	HeartbeatTimeout time.Duration
+}
+
+// Node connects the local actors to the actors of other nodes
+type Node struct {
+	name     string
+	cookie   string
+	codec    codec.Codec
+	listener net.Listener
+
+	heartbeatInterval time.Duration
+	heartbeatTimeout  time.Duration
+
+	mu    sync.RWMutex
+	conns map[string]*conn
+	// remotes interns the remote pids so the same remote actor is always the same pid.PID. a pid is released
+	// once its actor is known to have terminated or the connection to its node is lost, the pids decoded after
+	// that are new values.
+	remotes map[pid.Ref]*remotePID
+	// pending holds the callers waiting for a remote reply by the request ref
+	pending map[uint64]chan frame
+	nextRef uint64
+	// watches are the links and monitors through each connected node
+	watches map[string]*watches
+	// nodeMonitors are the actors monitoring each node, the ones under the empty name monitor all the nodes
+	nodeMonitors map[string]map[pid.PID]struct{}
+	// watcher drops the node monitors of the actors that have terminated
+	watcher *pid.ProtectedPID
+
+	stopOnce sync.Once
+	done     chan struct{}
+}
+
+// RegisterMessage registers a message type so its values can be sent to remote actors
+func RegisterMessage(message interface{}) {
+	codec.Register(message)
+}
+
+// Start starts a node listening for other nodes
+func Start(config Config) (*Node, error) {
+	address, err := addressOf(config.Name)
+	if err != nil {
+		return nil, err
+	}
+	listener, err := net.Listen("tcp", address)
+	if err != nil {
+		return nil, err
+	}
+
+	if config.Codec == nil {
+		config.Codec = codec.Gob
+	}
+	if config.HeartbeatInterval <= 0 {
+		config.HeartbeatInterval = defaultHeartbeatInterval
+	}
+	if config.HeartbeatTimeout <= 0 {
+		config.HeartbeatTimeout = defaultHeartbeatTimeout
+	}
+
+	name := config.Name
+	if _, port, _ := net.SplitHostPort(address); port == "0" {
+		host, _, _ := net.SplitHostPort(address)
+		_, port, _ = net.SplitHostPort(listener.Addr().String())
+		name = config.Name[:strings.LastIndex(config.Name, "@")+1] + net.JoinHostPort(host, port)
+	}
+
+	n := &Node{
+		name:     name,
+		cookie:   config.Cookie,
+		codec:    config.Codec,
+		listener: listener,
+
+		heartbeatInterval: config.HeartbeatInterval,
+		heartbeatTimeout:  config.HeartbeatTimeout,
+
+		conns:        make(map[string]*conn),
+		remotes:      make(map[pid.Ref]*remotePID),
+		pending:      make(map[uint64]chan frame),
+		watches:      make(map[string]*watches),
+		nodeMonitors: make(map[string]map[pid.PID]struct{}),
+		done:         make(chan struct{}),
+	}
+	n.watcher = actor.Spawn(n.watch)
+	go n.accept()
+	remote.Register(n)
+	return n, nil
+}
+
+// Name returns the node's name
+func (n *Node) Name() string {
+	return n.name
+}
+
+// Connect connects to the node with the given name. it's a no-op if they're already connected.
+func (n *Node) Connect(name string) error {
+	_, err := n.conn(name)
+	return err
+}
+
+// Disconnect closes the connection to the node
+func (n *Node) Disconnect(name string) {
+	n.mu.RLock()
+	c, ok := n.conns[name]
+	n.mu.RUnlock()
+	if ok {
+		c.close()
+	}
+}
+
+// Connected reports whether the node is connected to us
+func (n *Node) Connected(name string) bool {
+	n.mu.RLock()
+	defer n.mu.RUnlock()
+	_, ok := n.conns[name]
+	return ok
+}
+
+// Nodes returns the names of the connected nodes
+func (n *Node) Nodes() []string {
+	n.mu.RLock()
+	defer n.mu.RUnlock()
+	names := make([]string, 0, len(n.conns))
+	for name := range n.conns {
+		names = append(names, name)
+	}
+	return names
+}
+
+// WhereIs looks up the actor registered under the name on the given node
+func (n *Node) WhereIs(node, name string) (*pid.ProtectedPID, error) {
+	if node == n.name {
+		return nil, fmt.Errorf("node %s is the local node, use actor.WhereIs", node)
+	}
+	reply, err := n.request(node, frame{Kind: frameWhereIs, Name: name}, nil)
+	if err != nil {
+		return nil, err
+	}
+	return reply.PID, nil
+}
+
+// SendNamed sends the message to the actor registered under the name on the given node
+func (n *Node) SendNamed(node, name string, message interface{}) error {
+	return n.send(node, frame{Kind: frameSendNamed, Name: name}, message)
+}
+
+// PID returns the pid of the actor with the given ref
+func (n *Node) PID(ref pid.Ref) (*pid.ProtectedPID, error) {
+	p, err := n.resolve(ref)
+	if err != nil {
+		return nil, err
+	}
+	return pid.NewProtectedPID(p), nil
+}
+
+// Ref returns the location independent ref of the pid as seen from this node
+func (n *Node) Ref(ppid *pid.ProtectedPID) pid.Ref {
+	return pid.RefOf(n.name, pid.ExtractPID(ppid))
+}
+
+// Stop closes the listener and all the connections
+func (n *Node) Stop() {
+	n.stopOnce.Do(func() {
+		remote.Unregister(n)
+		close(n.done)
+		_ = n.listener.Close()
+		n.mu.RLock()
+		conns := make([]*conn, 0, len(n.conns))
+		for _, c := range n.conns {
+			conns = append(conns, c)
+		}
+		n.mu.RUnlock()
+		for _, c := range conns {
+			c.close()
+		}
+		actor.Send(n.watcher, stopWatcher{})
+	})
+}
+
+func (n *Node) accept() {
+	for {
+		nc, err := n.listener.Accept()
+		if err != nil {
+			select {
+			case <-n.done:
+			default:
+				log.Println("node: accept error:", err)
+			}
+			return
+		}
+		go func() {
+			c, err := acceptHandshake(n, nc)
+			if err != nil {
+				log.Println("node: handshake failed:", err)
+				_ = nc.Close()
+				return
+			}
+			n.register(c)
+		}()
+	}
+}
+
+// conn returns the connection to the node, connecting to it if needed
+func (n *Node) conn(name string) (*conn, error) {
+	n.mu.RLock()
+	c, ok := n.conns[name]
+	n.mu.RUnlock()
+	if ok {
+		return c, nil
+	}
+	select {
+	case <-n.done:
+		return nil, fmt.Errorf("node %s is stopped", n.name)
+	default:
+	}
+	if name == n.name {
+		return nil, fmt.Errorf("node %s can't connect to itself", name)
+	}
+
+	address, err := addressOf(name)
+	if err != nil {
+		return nil, err
+	}
+	nc, err := net.DialTimeout("tcp", address, defaultHandshakeTimeout)
+	if err != nil {
+		return nil, err
+	}
+	c, err = dialHandshake(n, nc, name)
+	if err != nil {
+		_ = nc.Close()
+		return nil, err
+	}
+	return n.register(c), nil
+}
+
+// register adds a handshaked connection. if both nodes have connected to each other at the same time, the
+// connection dialed by the node with the smaller name is kept on both sides.
+func (n *Node) register(c *conn) *conn {
+	n.mu.Lock()
+	existing, ok := n.conns[c.peer]
+	if ok {
+		dialer := n.name
+		if !c.outbound {
+			dialer = c.peer
+		}
+		keepNew := dialer == minName(n.name, c.peer)
+		if !keepNew {
+			n.mu.Unlock()
+			c.close()
+			return existing
+		}
+	}
+	n.conns[c.peer] = c
+	n.mu.Unlock()
+
+	go c.readLoop()
+	go c.heartbeat()
+	if ok {
+		existing.close()
+	} else {
+		n.nodeUp(c.peer)
+	}
+	return c
+}
+
+// unregister is called by a closed connection
+func (n *Node) unregister(c *conn) {
+	n.mu.Lock()
+	lost := n.conns[c.peer] == c
+	if lost {
+		delete(n.conns, c.peer)
+	}
+	n.mu.Unlock()
+
+	if lost {
+		n.connectionLost(c.peer)
+		n.releaseRemotes(c.peer)
+		n.nodeDown(c.peer, "connection lost")
+	}
+}
+
+func (n *Node) send(node string, f frame, message interface{}) error {
+	c, err := n.conn(node)
+	if err != nil {
+		return err
+	}
+	return c.send(f, message)
+}
+
+// request sends a frame expecting a reply frame with the same ref
+func (n *Node) request(node string, f frame, message interface{}) (frame, error) {
+	f.Ref = atomic.AddUint64(&n.nextRef, 1)
+	reply := make(chan frame, 1)
+	n.mu.Lock()
+	n.pending[f.Ref] = reply
+	n.mu.Unlock()
+	defer func() {
+		n.mu.Lock()
+		delete(n.pending, f.Ref)
+		n.mu.Unlock()
+	}()
+
+	if err := n.send(node, f, message); err != nil {
+		return frame{}, err
+	}
+	select {
+	case r := <-reply:
+		if r.Error != "" {
+			return r, fmt.Errorf("%s", r.Error)
+		}
+		return r, nil
+	case <-time.After(defaultCallTimeout):
+		return frame{}, fmt.Errorf("node %s didn't reply in time", node)
+	}
+}
+
+func (n *Node) reply(f frame) {
+	n.mu.RLock()
+	reply, ok := n.pending[f.Ref]
+	n.mu.RUnlock()
+	if ok {
+		reply <- f
+	}
+}
+
+// resolve is used when decoding the pids inside incoming messages
+func (n *Node) resolve(ref pid.Ref) (pid.PID, error) {
+	if ref.Node == n.name {
+		if local, ok := pid.Lookup(ref.ID); ok {
+			return local, nil
+		}
+		// the actor has terminated, its pid is still a valid value, just a dead one
+		return n.remotePID(ref), nil
+	}
+	if ref.Node == "" {
+		return nil, fmt.Errorf("pid %s has not been exported by a node", ref)
+	}
+	return n.remotePID(ref), nil
+}
+
+func (n *Node) remotePID(ref pid.Ref) *remotePID {
+	if ref.Node == n.name {
+		// the pids of our terminated actors aren't interned, there's nothing to tell them apart
+		return newRemotePID(n, ref)
+	}
+	n.mu.RLock()
+	r, ok := n.remotes[ref]
+	n.mu.RUnlock()
+	if ok {
+		return r
+	}
+
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if r, ok := n.remotes[ref]; ok {
+		return r
+	}
+	r = newRemotePID(n, ref)
+	n.remotes[ref] = r
+	return r
+}
+
+// releaseRemote drops the interned pid of a remote actor that has terminated
+func (n *Node) releaseRemote(remote *remotePID) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if n.remotes[remote.ref] == remote {
+		delete(n.remotes, remote.ref)
+	}
+}
+
+// releaseRemotes drops the interned pids of the node's actors
+func (n *Node) releaseRemotes(node string) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	for ref := range n.remotes {
+		if ref.Node == node {
+			delete(n.remotes, ref)
+		}
+	}
+}
+
+func (n *Node) codecContext(peer string) codec.Context {
+	return codec.Context{Peer: peer, Resolve: n.resolve}
+}
+
+// addressOf returns the host:port part of a node name
+func addressOf(name string) (string, error) {
+	i := strings.LastIndex(name, "@")
+	if i < 1 || i == len(name)-1 {
+		return "", fmt.Errorf("invalid node name %s, expected name@host:port", name)
+	}
+	address := name[i+1:]
+	if _, _, err := net.SplitHostPort(address); err != nil {
+		return "", fmt.Errorf("invalid node name %s: %v", name, err)
+	}
+	return address, nil
+}
+
+func minName(a, b string) string {
+	if a < b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,286 @@
+package node
+
+import (
+	"github.com/hedisam/goactor/actor"
+	"github.com/hedisam/goactor/codec"
+	"github.com/hedisam/goactor/internal/pid"
+	"github.com/hedisam/goactor/sysmsg"
+	"testing"
+	"time"
+)
+
+type ping struct {
+	From *pid.ProtectedPID
+}
+
+// pong carries back the pid of the ping's sender
+type pong struct {
+	To *pid.ProtectedPID
+}
+
+func init() {
+	codec.Register(ping{})
+	codec.Register(pong{})
+}
+
+func startNode(t *testing.T, name, cookie string) *Node {
+	n, err := Start(Config{Name: name + "@127.0.0.1:0", Cookie: cookie})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return n
+}
+
+// waitFor polls the condition for a second
+func waitFor(t *testing.T, what string, condition func() bool) {
+	deadline := time.Now().Add(time.Second)
+	for !condition() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+// spawnEcho spawns an actor registered under the name answering a ping with a pong, any other message stops it.
+// stop unregisters and stops it.
+func spawnEcho(t *testing.T, name string) (echo *pid.ProtectedPID, stop func()) {
+	echo = actor.Spawn(func(a *actor.Actor) {
+		a.Receive(func(message interface{}) (loop bool) {
+			p, ok := message.(ping)
+			if ok {
+				actor.Send(p.From, pong{To: p.From})
+			}
+			return ok
+		})
+	})
+	if err := actor.Register(name, echo); err != nil {
+		t.Fatal(err)
+	}
+	return echo, func() {
+		actor.Unregister(name)
+		actor.Send(echo, "stop")
+	}
+}
+
+// expectPong waits for the pong to the future and checks it carries the future's pid back
+func expectPong(t *testing.T, future interface {
+	Self() *pid.ProtectedPID
+	RecvWithTimeout(time.Duration) (interface{}, error)
+}) {
+	self := future.Self()
+	response, err := future.RecvWithTimeout(time.Second)
+	if err != nil {
+		t.Fatalf("no pong: %v", err)
+	}
+	p, ok := response.(pong)
+	if !ok {
+		t.Fatalf("expected a pong, got %#v", response)
+	}
+	if pid.ExtractPID(p.To) != pid.ExtractPID(self) {
+		t.Fatal("the pid sent to the other node did not come back as the same pid")
+	}
+}
+
+func TestHandshake(t *testing.T) {
+	a := startNode(t, "a", "secret")
+	defer a.Stop()
+	b := startNode(t, "b", "secret")
+	defer b.Stop()
+
+	if err := a.Connect(b.Name()); err != nil {
+		t.Fatalf("could not connect: %v", err)
+	}
+	if !a.Connected(b.Name()) {
+		t.Fatal("a is not connected to b")
+	}
+	waitFor(t, "b to register the connection", func() bool {
+		return b.Connected(a.Name())
+	})
+	if nodes := b.Nodes(); len(nodes) != 1 || nodes[0] != a.Name() {
+		t.Fatalf("unexpected nodes %v", nodes)
+	}
+}
+
+func TestCookieRejection(t *testing.T) {
+	a := startNode(t, "a", "secret")
+	defer a.Stop()
+	b := startNode(t, "b", "other")
+	defer b.Stop()
+
+	if err := a.Connect(b.Name()); err == nil {
+		t.Fatal("connected to a node with a different cookie")
+	}
+	if a.Connected(b.Name()) || b.Connected(a.Name()) {
+		t.Fatal("the nodes are connected")
+	}
+}
+
+func TestRemoteSend(t *testing.T) {
+	a := startNode(t, "a", "secret")
+	defer a.Stop()
+	b := startNode(t, "b", "secret")
+	defer b.Stop()
+	_, stop := spawnEcho(t, "send-echo")
+	defer stop()
+
+	future := actor.NewFutureActor()
+	defer future.Dispose()
+	if err := a.SendNamed(b.Name(), "send-echo", ping{From: future.Self()}); err != nil {
+		t.Fatal(err)
+	}
+	expectPong(t, future)
+}
+
+func TestWhereIs(t *testing.T) {
+	a := startNode(t, "a", "secret")
+	defer a.Stop()
+	b := startNode(t, "b", "secret")
+	defer b.Stop()
+	echo, stop := spawnEcho(t, "whereis-echo")
+	defer stop()
+
+	remote, err := a.WhereIs(b.Name(), "whereis-echo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if remote == nil {
+		t.Fatal("the echo actor was not found")
+	}
+	if got, want := a.Ref(remote), b.Ref(echo); got != want {
+		t.Fatalf("found %v, expected %v", got, want)
+	}
+	future := actor.NewFutureActor()
+	defer future.Dispose()
+	actor.Send(remote, ping{From: future.Self()})
+	expectPong(t, future)
+
+	missing, err := a.WhereIs(b.Name(), "whereis-missing")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if missing != nil {
+		t.Fatal("found an actor that's not registered")
+	}
+}
+
+func TestPIDRoundTrip(t *testing.T) {
+	a := startNode(t, "a", "secret")
+	defer a.Stop()
+	b := startNode(t, "b", "secret")
+	defer b.Stop()
+	if err := a.Connect(b.Name()); err != nil {
+		t.Fatal(err)
+	}
+	local := actor.Spawn(func(a *actor.Actor) {
+		a.Receive(func(message interface{}) (loop bool) {
+			return false
+		})
+	})
+	defer actor.Send(local, "stop")
+
+	ref := a.Ref(local)
+	if ref.Node != a.Name() || ref.ID == "" {
+		t.Fatalf("unexpected ref %v", ref)
+	}
+	remote, err := b.PID(ref)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b.Ref(remote) != ref {
+		t.Fatalf("the remote pid has the ref %v, expected %v", b.Ref(remote), ref)
+	}
+	again, err := b.PID(ref)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pid.ExtractPID(again) != pid.ExtractPID(remote) {
+		t.Fatal("the same ref resolved to different remote pids")
+	}
+	back, err := a.PID(ref)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pid.ExtractPID(back) != pid.ExtractPID(local) {
+		t.Fatal("the ref did not resolve to the local pid on its own node")
+	}
+}
+
+func TestFullMailboxDoesNotBlockTheConnection(t *testing.T) {
+	a := startNode(t, "a", "secret")
+	defer a.Stop()
+	b := startNode(t, "b", "secret")
+	defer b.Stop()
+	_, stop := spawnEcho(t, "blocked-echo")
+	defer stop()
+
+	release := make(chan struct{})
+	stuck := actor.Spawn(func(a *actor.Actor) {
+		<-release
+	})
+	defer close(release)
+	if err := actor.Register("stuck", stuck); err != nil {
+		t.Fatal(err)
+	}
+	defer actor.Unregister("stuck")
+	// more than the mailbox can hold
+	for i := 0; i < 200; i++ {
+		if err := a.SendNamed(b.Name(), "stuck", i); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	future := actor.NewFutureActor()
+	defer future.Dispose()
+	if err := a.SendNamed(b.Name(), "blocked-echo", ping{From: future.Self()}); err != nil {
+		t.Fatal(err)
+	}
+	expectPong(t, future)
+}
+
+func TestRemotePIDsReleased(t *testing.T) {
+	a := startNode(t, "a", "secret")
+	defer a.Stop()
+	b := startNode(t, "b", "secret")
+	defer b.Stop()
+	_, stop := spawnEcho(t, "released-echo")
+	defer stop()
+	interned := func(ppid *pid.ProtectedPID) bool {
+		a.mu.RLock()
+		defer a.mu.RUnlock()
+		_, ok := a.remotes[a.Ref(ppid)]
+		return ok
+	}
+
+	remote, err := a.WhereIs(b.Name(), "released-echo")
+	if err != nil || remote == nil {
+		t.Fatalf("the echo actor was not found: %v", err)
+	}
+	if !interned(remote) {
+		t.Fatal("the remote pid is not interned")
+	}
+	parent, dispose := actor.NewParentActor()
+	defer dispose()
+	parent.Monitor(remote)
+	actor.Send(remote, "stop")
+	parent.ReceiveWithTimeout(time.Second, func(message interface{}) (loop bool) {
+		if _, ok := message.(sysmsg.Exit); !ok {
+			t.Fatalf("expected an exit, got %#v", message)
+		}
+		return false
+	})
+	waitFor(t, "the terminated actor's pid to be released", func() bool {
+		return !interned(remote)
+	})
+
+	_, stop = spawnEcho(t, "disconnected-echo")
+	defer stop()
+	remote, err = a.WhereIs(b.Name(), "disconnected-echo")
+	if err != nil || remote == nil {
+		t.Fatalf("the echo actor was not found: %v", err)
+	}
+	a.Disconnect(b.Name())
+	if interned(remote) {
+		t.Fatal("the pid of a disconnected node is still interned")
+	}
+}
@@ -0,0 +1,109 @@
+package node
+
+import (
+	"github.com/hedisam/goactor/internal/mailbox"
+	"github.com/hedisam/goactor/internal/pid"
+	"github.com/hedisam/goactor/sysmsg"
+	"log"
+	"time"
+)
+
+// remotePID is the pid of an actor living on another node. its mailbox sends the messages over the connection
+// to that node.
+type remotePID struct {
+	ref  pid.Ref
+	node *Node
+	// dead is true for the refs of our own actors that had terminated before the ref was decoded
+	dead    bool
+	mailbox *remoteMailbox
+}
+
+func newRemotePID(n *Node, ref pid.Ref) *remotePID {
+	r := &remotePID{ref: ref, node: n, dead: ref.Node == n.name}
+	r.mailbox = &remoteMailbox{pid: r}
+	return r
+}
+
+func (r *remotePID) Ref() pid.Ref {
+	return r.ref
+}
+
+func (r *remotePID) Mailbox() mailbox.Mailbox {
+	return r.mailbox
+}
+
+// ShutdownFn returns a function that closes the remote actor's context
+func (r *remotePID) ShutdownFn() func() {
+	return func() {
+		if r.dead {
+			return
+		}
+		if err := r.node.send(r.ref.Node, frame{Kind: frameShutdown, To: r.ref.ID}, nil); err != nil {
+			log.Println("node: could not shutdown remote actor:", err)
+		}
+	}
+}
+
+// the following methods are only meaningful on the node the actor lives on
+
+func (r *remotePID) SetShutdownFn(func()) {}
+
+func (r *remotePID) SetActorTypeFn(func(int32)) {}
+
+func (r *remotePID) ActorTypeFn() func(int32) {
+	return func(int32) {}
+}
+
+func (r *remotePID) SetSupervisorFn(func(pid.PID)) {}
+
+func (r *remotePID) SupervisorFn() func(pid.PID) {
+	return func(pid.PID) {}
+}
+
+type remoteMailbox struct {
+	pid *remotePID
+}
+
+func (m *remoteMailbox) SendUserMessage(message interface{}) {
+	// system messages sent by actor.Send, like the supervisors' Shutdown, are system messages on the other end too
+	if _, ok := message.(sysmsg.SystemMessage); ok {
+		m.SendSystemMessage(message)
+		return
+	}
+	if m.pid.dead {
+		return
+	}
+	err := m.pid.node.send(m.pid.ref.Node, frame{Kind: frameSend, To: m.pid.ref.ID}, message)
+	if err != nil {
+		log.Printf("node: could not send to %s: %v\n", m.pid.ref, err)
+	}
+}
+
+func (m *remoteMailbox) SendSystemMessage(message interface{}) {
+	if m.pid.dead {
+		m.pid.node.noproc(m.pid.ref, message)
+		return
+	}
+	m.pid.node.sendSystem(m.pid, message)
+}
+
+// Receive can't be called on a remote mailbox
+func (m *remoteMailbox) Receive(handler mailbox.MessageHandler) {
+	handler(mailbox.ErrDisposed("can't receive from a remote mailbox"))
+}
+
+// ReceiveWithTimeout can't be called on a remote mailbox
+func (m *remoteMailbox) ReceiveWithTimeout(_ time.Duration, handler mailbox.MessageHandler) {
+	handler(mailbox.ErrDisposed("can't receive from a remote mailbox"))
+}
+
+func (m *remoteMailbox) Dispose() {}
+
+// Utils returns nil. DO NOT call me
+func (m *remoteMailbox) Utils() *mailbox.ActorUtils {
+	return nil
+}
+
+func (m *remoteMailbox) Len() int {
+	return 0
+}
@@ -0,0 +1,64 @@
+package node
+
+import (
+	"fmt"
+	"github.com/hedisam/goactor/internal/pid"
+	"github.com/hedisam/goactor/internal/remote"
+	"github.com/hedisam/goactor/sysmsg"
+)
+
+// spawnRequest is the wire form of remote.SpawnRequest, the args are carried in the frame's payload
+type spawnRequest struct {
+	Func    string
+	Link    *pid.ProtectedPID
+	Monitor *pid.ProtectedPID
+}
+
+// Spawn spawns the function registered under the request's name on the given node. the link or monitor of
+// the request is set up before the actor starts.
+func (n *Node) Spawn(node string, request remote.SpawnRequest) (pid.PID, error) {
+	if node == n.name {
+		return remote.SpawnLocal(request)
+	}
+	wire := &spawnRequest{Func: request.Func, Link: protect(request.Link), Monitor: protect(request.Monitor)}
+	reply, err := n.request(node, frame{Kind: frameSpawn, Spawn: wire}, request.Args)
+	if err != nil {
+		return nil, fmt.Errorf("could not spawn %s on %s: %v", request.Func, node, err)
+	}
+	spawned, ok := pid.ExtractPID(reply.PID).(*remotePID)
+	if !ok {
+		return nil, fmt.Errorf("node %s replied with an invalid pid", node)
+	}
+	if request.Link != nil {
+		n.trackOutbound(spawned, sysmsg.Link{To: request.Link})
+	}
+	if request.Monitor != nil {
+		n.trackOutbound(spawned, sysmsg.Monitor{Parent: request.Monitor})
+	}
+	return spawned, nil
+}
+
+// receiveSpawn spawns an actor on behalf of a remote node
+func (n *Node) receiveSpawn(c *conn, f frame, args interface{}) {
+	request := remote.SpawnRequest{
+		Func:    f.Spawn.Func,
+		Link:    toPID(unprotect(f.Spawn.Link)),
+		Monitor: toPID(unprotect(f.Spawn.Monitor)),
+	}
+	request.Args, _ = args.([]interface{})
+
+	reply := frame{Kind: frameSpawnReply, Ref: f.Ref}
+	spawned, err := remote.SpawnLocal(request)
+	if err != nil {
+		reply.Error = err.Error()
+	} else {
+		reply.PID = pid.NewProtectedPID(spawned)
+		if request.Link != nil {
+			n.trackInbound(spawned, sysmsg.Link{To: request.Link})
+		}
+		if request.Monitor != nil {
+			n.trackInbound(spawned, sysmsg.Monitor{Parent: request.Monitor})
+		}
+	}
+	_ = c.send(reply, nil)
+}
@@ -0,0 +1,341 @@
+package node
+
+import (
+	"fmt"
+	"github.com/hedisam/goactor/internal/pid"
+	"github.com/hedisam/goactor/sysmsg"
+	"log"
+)
+
+const (
+	systemExit systemType = iota + 1
+	systemMonitor
+	systemLink
+	systemShutdown
+)
+
+type systemType uint8
+
+// systemMessage is the wire form of the sysmsg messages. the pids in a sysmsg message are plain pid.PIDs which
+// the codecs can't encode, so they're carried as protected pids here.
+type systemMessage struct {
+	Type systemType
+	// PID is Exit.Who, Monitor.Parent or Link.To
+	PID *pid.ProtectedPID
+	// Parent is Exit.Parent or Shutdown.Parent
+	Parent   *pid.ProtectedPID
+	Reason   string
+	Details  string
+	Relation sysmsg.Relation
+	Revert   bool
+	Shutdown int32
+}
+
+// pair is a local actor and a remote one with a link or monitor between them
+type pair struct {
+	local  pid.PID
+	remote *remotePID
+}
+
+// watches are the links and monitors between our actors and the actors of a node
+type watches struct {
+	links map[pair]struct{}
+	// monitors are the remote actors monitored by our actors
+	monitors map[pair]struct{}
+	// monitoredBy are our actors monitored by the remote actors
+	monitoredBy map[pair]struct{}
+}
+
+func newWatches() *watches {
+	return &watches{
+		links:       make(map[pair]struct{}),
+		monitors:    make(map[pair]struct{}),
+		monitoredBy: make(map[pair]struct{}),
+	}
+}
+
+func toWire(message interface{}) (*systemMessage, error) {
+	switch msg := message.(type) {
+	case sysmsg.Exit:
+		wire := &systemMessage{
+			Type:     systemExit,
+			PID:      protect(msg.Who),
+			Parent:   protect(msg.Parent),
+			Reason:   msg.Reason.Type,
+			Relation: msg.Relation,
+		}
+		if msg.Reason.Details != nil {
+			wire.Details = fmt.Sprint(msg.Reason.Details)
+		}
+		return wire, nil
+	case sysmsg.Monitor:
+		return &systemMessage{Type: systemMonitor, PID: protect(msg.Parent), Revert: msg.Revert}, nil
+	case sysmsg.Link:
+		return &systemMessage{Type: systemLink, PID: protect(msg.To), Revert: msg.Revert}, nil
+	case sysmsg.Shutdown:
+		return &systemMessage{Type: systemShutdown, Parent: protect(msg.Parent), Shutdown: msg.Shutdown}, nil
+	default:
+		return nil, fmt.Errorf("system message %T can't be sent to a remote actor", message)
+	}
+}
+
+func fromWire(wire *systemMessage) (interface{}, error) {
+	switch wire.Type {
+	case systemExit:
+		reason := sysmsg.Reason{Type: wire.Reason}
+		if wire.Details != "" {
+			reason.Details = wire.Details
+		}
+		return sysmsg.Exit{
+			Who:      unprotect(wire.PID),
+			Parent:   unprotect(wire.Parent),
+			Reason:   reason,
+			Relation: wire.Relation,
+		}, nil
+	case systemMonitor:
+		return sysmsg.Monitor{Parent: unprotect(wire.PID), Revert: wire.Revert}, nil
+	case systemLink:
+		return sysmsg.Link{To: unprotect(wire.PID), Revert: wire.Revert}, nil
+	case systemShutdown:
+		return sysmsg.Shutdown{Parent: unprotect(wire.Parent), Shutdown: wire.Shutdown}, nil
+	default:
+		return nil, fmt.Errorf("unknown system message type %d", wire.Type)
+	}
+}
+
+func protect(p interface{}) *pid.ProtectedPID {
+	if p, ok := p.(pid.PID); ok && p != nil {
+		return pid.NewProtectedPID(p)
+	}
+	return nil
+}
+
+func unprotect(ppid *pid.ProtectedPID) interface{} {
+	if ppid == nil {
+		return nil
+	}
+	return pid.ExtractPID(ppid)
+}
+
+// sendSystem sends a system message from one of our actors to a remote actor
+func (n *Node) sendSystem(remote *remotePID, message interface{}) {
+	wire, err := toWire(message)
+	if err != nil {
+		log.Println("node:", err)
+		return
+	}
+	n.trackOutbound(remote, message)
+	err = n.send(remote.ref.Node, frame{Kind: frameSystem, To: remote.ref.ID, System: wire}, nil)
+	if err != nil {
+		n.unreachable(remote, message)
+	}
+}
+
+// receiveSystem delivers a system message sent by a remote actor to one of our actors, in order with the messages
+// read from the connection before it
+func (n *Node) receiveSystem(c *conn, to string, wire *systemMessage) {
+	message, err := fromWire(wire)
+	if err != nil {
+		log.Println("node:", err)
+		return
+	}
+	local, ok := pid.Lookup(to)
+	if !ok {
+		// the target has already terminated
+		n.noproc(pid.Ref{Node: n.name, ID: to}, message)
+		return
+	}
+	n.trackInbound(local, message)
+	if exit, ok := message.(sysmsg.Exit); ok {
+		if remote, ok := exit.Who.(*remotePID); ok {
+			n.releaseRemote(remote)
+		}
+	}
+	c.deliveries.deliver(local, func() {
+		local.Mailbox().SendSystemMessage(message)
+	})
+}
+
+// trackOutbound records the links and monitors our actors request from the remote actors
+func (n *Node) trackOutbound(remote *remotePID, message interface{}) {
+	var local pid.PID
+	switch msg := message.(type) {
+	case sysmsg.Monitor:
+		local = toPID(msg.Parent)
+	case sysmsg.Link:
+		local = toPID(msg.To)
+	case sysmsg.Exit:
+		local = toPID(msg.Who)
+	}
+	if local == nil {
+		return
+	}
+
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	w := n.watchesOf(remote.ref.Node)
+	p := pair{local: local, remote: remote}
+	switch msg := message.(type) {
+	case sysmsg.Monitor:
+		if msg.Revert {
+			delete(w.monitors, p)
+		} else {
+			w.monitors[p] = struct{}{}
+		}
+	case sysmsg.Link:
+		if msg.Revert {
+			delete(w.links, p)
+		} else {
+			w.links[p] = struct{}{}
+		}
+	case sysmsg.Exit:
+		// our actor has terminated
+		delete(w.links, p)
+		delete(w.monitoredBy, p)
+	}
+}
+
+// trackInbound records the links and monitors the remote actors request from our actors
+func (n *Node) trackInbound(local pid.PID, message interface{}) {
+	var remote *remotePID
+	switch msg := message.(type) {
+	case sysmsg.Monitor:
+		remote, _ = msg.Parent.(*remotePID)
+	case sysmsg.Link:
+		remote, _ = msg.To.(*remotePID)
+	case sysmsg.Exit:
+		remote, _ = msg.Who.(*remotePID)
+	}
+	if remote == nil || remote.dead {
+		return
+	}
+
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	w := n.watchesOf(remote.ref.Node)
+	p := pair{local: local, remote: remote}
+	switch msg := message.(type) {
+	case sysmsg.Monitor:
+		if msg.Revert {
+			delete(w.monitoredBy, p)
+		} else {
+			w.monitoredBy[p] = struct{}{}
+		}
+	case sysmsg.Link:
+		if msg.Revert {
+			delete(w.links, p)
+		} else {
+			w.links[p] = struct{}{}
+		}
+	case sysmsg.Exit:
+		// the remote actor has terminated
+		delete(w.links, p)
+		delete(w.monitors, p)
+	}
+}
+
+// unreachable is called when a system message couldn't be sent. a link or monitor request is answered with a
+// noconnection exit, unless the connection loss has already been reported.
+func (n *Node) unreachable(remote *remotePID, message interface{}) {
+	var local pid.PID
+	var relation sysmsg.Relation
+	switch msg := message.(type) {
+	case sysmsg.Monitor:
+		local, relation = toPID(msg.Parent), sysmsg.Monitored
+	case sysmsg.Link:
+		local, relation = toPID(msg.To), sysmsg.Linked
+	}
+	if local == nil {
+		return
+	}
+
+	p := pair{local: local, remote: remote}
+	n.mu.Lock()
+	w := n.watchesOf(remote.ref.Node)
+	_, watched := w.monitors[p]
+	if relation == sysmsg.Linked {
+		_, watched = w.links[p]
+	}
+	delete(w.monitors, p)
+	delete(w.links, p)
+	n.mu.Unlock()
+
+	if watched {
+		local.Mailbox().SendSystemMessage(sysmsg.Exit{
+			Who:      remote,
+			Reason:   sysmsg.Reason{Type: sysmsg.NoConnection},
+			Relation: relation,
+		})
+	}
+}
+
+// noproc answers a link or monitor request sent to one of our terminated actors
+func (n *Node) noproc(ref pid.Ref, message interface{}) {
+	var requester pid.PID
+	var relation sysmsg.Relation
+	switch msg := message.(type) {
+	case sysmsg.Monitor:
+		if !msg.Revert {
+			requester, relation = toPID(msg.Parent), sysmsg.Monitored
+		}
+	case sysmsg.Link:
+		if !msg.Revert {
+			requester, relation = toPID(msg.To), sysmsg.Linked
+		}
+	}
+	if requester == nil {
+		return
+	}
+	requester.Mailbox().SendSystemMessage(sysmsg.Exit{
+		Who:      n.remotePID(ref),
+		Reason:   sysmsg.Reason{Type: sysmsg.NoProc},
+		Relation: relation,
+	})
+}
+
+// connectionLost fires the links and monitors through the node with the noconnection reason
+func (n *Node) connectionLost(node string) {
+	n.mu.Lock()
+	w, ok := n.watches[node]
+	delete(n.watches, node)
+	n.mu.Unlock()
+	if !ok {
+		return
+	}
+
+	for p := range w.links {
+		p.local.Mailbox().SendSystemMessage(sysmsg.Link{To: p.remote, Revert: true})
+		p.local.Mailbox().SendSystemMessage(sysmsg.Exit{
+			Who:      p.remote,
+			Reason:   sysmsg.Reason{Type: sysmsg.NoConnection},
+			Relation: sysmsg.Linked,
+		})
+	}
+	for p := range w.monitors {
+		p.local.Mailbox().SendSystemMessage(sysmsg.Exit{
+			Who:      p.remote,
+			Reason:   sysmsg.Reason{Type: sysmsg.NoConnection},
+			Relation: sysmsg.Monitored,
+		})
+	}
+	for p := range w.monitoredBy {
+		p.local.Mailbox().SendSystemMessage(sysmsg.Monitor{Parent: p.remote, Revert: true})
+	}
+}
+
+// watchesOf must be called with the lock held
+func (n *Node) watchesOf(node string) *watches {
+	w, ok := n.watches[node]
+	if !ok {
+		w = newWatches()
+		n.watches[node] = w
+	}
+	return w
+}
+
+func toPID(p interface{}) pid.PID {
+	if p, ok := p.(pid.PID); ok {
+		return p
+	}
+	return nil
+}
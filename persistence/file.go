@@ -0,0 +1,184 @@
+package persistence
+
+import (
+	"bufio"
+	"fmt"
+	"github.com/hedisam/goactor/codec"
+	"github.com/hedisam/goactor/internal/persist"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+func init() {
+	codec.Register(Event{})
+	codec.Register(Snapshot{})
+}
+
+// FileJournal appends the events of each persistence id to its own file in a directory, syncing it before
+// returning. events are encoded with codec.Gob, so their types must be registered with codec.Register.
+type FileJournal struct {
+	dir string
+
+	mu sync.Mutex
+	// highest sequence numbers of the ids whose files have been checked
+	highest map[string]uint64
+}
+
+// NewFileJournal opens the journal in the directory, creating it if it doesn't exist
+func NewFileJournal(dir string) (*FileJournal, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	return &FileJournal{dir: dir, highest: make(map[string]uint64)}, nil
+}
+
+func (j *FileJournal) Append(persistenceID string, events []Event) error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	highest, err := j.load(persistenceID)
+	if err != nil {
+		return err
+	}
+	if err := checkSequence(persistenceID, highest, events); err != nil {
+		return err
+	}
+
+	f, err := os.OpenFile(j.path(persistenceID), os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0644)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	if err := writeEvents(f, events); err != nil {
+		// the events that have made it to the file are cut off, a retry would append them once more otherwise.
+		// if that fails too, the file is checked again by the next call.
+		if f.Truncate(info.Size()) != nil || f.Sync() != nil {
+			delete(j.highest, persistenceID)
+		}
+		return err
+	}
+	if len(events) > 0 {
+		j.highest[persistenceID] = events[len(events)-1].Sequence
+	}
+	return nil
+}
+
+func writeEvents(f *os.File, events []Event) error {
+	w := bufio.NewWriter(f)
+	for _, e := range events {
+		if err := persist.WriteRecord(w, e); err != nil {
+			return err
+		}
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+	return f.Sync()
+}
+
+func (j *FileJournal) Replay(persistenceID string, from uint64, fn func(e Event) error) error {
+	j.mu.Lock()
+	_, err := j.load(persistenceID)
+	j.mu.Unlock()
+	if err != nil {
+		return err
+	}
+	_, err = scan(j.path(persistenceID), func(e Event) error {
+		if e.Sequence < from {
+			return nil
+		}
+		return fn(e)
+	})
+	return err
+}
+
+func (j *FileJournal) HighestSequence(persistenceID string) (uint64, error) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return j.load(persistenceID)
+}
+
+// load returns the highest sequence number of the id, checking its file the first time. the file is truncated
+// after its last complete record, so the next events follow it.
+// it must be called with the lock held.
+func (j *FileJournal) load(persistenceID string) (uint64, error) {
+	if highest, ok := j.highest[persistenceID]; ok {
+		return highest, nil
+	}
+	var highest uint64
+	size, err := scan(j.path(persistenceID), func(e Event) error {
+		highest = e.Sequence
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	if info, err := os.Stat(j.path(persistenceID)); err == nil && info.Size() > size {
+		if err := os.Truncate(j.path(persistenceID), size); err != nil {
+			return 0, err
+		}
+	}
+	j.highest[persistenceID] = highest
+	return highest, nil
+}
+
+func (j *FileJournal) path(persistenceID string) string {
+	return filepath.Join(j.dir, url.PathEscape(persistenceID)+".journal")
+}
+
+// FileSnapshotStore keeps the latest snapshot of each persistence id in its own file in a directory, replacing
+// it atomically. states are encoded with codec.Gob, so their types must be registered with codec.Register.
+type FileSnapshotStore struct {
+	dir string
+}
+
+// NewFileSnapshotStore opens the snapshot store in the directory, creating it if it doesn't exist
+func NewFileSnapshotStore(dir string) (*FileSnapshotStore, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	return &FileSnapshotStore{dir: dir}, nil
+}
+
+func (s *FileSnapshotStore) Save(persistenceID string, snapshot Snapshot) error {
+	return persist.WriteValue(s.path(persistenceID), snapshot)
+}
+
+func (s *FileSnapshotStore) Load(persistenceID string) (*Snapshot, error) {
+	value, err := persist.ReadValue(s.path(persistenceID))
+	if err != nil || value == nil {
+		return nil, err
+	}
+	snapshot, ok := value.(Snapshot)
+	if !ok {
+		return nil, fmt.Errorf("persistence: invalid snapshot of %s", persistenceID)
+	}
+	return &snapshot, nil
+}
+
+func (s *FileSnapshotStore) path(persistenceID string) string {
+	return filepath.Join(s.dir, url.PathEscape(persistenceID)+".snapshot")
+}
+
+// scan calls fn with the events of the file and returns the size of its complete records. it fails if the
+// sequence numbers aren't contiguous from 1.
+func scan(path string, fn func(e Event) error) (int64, error) {
+	var highest uint64
+	return persist.Scan(path, func(value interface{}) error {
+		e, ok := value.(Event)
+		if !ok {
+			return fmt.Errorf("persistence: invalid journal record")
+		}
+		if e.Sequence != highest+1 {
+			return fmt.Errorf("persistence: event %d of journal %s is out of sequence, expected %d",
+				e.Sequence, filepath.Base(path), highest+1)
+		}
+		highest = e.Sequence
+		return fn(e)
+	})
+}
@@ -0,0 +1,158 @@
+package persistence
+
+import (
+	"github.com/hedisam/goactor/actor"
+	"github.com/hedisam/goactor/codec"
+	"github.com/hedisam/goactor/internal/pid"
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+)
+
+// joined is an event holding a pid
+type joined struct {
+	Member *pid.ProtectedPID
+}
+
+func init() {
+	codec.Register(joined{})
+	codec.Register([]*pid.ProtectedPID{})
+}
+
+// previous returns a pid of an actor from a previous run of the process, one that can't be resolved anymore
+func previous(id string) *pid.ProtectedPID {
+	return pid.DecodeRef(pid.Ref{ID: id})
+}
+
+func tempDir(t *testing.T) string {
+	dir, err := ioutil.TempDir("", "persistence")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return dir
+}
+
+func TestFileJournalReplayAfterRestart(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+	journal, err := NewFileJournal(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	events := []Event{
+		{Sequence: 1, Payload: joined{Member: previous("a")}},
+		{Sequence: 2, Payload: joined{Member: previous("b")}},
+	}
+	if err := journal.Append("group", events); err != nil {
+		t.Fatal(err)
+	}
+
+	// a new journal on the same directory is what the restarted process opens
+	journal, err = NewFileJournal(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	highest, err := journal.HighestSequence("group")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if highest != 2 {
+		t.Fatalf("expected the highest sequence to be 2, got %d", highest)
+	}
+	var members []string
+	err = journal.Replay("group", 1, func(e Event) error {
+		members = append(members, pid.EncodeRef(e.Payload.(joined).Member).ID)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("could not replay the journal: %v", err)
+	}
+	if len(members) != 2 || members[0] != "a" || members[1] != "b" {
+		t.Fatalf("unexpected replayed members %v", members)
+	}
+	if err := journal.Append("group", []Event{{Sequence: 3, Payload: joined{Member: previous("c")}}}); err != nil {
+		t.Fatalf("could not append after the restart: %v", err)
+	}
+}
+
+func TestFileSnapshotStoreLoadAfterRestart(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+	store, err := NewFileSnapshotStore(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	state := []*pid.ProtectedPID{previous("a")}
+	if err := store.Save("group", Snapshot{Sequence: 1, State: state}); err != nil {
+		t.Fatal(err)
+	}
+
+	store, err = NewFileSnapshotStore(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	snapshot, err := store.Load("group")
+	if err != nil {
+		t.Fatalf("could not load the snapshot: %v", err)
+	}
+	if snapshot == nil || snapshot.Sequence != 1 {
+		t.Fatalf("unexpected snapshot %+v", snapshot)
+	}
+	members := snapshot.State.([]*pid.ProtectedPID)
+	if len(members) != 1 || pid.EncodeRef(members[0]).ID != "a" {
+		t.Fatalf("unexpected snapshot state %v", members)
+	}
+}
+
+func TestRecoveryAfterRestart(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+	journal, err := NewFileJournal(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	store, err := NewFileSnapshotStore(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Save("group", Snapshot{Sequence: 1, State: []*pid.ProtectedPID{previous("a")}}); err != nil {
+		t.Fatal(err)
+	}
+	events := []Event{
+		{Sequence: 1, Payload: joined{Member: previous("a")}},
+		{Sequence: 2, Payload: joined{Member: previous("b")}},
+	}
+	if err := journal.Append("group", events); err != nil {
+		t.Fatal(err)
+	}
+
+	recovered := make(chan []*pid.ProtectedPID, 1)
+	ppid := actor.Spawn(Func(Config{
+		PersistenceID: "group",
+		Journal:       journal,
+		Snapshots:     store,
+		InitialState: func() interface{} {
+			return []*pid.ProtectedPID{}
+		},
+		Event: func(state interface{}, event interface{}) interface{} {
+			return append(state.([]*pid.ProtectedPID), event.(joined).Member)
+		},
+		Command: func(c *Context, command interface{}) bool {
+			return false
+		},
+		Recovered: func(c *Context) {
+			recovered <- c.State().([]*pid.ProtectedPID)
+		},
+	}))
+	defer actor.Send(ppid, "stop")
+
+	select {
+	case members := <-recovered:
+		if len(members) != 2 || pid.EncodeRef(members[1]).ID != "b" {
+			t.Fatalf("unexpected recovered state %v", members)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("the actor has not recovered")
+	}
+}
@@ -0,0 +1,119 @@
+package persistence
+
+import (
+	"fmt"
+	"sync"
+)
+
+// Event is a journaled event. sequence numbers start at 1 and are contiguous per persistence id.
+type Event struct {
+	Sequence uint64
+	Payload  interface{}
+}
+
+// Snapshot is the state of an actor as of the event with the sequence number
+type Snapshot struct {
+	Sequence uint64
+	State    interface{}
+}
+
+// Journal stores the events of the persistent actors
+type Journal interface {
+	// Append appends the events, it fails if they don't follow the last journaled event of the persistence id
+	Append(persistenceID string, events []Event) error
+	// Replay calls fn with the events of the persistence id in order, starting from the sequence number
+	Replay(persistenceID string, from uint64, fn func(e Event) error) error
+	// HighestSequence returns the sequence number of the last journaled event, zero if there's none
+	HighestSequence(persistenceID string) (uint64, error)
+}
+
+// SnapshotStore stores the latest snapshot of the persistent actors
+type SnapshotStore interface {
+	Save(persistenceID string, snapshot Snapshot) error
+	// Load returns the latest snapshot, nil if there's none
+	Load(persistenceID string) (*Snapshot, error)
+}
+
+// MemoryJournal keeps the events in memory, they survive the actors restarting but not the process
+type MemoryJournal struct {
+	mu     sync.RWMutex
+	events map[string][]Event
+}
+
+// NewMemoryJournal returns an empty memory journal
+func NewMemoryJournal() *MemoryJournal {
+	return &MemoryJournal{events: make(map[string][]Event)}
+}
+
+func (j *MemoryJournal) Append(persistenceID string, events []Event) error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	journaled := j.events[persistenceID]
+	if err := checkSequence(persistenceID, uint64(len(journaled)), events); err != nil {
+		return err
+	}
+	j.events[persistenceID] = append(journaled, events...)
+	return nil
+}
+
+func (j *MemoryJournal) Replay(persistenceID string, from uint64, fn func(e Event) error) error {
+	j.mu.RLock()
+	events := j.events[persistenceID]
+	j.mu.RUnlock()
+	for _, e := range events {
+		if e.Sequence < from {
+			continue
+		}
+		if err := fn(e); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (j *MemoryJournal) HighestSequence(persistenceID string) (uint64, error) {
+	j.mu.RLock()
+	defer j.mu.RUnlock()
+	return uint64(len(j.events[persistenceID])), nil
+}
+
+// MemorySnapshotStore keeps the snapshots in memory. the states are kept as they are, so they shouldn't be
+// modified in place once they've been snapshotted.
+type MemorySnapshotStore struct {
+	mu        sync.RWMutex
+	snapshots map[string]Snapshot
+}
+
+// NewMemorySnapshotStore returns an empty memory snapshot store
+func NewMemorySnapshotStore() *MemorySnapshotStore {
+	return &MemorySnapshotStore{snapshots: make(map[string]Snapshot)}
+}
+
+func (s *MemorySnapshotStore) Save(persistenceID string, snapshot Snapshot) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.snapshots[persistenceID] = snapshot
+	return nil
+}
+
+func (s *MemorySnapshotStore) Load(persistenceID string) (*Snapshot, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	snapshot, ok := s.snapshots[persistenceID]
+	if !ok {
+		return nil, nil
+	}
+	return &snapshot, nil
+}
+
+// checkSequence makes sure the events follow the highest sequence number, which catches two actors running
+// with the same persistence id
+func checkSequence(persistenceID string, highest uint64, events []Event) error {
+	for i, e := range events {
+		if e.Sequence != highest+uint64(i)+1 {
+			return fmt.Errorf("persistence: event %d of %s is out of sequence, expected %d",
+				e.Sequence, persistenceID, highest+uint64(i)+1)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,133 @@
+package persistence
+
+import (
+	"fmt"
+	"github.com/hedisam/goactor/actor"
+	"github.com/hedisam/goactor/internal/persist"
+)
+
+// Config describes an event sourced actor. its state is only ever changed by applying events, which are appended to
+// the journal before they're applied. when the actor starts, a supervised restart included, it recovers its state
+// by replaying the latest snapshot and the events that follow it.
+type Config struct {
+	// PersistenceID identifies the actor's events in the journal. if empty, the actor's first argument is used,
+	// which must be a string, so one func can run many actors, an entity per id for instance.
+	PersistenceID string
+	Journal       Journal
+	// Snapshots stores the snapshots, no snapshots are taken if it's nil
+	Snapshots SnapshotStore
+	// SnapshotEvery is the number of events between two snapshots, zero means never
+	SnapshotEvery uint64
+	// InitialState returns the state before any event has been applied
+	InitialState func() interface{}
+	// Command handles the messages, persisting events through the context. returning false stops the actor.
+	Command func(c *Context, command interface{}) (loop bool)
+	// Event applies an event to the state and returns the new state. it's called on recovery too, so it must
+	// not have side effects.
+	Event func(state interface{}, event interface{}) interface{}
+	// Recovered is called once the state has been recovered, before the first command. it's optional.
+	Recovered func(c *Context)
+}
+
+// Context is passed to the command handler, it's the actor along with its persistent state
+type Context struct {
+	*actor.Actor
+	config        Config
+	persistenceID string
+	state         interface{}
+	sequence      uint64
+	snapshotted   uint64
+}
+
+// Func returns the actor func of the event sourced actor, to be spawned or used in a supervisor spec.
+// an actor that fails to recover panics, so its supervisor can try again.
+func Func(config Config) actor.Func {
+	return func(a *actor.Actor) {
+		c, err := recoverState(a, config)
+		if err != nil {
+			panic(fmt.Errorf("persistence: could not recover %s: %v", c.persistenceID, err))
+		}
+		if config.Recovered != nil {
+			config.Recovered(c)
+		}
+		a.Receive(func(message interface{}) (loop bool) {
+			return config.Command(c, message)
+		})
+	}
+}
+
+// PersistenceID returns the id the actor's events are journaled under
+func (c *Context) PersistenceID() string {
+	return c.persistenceID
+}
+
+// State returns the current state
+func (c *Context) State() interface{} {
+	return c.state
+}
+
+// Sequence returns the sequence number of the last persisted event
+func (c *Context) Sequence() uint64 {
+	return c.sequence
+}
+
+// Persist appends the events to the journal and then applies them to the state, which it returns. if the journal
+// fails nothing is applied, the handler decides whether to go on or to crash and recover.
+func (c *Context) Persist(events ...interface{}) (interface{}, error) {
+	if len(events) == 0 {
+		return c.state, nil
+	}
+	journaled := make([]Event, len(events))
+	for i, event := range events {
+		journaled[i] = Event{Sequence: c.sequence + uint64(i) + 1, Payload: event}
+	}
+	if err := c.config.Journal.Append(c.persistenceID, journaled); err != nil {
+		return c.state, err
+	}
+	for _, e := range journaled {
+		c.state = c.config.Event(c.state, e.Payload)
+		c.sequence = e.Sequence
+	}
+	c.snapshot()
+	return c.state, nil
+}
+
+// snapshot saves the state once enough events have been persisted since the last snapshot. a failed snapshot only
+// makes the next recovery replay more events, so it's retried with the next event.
+func (c *Context) snapshot() {
+	if c.config.Snapshots == nil || c.config.SnapshotEvery == 0 || c.sequence-c.snapshotted < c.config.SnapshotEvery {
+		return
+	}
+	if err := c.config.Snapshots.Save(c.persistenceID, Snapshot{Sequence: c.sequence, State: c.state}); err == nil {
+		c.snapshotted = c.sequence
+	}
+}
+
+// recoverState rebuilds the state from the latest snapshot and the events following it
+func recoverState(a *actor.Actor, config Config) (*Context, error) {
+	c := &Context{Actor: a, config: config}
+	var err error
+	if c.persistenceID, err = persist.ID(config.PersistenceID, a.Args()); err != nil {
+		return c, err
+	}
+	if config.InitialState != nil {
+		c.state = config.InitialState()
+	}
+
+	if config.Snapshots != nil {
+		snapshot, err := config.Snapshots.Load(c.persistenceID)
+		if err != nil {
+			return c, err
+		}
+		if snapshot != nil {
+			c.state = snapshot.State
+			c.sequence, c.snapshotted = snapshot.Sequence, snapshot.Sequence
+		}
+	}
+	err = config.Journal.Replay(c.persistenceID, c.sequence+1, func(e Event) error {
+		c.state = config.Event(c.state, e.Payload)
+		c.sequence = e.Sequence
+		return nil
+	})
+	return c, err
+}
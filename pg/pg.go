@@ -0,0 +1,177 @@
+package pg
+
+import (
+	"github.com/hedisam/goactor/actor"
+	"github.com/hedisam/goactor/internal/pid"
+	"math/rand"
+	"sync"
+)
+
+// Joined is sent to subscribers when an actor joins a group
+type Joined struct {
+	Group string
+	PID   *pid.ProtectedPID
+}
+
+// Left is sent to subscribers when an actor leaves a group, either explicitly or by terminating
+type Left struct {
+	Group string
+	PID   *pid.ProtectedPID
+}
+
+type groups struct {
+	sync.RWMutex
+	members     map[string]map[pid.PID]struct{}
+	subscribers map[pid.PID]struct{}
+}
+
+var state = &groups{
+	members:     make(map[string]map[pid.PID]struct{}),
+	subscribers: make(map[pid.PID]struct{}),
+}
+
+// Join adds the actor to the group. the membership is removed automatically when the actor terminates.
+// joining a group more than once has no effect.
+func Join(group string, ppid *pid.ProtectedPID) {
+	_pid := pid.ExtractPID(ppid)
+
+	state.Lock()
+	members, ok := state.members[group]
+	if !ok {
+		members = make(map[pid.PID]struct{})
+		state.members[group] = members
+	}
+	if _, joined := members[_pid]; joined {
+		state.Unlock()
+		return
+	}
+	members[_pid] = struct{}{}
+	subscribers := state.subscribersList()
+	state.Unlock()
+
+	watch(_pid)
+	notify(subscribers, Joined{Group: group, PID: ppid})
+}
+
+// Leave removes the actor from the group
+func Leave(group string, ppid *pid.ProtectedPID) {
+	_pid := pid.ExtractPID(ppid)
+
+	state.Lock()
+	if !state.leave(group, _pid) {
+		state.Unlock()
+		return
+	}
+	subscribers := state.subscribersList()
+	state.Unlock()
+
+	notify(subscribers, Left{Group: group, PID: ppid})
+}
+
+// Members returns the actors that are members of the group
+func Members(group string) []*pid.ProtectedPID {
+	state.RLock()
+	defer state.RUnlock()
+
+	members := make([]*pid.ProtectedPID, 0, len(state.members[group]))
+	for _pid := range state.members[group] {
+		members = append(members, pid.NewProtectedPID(_pid))
+	}
+	return members
+}
+
+// RandomMember picks one of the group members randomly. it returns nil if the group is empty.
+func RandomMember(group string) *pid.ProtectedPID {
+	members := Members(group)
+	if len(members) == 0 {
+		return nil
+	}
+	return members[rand.Intn(len(members))]
+}
+
+// Groups returns the name of all groups that have at least one member
+func Groups() []string {
+	state.RLock()
+	defer state.RUnlock()
+
+	names := make([]string, 0, len(state.members))
+	for name := range state.members {
+		names = append(names, name)
+	}
+	return names
+}
+
+// Broadcast sends the message to every member of the group
+func Broadcast(group string, message interface{}) {
+	for _, member := range Members(group) {
+		actor.Send(member, message)
+	}
+}
+
+// Subscribe registers the actor to receive Joined and Left events for all groups
+func Subscribe(ppid *pid.ProtectedPID) {
+	_pid := pid.ExtractPID(ppid)
+
+	state.Lock()
+	state.subscribers[_pid] = struct{}{}
+	state.Unlock()
+
+	watch(_pid)
+}
+
+// Unsubscribe stops sending group events to the actor
+func Unsubscribe(ppid *pid.ProtectedPID) {
+	state.Lock()
+	delete(state.subscribers, pid.ExtractPID(ppid))
+	state.Unlock()
+}
+
+// leave must be called while holding the lock. it returns false if the actor wasn't a member.
+func (g *groups) leave(group string, _pid pid.PID) bool {
+	members, ok := g.members[group]
+	if !ok {
+		return false
+	}
+	if _, joined := members[_pid]; !joined {
+		return false
+	}
+	delete(members, _pid)
+	if len(members) == 0 {
+		delete(g.members, group)
+	}
+	return true
+}
+
+// leaveAll removes a terminated actor from every group and the subscribers list
+func (g *groups) leaveAll(_pid pid.PID) {
+	g.Lock()
+	delete(g.subscribers, _pid)
+	var left []string
+	for group := range g.members {
+		if g.leave(group, _pid) {
+			left = append(left, group)
+		}
+	}
+	subscribers := g.subscribersList()
+	g.Unlock()
+
+	ppid := pid.NewProtectedPID(_pid)
+	for _, group := range left {
+		notify(subscribers, Left{Group: group, PID: ppid})
+	}
+}
+
+// subscribersList must be called while holding the lock
+func (g *groups) subscribersList() []pid.PID {
+	subscribers := make([]pid.PID, 0, len(g.subscribers))
+	for subscriber := range g.subscribers {
+		subscribers = append(subscribers, subscriber)
+	}
+	return subscribers
+}
+
+func notify(subscribers []pid.PID, event interface{}) {
+	for _, subscriber := range subscribers {
+		actor.Send(pid.NewProtectedPID(subscriber), event)
+	}
+}
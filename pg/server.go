@@ -0,0 +1,48 @@
+package pg
+
+import (
+	"github.com/hedisam/goactor/actor"
+	"github.com/hedisam/goactor/internal/pid"
+	"github.com/hedisam/goactor/sysmsg"
+)
+
+// serverPID is the actor that monitors group members and subscribers
+var serverPID *pid.ProtectedPID
+
+type cmdWatch struct {
+	pid pid.PID
+}
+
+func init() {
+	serverPID = actor.Spawn(server)
+}
+
+// watch asks the server to monitor the actor so it can be removed from the groups when it terminates
+func watch(_pid pid.PID) {
+	actor.Send(serverPID, cmdWatch{pid: _pid})
+}
+
+func server(act *actor.Actor) {
+	// actors we're monitoring. an actor stays monitored after leaving its groups which is harmless since
+	// its termination just finds nothing to remove.
+	watching := make(map[pid.PID]struct{})
+
+	act.Receive(func(message interface{}) (loop bool) {
+		switch msg := message.(type) {
+		case cmdWatch:
+			if _, ok := watching[msg.pid]; ok {
+				return true
+			}
+			watching[msg.pid] = struct{}{}
+			act.Monitor(pid.NewProtectedPID(msg.pid))
+		case sysmsg.Exit:
+			who, ok := msg.Who.(pid.PID)
+			if !ok {
+				return true
+			}
+			delete(watching, who)
+			state.leaveAll(who)
+		}
+		return true
+	})
+}
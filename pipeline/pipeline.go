@@ -0,0 +1,251 @@
+package pipeline
+
+import (
+	"fmt"
+	"github.com/hedisam/goactor/actor"
+	"github.com/hedisam/goactor/internal/pid"
+	"github.com/hedisam/goactor/stage"
+	"github.com/hedisam/goactor/supervisor"
+	"github.com/hedisam/goactor/supervisor/spec"
+	"github.com/hedisam/goactor/sysmsg"
+	"runtime"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// DefaultBatcher is the batcher of the messages that haven't been put in one
+const DefaultBatcher = "default"
+
+const (
+	defaultProcessorDemand = 10
+	defaultBatchSize       = 100
+	defaultBatchTimeout    = time.Second
+	// retryInterval is how often a stage tries to subscribe to a producer that isn't running
+	retryInterval = 100 * time.Millisecond
+)
+
+// Message is a message flowing through the pipeline. the events of the producer are wrapped in messages unless
+// they're messages already.
+type Message struct {
+	Data interface{}
+	// Batcher is the batcher the message goes to once it's been processed
+	Batcher string
+	// BatchKey splits the messages of a batcher into batches, messages with different keys never share a batch
+	BatchKey interface{}
+	// Err is the reason the message has failed, nil if it hasn't
+	Err error
+}
+
+// Fail returns the message marked as failed
+func (m Message) Fail(reason error) Message {
+	m.Err = reason
+	return m
+}
+
+// Failed returns true if the message has failed
+func (m Message) Failed() bool {
+	return m.Err != nil
+}
+
+// PutBatcher returns the message sent to the batcher
+func (m Message) PutBatcher(batcher string) Message {
+	m.Batcher = batcher
+	return m
+}
+
+// PutBatchKey returns the message with the batch key
+func (m Message) PutBatchKey(key interface{}) Message {
+	m.BatchKey = key
+	return m
+}
+
+// Processors describes the processors of a pipeline, which handle the messages one by one
+type Processors struct {
+	// Concurrency is the number of processors, default is the number of CPUs
+	Concurrency int
+	// MaxDemand is the most messages a processor has asked for at a time, default is 10
+	MaxDemand int
+	// Handle processes the message and returns it, failed or not. a panic fails the message.
+	Handle func(m Message) Message
+}
+
+// Batcher describes a batcher, which groups the processed messages into batches handled together
+type Batcher struct {
+	// BatchSize is the size at which a batch is handled, default is 100
+	BatchSize int
+	// BatchTimeout is how long a batch waits to be filled before it's handled anyway, default is 1s
+	BatchTimeout time.Duration
+	// Handle handles the batch and returns its messages, failed or not. a panic fails the whole batch.
+	Handle func(batch []Message, info BatchInfo) []Message
+}
+
+// BatchInfo describes a batch
+type BatchInfo struct {
+	Batcher string
+	Key     interface{}
+	// Timeout is true if the batch is handled because its timeout has expired, or the pipeline is stopping
+	Timeout bool
+}
+
+// Config describes a pipeline
+type Config struct {
+	// Name prefixes the names the pipeline's stages are registered under
+	Name       string
+	Producer   stage.Producer
+	Processors Processors
+	// Batchers by their names, processed messages are acked right away if there are none
+	Batchers map[string]Batcher
+	// Ack is called with the messages once they've succeeded or failed, from the processors' and the batchers'
+	// goroutines, so it must be safe for concurrent use. it's optional.
+	Ack func(successful []Message, failed []Message)
+	// MaxRestarts within Period seconds before the pipeline's supervisor gives up, default is 3 within 5
+	MaxRestarts int
+	Period      int
+}
+
+// Pipeline is a producer feeding a pool of processors, whose messages are then grouped into batches by the
+// batchers. the stages run under a single one for one supervisor and find each other by name, so a restarted
+// stage is subscribed to again.
+type Pipeline struct {
+	config Config
+	sup    *spec.SupRef
+}
+
+// Start starts the pipeline's stages under their supervisor
+func Start(config Config) (*Pipeline, error) {
+	if config.Name == "" {
+		return nil, fmt.Errorf("pipeline: name is required")
+	}
+	if config.Processors.Handle == nil {
+		return nil, fmt.Errorf("pipeline: processors handler is required")
+	}
+	if config.Processors.Concurrency <= 0 {
+		config.Processors.Concurrency = runtime.NumCPU()
+	}
+	if config.Processors.MaxDemand <= 0 {
+		config.Processors.MaxDemand = defaultProcessorDemand
+	}
+	for name, b := range config.Batchers {
+		if b.Handle == nil {
+			return nil, fmt.Errorf("pipeline: batcher %s handler is required", name)
+		}
+		if b.BatchSize <= 0 {
+			b.BatchSize = defaultBatchSize
+		}
+		if b.BatchTimeout <= 0 {
+			b.BatchTimeout = defaultBatchTimeout
+		}
+		config.Batchers[name] = b
+	}
+	if config.MaxRestarts <= 0 || config.Period <= 0 {
+		config.MaxRestarts, config.Period = 3, 5
+	}
+
+	p := &Pipeline{config: config}
+	specs := []spec.Spec{worker(p.producerName(), producer(config.Producer))}
+	for i := 0; i < config.Processors.Concurrency; i++ {
+		specs = append(specs, worker(p.processorName(i), processor(p)))
+	}
+	for _, name := range p.batchers() {
+		specs = append(specs, worker(p.batcherName(name), batcher(p, name)))
+	}
+	sup, err := supervisor.Start(supervisor.NewOptions(supervisor.OneForOneStrategy, config.MaxRestarts, config.Period),
+		specs...)
+	if err != nil {
+		return nil, err
+	}
+	p.sup = sup
+	return p, nil
+}
+
+// Producer returns the pid of the running producer, nil if it's not running
+func (p *Pipeline) Producer() *pid.ProtectedPID {
+	return actor.WhereIs(p.producerName())
+}
+
+// Stop stops the producer and waits for the messages in flight to be processed, batched and acked, up to the
+// timeout, before stopping the supervisor
+func (p *Pipeline) Stop(timeout time.Duration) error {
+	parent, dispose := actor.NewParentActor()
+	defer dispose()
+
+	names := []string{p.producerName()}
+	for i := 0; i < p.config.Processors.Concurrency; i++ {
+		names = append(names, p.processorName(i))
+	}
+	for _, name := range p.batchers() {
+		names = append(names, p.batcherName(name))
+	}
+	running := 0
+	for _, name := range names {
+		if ppid := actor.WhereIs(name); ppid != nil {
+			parent.Monitor(ppid)
+			running++
+		}
+	}
+
+	var err error
+	if producer := p.Producer(); producer != nil {
+		actor.Send(producer, drain{})
+	}
+	deadline := time.Now().Add(timeout)
+	for running > 0 {
+		left := time.Until(deadline)
+		if left <= 0 {
+			err = fmt.Errorf("pipeline: %s did not drain in time", p.config.Name)
+			break
+		}
+		parent.ReceiveWithTimeout(left, func(message interface{}) (loop bool) {
+			if _, ok := message.(sysmsg.Exit); ok {
+				running--
+			}
+			return false
+		})
+	}
+	if stopErr := p.sup.Stop("pipeline stopped"); err == nil {
+		err = stopErr
+	}
+	return err
+}
+
+func (p *Pipeline) batchers() []string {
+	names := make([]string, 0, len(p.config.Batchers))
+	for name := range p.config.Batchers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func (p *Pipeline) producerName() string {
+	return p.config.Name + ".producer"
+}
+
+func (p *Pipeline) processorName(i int) string {
+	return p.config.Name + ".processor." + strconv.Itoa(i)
+}
+
+func (p *Pipeline) batcherName(batcher string) string {
+	return p.config.Name + ".batcher." + batcher
+}
+
+func (p *Pipeline) ack(messages []Message) {
+	if p.config.Ack == nil || len(messages) == 0 {
+		return
+	}
+	var successful, failed []Message
+	for _, m := range messages {
+		if m.Failed() {
+			failed = append(failed, m)
+		} else {
+			successful = append(successful, m)
+		}
+	}
+	p.config.Ack(successful, failed)
+}
+
+// the stages exit normally once they've drained, so they're only restarted if they crash
+func worker(name string, fn actor.Func) spec.WorkerSpec {
+	return spec.NewWorkerSpec(name, fn).SetRestart(spec.RestartTransient)
+}
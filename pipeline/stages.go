@@ -0,0 +1,257 @@
+package pipeline
+
+import (
+	"fmt"
+	"github.com/hedisam/goactor/actor"
+	"github.com/hedisam/goactor/stage"
+	"time"
+)
+
+// drain tells the producer to stop producing and exit once its buffer is empty
+type drain struct{}
+
+// drained is sent to a draining stage to check whether its buffer is empty
+type drained struct{}
+
+// resubscribe is sent to a stage to subscribe to a producer
+type resubscribe struct {
+	subscription stage.Subscription
+}
+
+// flush is sent to a batcher when the timeout of a batch expires
+type flush struct {
+	key interface{}
+	// generation tells the batch apart from the batches that have been handled under the same key
+	generation uint64
+}
+
+// producer wraps the producer so it stops producing when the pipeline is stopping
+func producer(config stage.Producer) actor.Func {
+	draining := false
+	demand, handle := config.Demand, config.Handle
+	config.Demand = func(c *stage.Context, n int) {
+		if !draining && demand != nil {
+			demand(c, n)
+		}
+	}
+	config.Handle = func(c *stage.Context, message interface{}) (loop bool) {
+		switch message.(type) {
+		case drain:
+			draining = true
+			return checkDrained(c)
+		case drained:
+			return checkDrained(c)
+		}
+		if handle == nil {
+			return true
+		}
+		return handle(c, message)
+	}
+	fn := stage.NewProducer(config)
+	return func(a *actor.Actor) {
+		draining = false
+		fn(a)
+	}
+}
+
+// checkDrained returns false once the stage's buffer is empty, checking again later if it's not
+func checkDrained(c *stage.Context) bool {
+	if c.Buffered() == 0 {
+		return false
+	}
+	self := c.Self()
+	time.AfterFunc(retryInterval/10, func() {
+		actor.Send(self, drained{})
+	})
+	return true
+}
+
+// processor returns the actor func of a processor, a consumer of the producer or, if there are batchers,
+// a producer consumer dispatching the messages to them by batcher
+func processor(p *Pipeline) actor.Func {
+	subscription := stage.Subscription{To: p.producerName(), MaxDemand: p.config.Processors.MaxDemand}
+	events := func(c *stage.Context, events []interface{}) {
+		processed := make([]Message, 0, len(events))
+		for _, event := range events {
+			processed = append(processed, process(p, event))
+		}
+		if len(p.config.Batchers) == 0 {
+			p.ack(processed)
+			return
+		}
+		var failed []Message
+		for _, m := range processed {
+			if m.Failed() {
+				failed = append(failed, m)
+				continue
+			}
+			c.Dispatch(m)
+		}
+		p.ack(failed)
+	}
+	handle := func(c *stage.Context, message interface{}) (loop bool) {
+		switch msg := message.(type) {
+		case resubscribe:
+			subscribe(c, msg.subscription)
+		case drained:
+			return checkDrained(c)
+		}
+		return true
+	}
+	// the producer exits normally only when the pipeline is stopping, the buffered messages are dispatched first
+	cancelled := func(c *stage.Context, subscription stage.Subscription, reason string) (loop bool) {
+		if reason == "" {
+			return checkDrained(c)
+		}
+		subscribeLater(c, subscription)
+		return true
+	}
+
+	var fn actor.Func
+	if len(p.config.Batchers) == 0 {
+		fn = stage.NewConsumer(stage.Consumer{Events: events, Handle: handle, Cancelled: cancelled})
+	} else {
+		fn = stage.NewProducerConsumer(stage.ProducerConsumer{
+			Events:    events,
+			Handle:    handle,
+			Cancelled: cancelled,
+			Dispatcher: func() stage.Dispatcher {
+				return stage.NewPartitionDispatcher(func(event interface{}) interface{} {
+					return event.(Message).Batcher
+				})
+			},
+		})
+	}
+	return func(a *actor.Actor) {
+		actor.Send(a.Self(), resubscribe{subscription: subscription})
+		fn(a)
+	}
+}
+
+// process runs the processors' handler, failing the message if it panics or if its batcher doesn't exist
+func process(p *Pipeline, event interface{}) (m Message) {
+	m, ok := event.(Message)
+	if !ok {
+		m = Message{Data: event}
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			m = m.Fail(fmt.Errorf("pipeline: processor panicked: %v", r))
+		}
+	}()
+	m = p.config.Processors.Handle(m)
+	if m.Failed() || len(p.config.Batchers) == 0 {
+		return m
+	}
+	if m.Batcher == "" {
+		m.Batcher = DefaultBatcher
+	}
+	if _, ok := p.config.Batchers[m.Batcher]; !ok {
+		m = m.Fail(fmt.Errorf("pipeline: no batcher %s", m.Batcher))
+	}
+	return m
+}
+
+type batch struct {
+	messages   []Message
+	generation uint64
+}
+
+// batcher returns the actor func of a batcher, a consumer of every processor's partition of the batcher
+func batcher(p *Pipeline, name string) actor.Func {
+	config := p.config.Batchers[name]
+	return func(a *actor.Actor) {
+		batches := make(map[interface{}]*batch)
+		var generation uint64
+		// processors that have exited normally, once they all have the batcher has drained
+		drainedProcessors := 0
+
+		emit := func(key interface{}, timeout bool) {
+			b := batches[key]
+			delete(batches, key)
+			p.ack(handleBatch(config, b.messages, BatchInfo{Batcher: name, Key: key, Timeout: timeout}))
+		}
+		fn := stage.NewConsumer(stage.Consumer{
+			Events: func(c *stage.Context, events []interface{}) {
+				for _, event := range events {
+					m := event.(Message)
+					b, ok := batches[m.BatchKey]
+					if !ok {
+						generation++
+						b = &batch{generation: generation}
+						batches[m.BatchKey] = b
+						self, tick := c.Self(), flush{key: m.BatchKey, generation: generation}
+						time.AfterFunc(config.BatchTimeout, func() {
+							actor.Send(self, tick)
+						})
+					}
+					b.messages = append(b.messages, m)
+					if len(b.messages) >= config.BatchSize {
+						emit(m.BatchKey, false)
+					}
+				}
+			},
+			Handle: func(c *stage.Context, message interface{}) (loop bool) {
+				switch msg := message.(type) {
+				case resubscribe:
+					subscribe(c, msg.subscription)
+				case flush:
+					if b, ok := batches[msg.key]; ok && b.generation == msg.generation {
+						emit(msg.key, true)
+					}
+				}
+				return true
+			},
+			Cancelled: func(c *stage.Context, subscription stage.Subscription, reason string) (loop bool) {
+				if reason != "" {
+					subscribeLater(c, subscription)
+					return true
+				}
+				drainedProcessors++
+				if drainedProcessors < p.config.Processors.Concurrency {
+					return true
+				}
+				for key := range batches {
+					emit(key, true)
+				}
+				return false
+			},
+		})
+
+		for i := 0; i < p.config.Processors.Concurrency; i++ {
+			actor.Send(a.Self(), resubscribe{subscription: stage.Subscription{
+				To:        p.processorName(i),
+				MaxDemand: config.BatchSize,
+				Partition: name,
+			}})
+		}
+		fn(a)
+	}
+}
+
+// handleBatch runs the batch handler, failing the whole batch if it panics
+func handleBatch(config Batcher, messages []Message, info BatchInfo) (handled []Message) {
+	defer func() {
+		if r := recover(); r != nil {
+			handled = make([]Message, len(messages))
+			for i, m := range messages {
+				handled[i] = m.Fail(fmt.Errorf("pipeline: batcher %s panicked: %v", info.Batcher, r))
+			}
+		}
+	}()
+	return config.Handle(messages, info)
+}
+
+// subscribe subscribes the stage to a producer, trying again later if it's not running
+func subscribe(c *stage.Context, subscription stage.Subscription) {
+	if err := c.Subscribe(subscription); err != nil {
+		subscribeLater(c, subscription)
+	}
+}
+
+func subscribeLater(c *stage.Context, subscription stage.Subscription) {
+	self := c.Self()
+	time.AfterFunc(retryInterval, func() {
+		actor.Send(self, resubscribe{subscription: subscription})
+	})
+}
@@ -0,0 +1,310 @@
+package pool
+
+import (
+	"fmt"
+	"github.com/hedisam/goactor/actor"
+	"github.com/hedisam/goactor/internal/pid"
+	"github.com/hedisam/goactor/supervisor"
+	"github.com/hedisam/goactor/supervisor/spec"
+	"github.com/hedisam/goactor/sysmsg"
+	"github.com/rs/xid"
+	"log"
+)
+
+type worker struct {
+	id       string
+	ppid     *pid.ProtectedPID
+	overflow bool
+}
+
+type checkedOut struct {
+	ref   string
+	owner pid.PID
+}
+
+type waiter struct {
+	ref    string
+	sender *pid.ProtectedPID
+	owner  *pid.ProtectedPID
+}
+
+type state struct {
+	options *Options
+	manager *actor.Actor
+	// workers is the worker supervisor. workers are temporary children, the manager replaces the dead ones.
+	workers    *spec.SupRef
+	all        map[pid.PID]*worker
+	idle       []*worker
+	checkedOut map[pid.PID]checkedOut
+	waiting    []waiter
+	// owners counts the workers each monitored owner has checked out
+	owners   map[pid.PID]int
+	overflow int
+	// stale are the ids of dead workers whose specs are yet to be deleted from the worker supervisor.
+	// the supervisor might not have processed the worker's exit by the time we try to delete it.
+	stale []string
+}
+
+func manager(act *actor.Actor) {
+	options := act.Args()[0].(*Options)
+	state := &state{
+		options:    options,
+		manager:    act,
+		all:        make(map[pid.PID]*worker),
+		checkedOut: make(map[pid.PID]checkedOut),
+		owners:     make(map[pid.PID]int),
+	}
+	if err := state.init(); err != nil {
+		panic(err)
+	}
+	// the worker supervisor doesn't die with us since it's not our supervisor, we have to take it down ourselves
+	defer actor.Send(state.workers.PPID, sysmsg.Shutdown{Parent: pid.ExtractPID(act.Self())})
+
+	act.Receive(func(message interface{}) (loop bool) {
+		switch msg := message.(type) {
+		case checkout:
+			state.handleCheckout(msg)
+		case cancelWaiting:
+			state.handleCancel(msg)
+		case checkin:
+			state.checkin(pid.ExtractPID(msg.worker))
+		case status:
+			actor.Send(msg.sender, Status{
+				Idle:     len(state.idle),
+				Busy:     len(state.checkedOut),
+				Overflow: state.overflow,
+				Waiting:  len(state.waiting),
+			})
+		case sysmsg.Exit:
+			who, _ := msg.Who.(pid.PID)
+			if _, ok := state.all[who]; ok {
+				state.handleWorkerExit(who)
+			} else if _, ok := state.owners[who]; ok {
+				state.handleOwnerExit(who)
+			}
+		default:
+			log.Println("pool manager received unknown message:", msg)
+		}
+		return true
+	})
+}
+
+func (state *state) init() error {
+	specs := make([]spec.Spec, state.options.Size)
+	ids := make([]string, state.options.Size)
+	for i := range specs {
+		ids[i] = newWorkerID()
+		specs[i] = state.workerSpec(ids[i])
+	}
+	workers, err := supervisor.Start(supervisor.OneForOneStrategyOption().SetNaming(supervisor.NoNaming), specs...)
+	if err != nil {
+		return err
+	}
+	state.workers = workers
+	// if the worker supervisor goes down we go down with it
+	state.manager.Link(workers.PPID)
+
+	for _, id := range ids {
+		w, err := state.track(id, false)
+		if err != nil {
+			return err
+		}
+		state.idle = append(state.idle, w)
+	}
+	return nil
+}
+
+func (state *state) workerSpec(id string) spec.WorkerSpec {
+	return spec.NewWorkerSpec(id, state.options.Worker, state.options.Args...).
+		SetRestart(spec.RestartNever).
+		SetShutdown(spec.ShutdownKill)
+}
+
+// track finds a started worker's pid and monitors it
+func (state *state) track(id string, overflow bool) (*worker, error) {
+	children, err := state.workers.WithChildren()
+	if err != nil {
+		return nil, err
+	}
+	for _, child := range children.ChildrenInfo {
+		if child.Id != id || child.PID == nil {
+			continue
+		}
+		w := &worker{id: id, ppid: child.PID, overflow: overflow}
+		state.all[pid.ExtractPID(child.PID)] = w
+		state.manager.Monitor(child.PID)
+		return w, nil
+	}
+	return nil, fmt.Errorf("pool worker %s is not running", id)
+}
+
+// startWorker starts a new worker under the worker supervisor
+func (state *state) startWorker(overflow bool) (*worker, error) {
+	state.deleteStale()
+	id := newWorkerID()
+	if err := state.workers.StartChild(state.workerSpec(id)); err != nil {
+		return nil, err
+	}
+	return state.track(id, overflow)
+}
+
+// dismiss terminates an overflow worker
+func (state *state) dismiss(w *worker) {
+	state.manager.Demonitor(w.ppid)
+	delete(state.all, pid.ExtractPID(w.ppid))
+	state.overflow--
+	if err := state.workers.TerminateChild(w.id); err != nil {
+		log.Println("pool: could not terminate overflow worker:", err)
+	}
+	state.stale = append(state.stale, w.id)
+	state.deleteStale()
+}
+
+func (state *state) deleteStale() {
+	var stale []string
+	for _, id := range state.stale {
+		if err := state.workers.DeleteChild(id); err != nil {
+			stale = append(stale, id)
+		}
+	}
+	state.stale = stale
+}
+
+func (state *state) handleCheckout(req checkout) {
+	if len(state.idle) != 0 {
+		w := state.idle[len(state.idle)-1]
+		state.idle = state.idle[:len(state.idle)-1]
+		state.handOut(w, req.ref, req.sender, req.owner)
+		return
+	}
+	if state.overflow < state.options.MaxOverflow {
+		w, err := state.startWorker(true)
+		if err != nil {
+			actor.Send(req.sender, err)
+			return
+		}
+		state.overflow++
+		state.handOut(w, req.ref, req.sender, req.owner)
+		return
+	}
+	if !req.block {
+		actor.Send(req.sender, ErrFull)
+		return
+	}
+	state.waiting = append(state.waiting, waiter{ref: req.ref, sender: req.sender, owner: req.owner})
+}
+
+func (state *state) handOut(w *worker, ref string, sender, owner *pid.ProtectedPID) {
+	co := checkedOut{ref: ref}
+	if owner != nil {
+		co.owner = pid.ExtractPID(owner)
+		if state.owners[co.owner] == 0 {
+			state.manager.Monitor(owner)
+		}
+		state.owners[co.owner]++
+	}
+	state.checkedOut[pid.ExtractPID(w.ppid)] = co
+	actor.Send(sender, w.ppid)
+}
+
+func (state *state) handleCancel(req cancelWaiting) {
+	for i, w := range state.waiting {
+		if w.ref == req.ref {
+			state.waiting = append(state.waiting[:i], state.waiting[i+1:]...)
+			return
+		}
+	}
+	// the worker might have been handed out right when the caller gave up
+	for workerPID, co := range state.checkedOut {
+		if co.ref == req.ref {
+			state.checkin(workerPID)
+			return
+		}
+	}
+}
+
+func (state *state) checkin(workerPID pid.PID) {
+	co, ok := state.checkedOut[workerPID]
+	if !ok {
+		return
+	}
+	delete(state.checkedOut, workerPID)
+	state.release(co.owner)
+	state.available(state.all[workerPID])
+}
+
+// available gives a free worker to the first waiting caller, or puts it back in the pool
+func (state *state) available(w *worker) {
+	if len(state.waiting) != 0 {
+		next := state.waiting[0]
+		state.waiting = state.waiting[1:]
+		state.handOut(w, next.ref, next.sender, next.owner)
+		return
+	}
+	if w.overflow {
+		state.dismiss(w)
+		return
+	}
+	state.idle = append(state.idle, w)
+}
+
+// release decrements the owner's checked out workers and stops monitoring it if it has none left
+func (state *state) release(owner pid.PID) {
+	if owner == nil {
+		return
+	}
+	state.owners[owner]--
+	if state.owners[owner] > 0 {
+		return
+	}
+	delete(state.owners, owner)
+	state.manager.Demonitor(pid.NewProtectedPID(owner))
+}
+
+func (state *state) handleWorkerExit(workerPID pid.PID) {
+	w := state.all[workerPID]
+	delete(state.all, workerPID)
+	state.stale = append(state.stale, w.id)
+
+	if co, ok := state.checkedOut[workerPID]; ok {
+		delete(state.checkedOut, workerPID)
+		state.release(co.owner)
+	}
+	for i, idle := range state.idle {
+		if idle == w {
+			state.idle = append(state.idle[:i], state.idle[i+1:]...)
+			break
+		}
+	}
+
+	if w.overflow {
+		state.overflow--
+		return
+	}
+	replacement, err := state.startWorker(false)
+	if err != nil {
+		log.Println("pool: could not replace worker:", err)
+		return
+	}
+	state.available(replacement)
+}
+
+func (state *state) handleOwnerExit(owner pid.PID) {
+	delete(state.owners, owner)
+	for workerPID, co := range state.checkedOut {
+		if co.owner == owner {
+			delete(state.checkedOut, workerPID)
+			state.available(state.all[workerPID])
+		}
+	}
+	for i := 0; i < len(state.waiting); i++ {
+		if w := state.waiting[i]; w.owner != nil && pid.ExtractPID(w.owner) == owner {
+			state.waiting = append(state.waiting[:i], state.waiting[i+1:]...)
+			i--
+		}
+	}
+}
+
+func newWorkerID() string {
+	return "worker-" + xid.New().String()
+}
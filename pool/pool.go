@@ -0,0 +1,212 @@
+package pool
+
+import (
+	"fmt"
+	"github.com/hedisam/goactor/actor"
+	"github.com/hedisam/goactor/internal/pid"
+	"github.com/hedisam/goactor/supervisor"
+	"github.com/hedisam/goactor/supervisor/spec"
+	"github.com/rs/xid"
+	"time"
+)
+
+const defaultCallTimeout = 5 * time.Second
+
+// ErrFull is returned by a non-blocking checkout when all workers, including the overflow ones, are busy
+var ErrFull = fmt.Errorf("pool: all workers are busy")
+
+// ErrTimeout is returned by a blocking checkout when no worker becomes available in time
+var ErrTimeout = fmt.Errorf("pool: checkout timeout")
+
+type Options struct {
+	// Name is the name the pool manager is registered under
+	Name string
+	// Via is the resolver the pool manager is registered through. nil means the default registry.
+	Via actor.Resolver
+	// Size is the number of long-lived workers
+	Size int
+	// MaxOverflow is the number of temporary workers that can be created when all workers are busy
+	MaxOverflow int
+	Worker      actor.Func
+	Args        []interface{}
+}
+
+// Status describes the pool's workers
+type Status struct {
+	Idle     int
+	Busy     int
+	Overflow int
+	Waiting  int
+}
+
+func NewOptions(name string, size, maxOverflow int, worker actor.Func, args ...interface{}) Options {
+	return Options{
+		Name:        name,
+		Size:        size,
+		MaxOverflow: maxOverflow,
+		Worker:      worker,
+		Args:        args,
+	}
+}
+
+func (opt Options) SetVia(via actor.Resolver) Options {
+	opt.Via = via
+	return opt
+}
+
+func (opt *Options) checkOptions() error {
+	if opt.Name == "" {
+		return fmt.Errorf("invalid pool Name: %s", opt.Name)
+	} else if opt.Size < 1 {
+		return fmt.Errorf("invalid pool Size: %d", opt.Size)
+	} else if opt.MaxOverflow < 0 {
+		return fmt.Errorf("invalid pool MaxOverflow: %d", opt.MaxOverflow)
+	} else if opt.Worker == nil {
+		return fmt.Errorf("pool's worker fn (actor.Func(actor.Actor)) could not be nil")
+	}
+	return nil
+}
+
+func (opt *Options) resolver() actor.Resolver {
+	if opt.Via == nil {
+		return actor.DefaultRegistry()
+	}
+	return opt.Via
+}
+
+// Spec returns the child spec of the pool manager so the pool can be started under any supervisor.
+// the manager starts and supervises its own workers.
+func Spec(options Options) spec.WorkerSpec {
+	return spec.NewWorkerSpec(options.Name, manager, &options).
+		SetRestart(spec.RestartAlways).
+		SetVia(options.resolver())
+}
+
+// Start starts the pool under a new supervisor
+func Start(options Options) (*Ref, error) {
+	if err := options.checkOptions(); err != nil {
+		return nil, err
+	}
+	sup, err := supervisor.Start(supervisor.OneForOneStrategyOption(), Spec(options))
+	if err != nil {
+		return nil, err
+	}
+	ref := NewRef(options.Name, options.Via)
+	ref.Sup = sup
+	return ref, nil
+}
+
+// Ref is used to talk to a pool. it finds the pool manager by its name so it keeps working after the manager
+// gets restarted.
+type Ref struct {
+	Name actor.Name
+	// Sup is the supervisor started by Start, nil if the pool was started by a user's supervisor
+	Sup *spec.SupRef
+}
+
+// NewRef returns a Ref for a pool started under a user's supervisor
+func NewRef(name string, via actor.Resolver) *Ref {
+	return &Ref{Name: actor.ViaName(via, name)}
+}
+
+// Checkout takes a worker out of the pool for the exclusive use of the owner. the worker is checked in
+// automatically if the owner terminates before checking it in. owner can be nil.
+// if block is false and all workers are busy it fails right away with ErrFull, otherwise it waits for a worker to
+// be checked in. a timeout less than one means waiting forever.
+func (r *Ref) Checkout(owner *pid.ProtectedPID, block bool, timeout time.Duration) (*pid.ProtectedPID, error) {
+	manager := actor.WhereIsName(r.Name)
+	if manager == nil {
+		return nil, fmt.Errorf("pool %s is not running", r.Name)
+	}
+
+	ref := xid.New().String()
+	future := actor.NewFutureActor()
+	actor.Send(manager, checkout{ref: ref, sender: future.Self(), owner: owner, block: block})
+
+	if !block {
+		// the manager answers right away
+		timeout = defaultCallTimeout
+	}
+	var result interface{}
+	var err error
+	if timeout > 0 {
+		result, err = future.RecvWithTimeout(timeout)
+	} else {
+		result, err = future.Recv()
+	}
+	if err != nil {
+		// we gave up waiting. the manager checks the worker in if it has already been handed to us.
+		actor.Send(manager, cancelWaiting{ref: ref})
+		return nil, ErrTimeout
+	}
+
+	switch result := result.(type) {
+	case *pid.ProtectedPID:
+		return result, nil
+	case error:
+		return nil, result
+	default:
+		return nil, fmt.Errorf("invalid response sent by pool: %v", result)
+	}
+}
+
+// Checkin returns the worker to the pool
+func (r *Ref) Checkin(worker *pid.ProtectedPID) {
+	actor.SendNamed(r.Name, checkin{worker: worker})
+}
+
+// Transaction checks out a worker, runs fn with it and checks it back in
+func (r *Ref) Transaction(owner *pid.ProtectedPID, timeout time.Duration, fn func(worker *pid.ProtectedPID) error) error {
+	worker, err := r.Checkout(owner, true, timeout)
+	if err != nil {
+		return err
+	}
+	defer r.Checkin(worker)
+	return fn(worker)
+}
+
+// Status returns the current state of the pool's workers
+func (r *Ref) Status() (Status, error) {
+	manager := actor.WhereIsName(r.Name)
+	if manager == nil {
+		return Status{}, fmt.Errorf("pool %s is not running", r.Name)
+	}
+	future := actor.NewFutureActor()
+	actor.Send(manager, status{sender: future.Self()})
+	result, err := future.RecvWithTimeout(defaultCallTimeout)
+	if err != nil {
+		return Status{}, err
+	}
+	s, ok := result.(Status)
+	if !ok {
+		return Status{}, fmt.Errorf("invalid response sent by pool: %v", result)
+	}
+	return s, nil
+}
+
+// Stop stops a pool started by Start
+func (r *Ref) Stop() error {
+	if r.Sup == nil {
+		return fmt.Errorf("pool %s was not started by pool.Start", r.Name)
+	}
+	return r.Sup.Stop("pool stopped")
+}
+
+type checkout struct {
+	ref    string
+	sender *pid.ProtectedPID
+	owner  *pid.ProtectedPID
+	block  bool
+}
+
+type cancelWaiting struct {
+	ref string
+}
+
+type checkin struct {
+	worker *pid.ProtectedPID
+}
+
+type status struct {
+	sender *pid.ProtectedPID
+}
@@ -0,0 +1,122 @@
+package pool
+
+import (
+	"github.com/hedisam/goactor/actor"
+	"github.com/hedisam/goactor/internal/pid"
+	"testing"
+	"time"
+)
+
+func idleWorker(a *actor.Actor) {
+	a.Receive(func(message interface{}) (loop bool) {
+		return true
+	})
+}
+
+func startPool(t *testing.T, name string, size, maxOverflow int) *Ref {
+	ref, err := Start(NewOptions(name, size, maxOverflow, idleWorker))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return ref
+}
+
+// waitForStatus polls the pool's status until it's the expected one
+func waitForStatus(t *testing.T, ref *Ref, expected Status) {
+	deadline := time.Now().Add(time.Second)
+	for {
+		status, err := ref.Status()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if status == expected {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("expected the status %+v, got %+v", expected, status)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestCheckoutTimeout(t *testing.T) {
+	ref := startPool(t, "timeout-pool", 1, 0)
+	defer ref.Stop()
+
+	worker, err := ref.Checkout(nil, true, time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ref.Checkout(nil, false, 0); err != ErrFull {
+		t.Fatalf("expected ErrFull, got %v", err)
+	}
+	start := time.Now()
+	if _, err := ref.Checkout(nil, true, 50*time.Millisecond); err != ErrTimeout {
+		t.Fatalf("expected ErrTimeout, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed < 50*time.Millisecond {
+		t.Fatalf("the checkout gave up after %v", elapsed)
+	}
+	waitForStatus(t, ref, Status{Busy: 1})
+
+	ref.Checkin(worker)
+	again, err := ref.Checkout(nil, true, time.Second)
+	if err != nil {
+		t.Fatalf("could not check the worker out again: %v", err)
+	}
+	if pid.ExtractPID(again) != pid.ExtractPID(worker) {
+		t.Fatal("checked out another worker")
+	}
+}
+
+func TestOverflow(t *testing.T) {
+	ref := startPool(t, "overflow-pool", 1, 1)
+	defer ref.Stop()
+
+	first, err := ref.Checkout(nil, false, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	overflow, err := ref.Checkout(nil, false, 0)
+	if err != nil {
+		t.Fatalf("could not check out an overflow worker: %v", err)
+	}
+	waitForStatus(t, ref, Status{Busy: 2, Overflow: 1})
+	if _, err := ref.Checkout(nil, false, 0); err != ErrFull {
+		t.Fatalf("expected ErrFull past the overflow, got %v", err)
+	}
+
+	// the overflow worker is dismissed on checkin, the long-lived one goes back to the pool
+	ref.Checkin(overflow)
+	waitForStatus(t, ref, Status{Busy: 1})
+	ref.Checkin(first)
+	waitForStatus(t, ref, Status{Idle: 1})
+}
+
+func TestCheckinOnOwnerExit(t *testing.T) {
+	ref := startPool(t, "owner-pool", 1, 0)
+	defer ref.Stop()
+	owner := actor.Spawn(func(a *actor.Actor) {
+		a.Receive(func(message interface{}) (loop bool) {
+			return false
+		})
+	})
+
+	if _, err := ref.Checkout(owner, true, time.Second); err != nil {
+		t.Fatal(err)
+	}
+	waitForStatus(t, ref, Status{Busy: 1})
+
+	// a caller waiting for the worker gets it once the owner terminates
+	checkedOut := make(chan error, 1)
+	go func() {
+		_, err := ref.Checkout(nil, true, time.Second)
+		checkedOut <- err
+	}()
+	waitForStatus(t, ref, Status{Busy: 1, Waiting: 1})
+	actor.Send(owner, "stop")
+	if err := <-checkedOut; err != nil {
+		t.Fatalf("the worker of the terminated owner was not checked in: %v", err)
+	}
+	waitForStatus(t, ref, Status{Busy: 1})
+}
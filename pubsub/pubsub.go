@@ -0,0 +1,114 @@
+package pubsub
+
+import (
+	"github.com/hedisam/goactor/actor"
+	"github.com/hedisam/goactor/internal/pid"
+	"github.com/hedisam/goactor/node"
+	"runtime"
+	"sort"
+)
+
+// published carries a message published on another node to our subscribers
+type published struct {
+	Topic   string
+	Message interface{}
+}
+
+func init() {
+	node.RegisterMessage(published{})
+}
+
+// PubSub delivers the messages published on a topic to its subscribers on every connected node.
+// subscriptions are kept in a duplicate keys registry partitioned by topic, so publishing only locks the
+// partition of its topic. subscribers are removed automatically when they terminate.
+type PubSub struct {
+	node          *node.Node
+	subscriptions *actor.Registry
+	server        *pid.ProtectedPID
+}
+
+// Start starts the pubsub of the node. a nil node starts a local only pubsub.
+// messages published to other nodes must be registered with node.RegisterMessage.
+func Start(n *node.Node) (*PubSub, error) {
+	ps := &PubSub{
+		node:          n,
+		subscriptions: actor.NewRegistry(actor.DuplicateKeys, runtime.NumCPU()),
+	}
+	if n == nil {
+		return ps, nil
+	}
+	ps.server = actor.Spawn(server, ps)
+	if err := actor.Register(serverName(n.Name()), ps.server); err != nil {
+		actor.Send(ps.server, cmdStop{})
+		ps.subscriptions.Close()
+		return nil, err
+	}
+	return ps, nil
+}
+
+// Subscribe makes the actor receive the messages published on the topic. subscribing more than once has no effect.
+func (ps *PubSub) Subscribe(topic string, ppid *pid.ProtectedPID) {
+	_ = ps.subscriptions.Register(topic, ppid, nil)
+}
+
+// Unsubscribe stops sending the messages published on the topic to the actor
+func (ps *PubSub) Unsubscribe(topic string, ppid *pid.ProtectedPID) {
+	ps.subscriptions.Unregister(topic, ppid)
+}
+
+// Publish sends the message to the subscribers of the topic on every connected node
+func (ps *PubSub) Publish(topic string, message interface{}) {
+	ps.PublishLocal(topic, message)
+	if ps.node == nil {
+		return
+	}
+	for _, peer := range ps.node.Nodes() {
+		_ = ps.node.SendNamed(peer, serverName(peer), published{Topic: topic, Message: message})
+	}
+}
+
+// PublishLocal sends the message to the subscribers of the topic on the local node only
+func (ps *PubSub) PublishLocal(topic string, message interface{}) {
+	ps.subscriptions.Dispatch(topic, func(entries []actor.RegistryEntry) {
+		for _, e := range entries {
+			actor.Send(e.PID, message)
+		}
+	})
+}
+
+// Subscribers returns the local subscribers of the topic
+func (ps *PubSub) Subscribers(topic string) []*pid.ProtectedPID {
+	entries := ps.subscriptions.Lookup(topic)
+	subscribers := make([]*pid.ProtectedPID, 0, len(entries))
+	for _, e := range entries {
+		subscribers = append(subscribers, e.PID)
+	}
+	return subscribers
+}
+
+// Topics returns the topics with local subscribers, sorted
+func (ps *PubSub) Topics() []string {
+	seen := make(map[string]struct{})
+	for _, e := range ps.subscriptions.Select(func(actor.RegistryEntry) bool { return true }) {
+		seen[e.Key] = struct{}{}
+	}
+	topics := make([]string, 0, len(seen))
+	for topic := range seen {
+		topics = append(topics, topic)
+	}
+	sort.Strings(topics)
+	return topics
+}
+
+// Stop stops receiving the messages published on other nodes and watching the subscribers
+func (ps *PubSub) Stop() {
+	if ps.server != nil {
+		actor.Unregister(serverName(ps.node.Name()))
+		actor.Send(ps.server, cmdStop{})
+	}
+	ps.subscriptions.Close()
+}
+
+func serverName(node string) string {
+	return "goactor.pubsub@" + node
+}
@@ -0,0 +1,25 @@
+package pubsub
+
+import (
+	"github.com/hedisam/goactor/actor"
+	"log"
+)
+
+type cmdStop struct{}
+
+// server delivers the messages published on the other nodes to the local subscribers
+func server(a *actor.Actor) {
+	ps := a.Args()[0].(*PubSub)
+
+	a.Receive(func(message interface{}) (loop bool) {
+		switch msg := message.(type) {
+		case published:
+			ps.PublishLocal(msg.Topic, msg.Message)
+		case cmdStop:
+			return false
+		default:
+			log.Printf("pubsub: server received unknown message: %+v\n", message)
+		}
+		return true
+	})
+}
@@ -0,0 +1,171 @@
+package raft
+
+import (
+	"bufio"
+	"fmt"
+	"github.com/hedisam/goactor/internal/persist"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+const (
+	stateFile    = "state"
+	snapshotFile = "snapshot"
+	logFile      = "log"
+)
+
+// FileStore persists the state of a member in a directory. entries are appended to a log file, synced before
+// returning, which is rewritten when it's truncated or compacted by a snapshot. the state and the snapshots are
+// replaced atomically. values are encoded with codec.Gob, so the commands must be registered with codec.Register.
+type FileStore struct {
+	dir string
+
+	mu       sync.Mutex
+	log      *os.File
+	entries  []Entry
+	snapshot *Snapshot
+}
+
+// NewFileStore opens the store in the directory, creating it if it doesn't exist
+func NewFileStore(dir string) (*FileStore, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	s := &FileStore{dir: dir}
+	var err error
+	if s.snapshot, err = s.readSnapshot(); err != nil {
+		return nil, err
+	}
+	if s.entries, err = s.readLog(); err != nil {
+		return nil, err
+	}
+	// rewriting the log drops an incomplete record at its end
+	if err := s.rewriteLog(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+func (s *FileStore) LoadState() (HardState, error) {
+	value, err := persist.ReadValue(filepath.Join(s.dir, stateFile))
+	if err != nil || value == nil {
+		return HardState{}, err
+	}
+	state, ok := value.(HardState)
+	if !ok {
+		return HardState{}, fmt.Errorf("raft: invalid state file")
+	}
+	return state, nil
+}
+
+func (s *FileStore) SaveState(state HardState) error {
+	return persist.WriteValue(filepath.Join(s.dir, stateFile), state)
+}
+
+func (s *FileStore) Entries() ([]Entry, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]Entry(nil), s.entries...), nil
+}
+
+func (s *FileStore) Append(entries []Entry) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	w := bufio.NewWriter(s.log)
+	for _, e := range entries {
+		if err := persist.WriteRecord(w, e); err != nil {
+			return err
+		}
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+	if err := s.log.Sync(); err != nil {
+		return err
+	}
+	s.entries = append(s.entries, entries...)
+	return nil
+}
+
+func (s *FileStore) TruncateFrom(index uint64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.entries = truncateFrom(s.entries, index)
+	return s.rewriteLog()
+}
+
+func (s *FileStore) LoadSnapshot() (*Snapshot, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.snapshot == nil {
+		return nil, nil
+	}
+	snapshot := *s.snapshot
+	return &snapshot, nil
+}
+
+func (s *FileStore) SaveSnapshot(snapshot Snapshot) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := persist.WriteValue(filepath.Join(s.dir, snapshotFile), snapshot); err != nil {
+		return err
+	}
+	s.snapshot = &snapshot
+	s.entries = compact(s.entries, snapshot.Index)
+	return s.rewriteLog()
+}
+
+// Close closes the log file
+func (s *FileStore) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.log.Close()
+}
+
+func (s *FileStore) readSnapshot() (*Snapshot, error) {
+	value, err := persist.ReadValue(filepath.Join(s.dir, snapshotFile))
+	if err != nil || value == nil {
+		return nil, err
+	}
+	snapshot, ok := value.(Snapshot)
+	if !ok {
+		return nil, fmt.Errorf("raft: invalid snapshot file")
+	}
+	return &snapshot, nil
+}
+
+func (s *FileStore) readLog() ([]Entry, error) {
+	var entries []Entry
+	_, err := persist.Scan(filepath.Join(s.dir, logFile), func(value interface{}) error {
+		e, ok := value.(Entry)
+		if !ok {
+			return fmt.Errorf("raft: invalid log record")
+		}
+		entries = append(entries, e)
+		return nil
+	})
+	return entries, err
+}
+
+// rewriteLog writes the entries to a new log file which replaces the current one, it must be called with the
+// lock held
+func (s *FileStore) rewriteLog() error {
+	f, err := persist.Rewrite(filepath.Join(s.dir, logFile), func(w io.Writer) error {
+		for _, e := range s.entries {
+			if err := persist.WriteRecord(w, e); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if s.log != nil {
+		_ = s.log.Close()
+	}
+	s.log = f
+	return nil
+}
@@ -0,0 +1,775 @@
+package raft
+
+import (
+	"github.com/hedisam/goactor/actor"
+	"github.com/hedisam/goactor/internal/pid"
+	"log"
+	"math/rand"
+	"sort"
+	"time"
+)
+
+const (
+	// maxBatch is the maximum number of entries sent in one appendEntries
+	maxBatch = 128
+	// maxHops bounds the forwarding of a call while the members disagree on the leader
+	maxHops = 3
+)
+
+type pendingCall struct {
+	term    uint64
+	replyTo *pid.ProtectedPID
+}
+
+type memberState struct {
+	m      *Member
+	config Config
+	rand   *rand.Rand
+
+	role     Role
+	term     uint64
+	votedFor string
+	leader   string
+	// log holds the entries following the snapshot
+	log         []Entry
+	snapIndex   uint64
+	snapTerm    uint64
+	snapMembers []string
+	// members is the latest configuration in the log, committed or not
+	members     []string
+	commitIndex uint64
+	lastApplied uint64
+
+	votes      map[string]bool
+	nextIndex  map[string]uint64
+	matchIndex map[string]uint64
+	lastAck    map[string]time.Time
+	pending    map[uint64]pendingCall
+
+	electionDeadline time.Time
+	leaderContact    time.Time
+	lastHeartbeat    time.Time
+	// failed is set when the store fails, the member stops since it can't keep its promises anymore
+	failed bool
+}
+
+// newMemberState restores the member from its store
+func newMemberState(m *Member) (*memberState, error) {
+	s := &memberState{
+		m:       m,
+		config:  m.config,
+		rand:    rand.New(rand.NewSource(time.Now().UnixNano())),
+		members: append([]string(nil), m.config.Peers...),
+		pending: make(map[uint64]pendingCall),
+	}
+	state, err := s.config.Store.LoadState()
+	if err != nil {
+		return nil, err
+	}
+	s.term, s.votedFor = state.Term, state.VotedFor
+
+	snapshot, err := s.config.Store.LoadSnapshot()
+	if err != nil {
+		return nil, err
+	}
+	if snapshot != nil {
+		if err := s.config.StateMachine.Restore(snapshot.Data); err != nil {
+			return nil, err
+		}
+		s.snapIndex, s.snapTerm = snapshot.Index, snapshot.Term
+		s.snapMembers = snapshot.Members
+		s.commitIndex, s.lastApplied = snapshot.Index, snapshot.Index
+	}
+	if s.log, err = s.config.Store.Entries(); err != nil {
+		return nil, err
+	}
+	if snapshot != nil || len(s.log) != 0 {
+		s.members = s.membersAt(s.lastIndex())
+	}
+	return s, nil
+}
+
+// member runs the raft algorithm. all the state is owned by the actor, timers are driven by a ticker.
+func member(a *actor.Actor) {
+	s := a.Args()[0].(*memberState)
+	s.resetElectionDeadline()
+	s.publish()
+
+	done := make(chan struct{})
+	defer close(done)
+	go tick(a.Self(), s.config.HeartbeatInterval/2, done)
+
+	a.Receive(func(message interface{}) (loop bool) {
+		switch msg := message.(type) {
+		case requestVote:
+			s.requestVote(msg)
+		case voteReply:
+			s.voteReply(msg)
+		case appendEntries:
+			s.appendEntries(msg)
+		case appendReply:
+			s.appendReply(msg)
+		case installSnapshot:
+			s.installSnapshot(msg)
+		case snapshotReply:
+			s.snapshotReply(msg)
+		case call:
+			s.call(msg)
+		case cmdTick:
+			s.tick()
+		case cmdStop:
+			s.failPending()
+			return false
+		default:
+			log.Printf("raft: member %s received unknown message: %+v\n", s.config.ID, message)
+		}
+		if s.failed {
+			s.failPending()
+			return false
+		}
+		s.publish()
+		return true
+	})
+}
+
+func (s *memberState) tick() {
+	now := time.Now()
+	if s.role != Leader {
+		if now.After(s.electionDeadline) {
+			s.startElection()
+		}
+		return
+	}
+	if now.Sub(s.lastHeartbeat) >= s.config.HeartbeatInterval {
+		s.broadcastAppend()
+	}
+	// a leader that hasn't heard from a majority for an election timeout is probably on the minority side of a
+	// partition, stepping down makes its callers look for the actual leader
+	active := func(id string) bool {
+		return id == s.config.ID || now.Sub(s.lastAck[id]) < s.config.ElectionTimeout
+	}
+	if !s.quorum(active) {
+		log.Printf("raft: leader %s lost contact with the majority, stepping down\n", s.config.ID)
+		s.becomeFollower(s.term, "")
+	}
+}
+
+func (s *memberState) startElection() {
+	s.resetElectionDeadline()
+	// a member that's not part of the group, yet or anymore, never campaigns
+	if !s.isMember(s.config.ID) {
+		return
+	}
+	s.term++
+	s.role = Candidate
+	s.votedFor = s.config.ID
+	s.leader = ""
+	s.persistState()
+	s.votes = map[string]bool{s.config.ID: true}
+	if s.quorum(func(id string) bool { return s.votes[id] }) {
+		s.becomeLeader()
+		return
+	}
+	request := requestVote{
+		Term:         s.term,
+		Candidate:    s.config.ID,
+		LastLogIndex: s.lastIndex(),
+		LastLogTerm:  s.lastTerm(),
+	}
+	for _, id := range s.peers() {
+		s.send(id, request)
+	}
+}
+
+func (s *memberState) requestVote(msg requestVote) {
+	// a member that hears from its leader ignores the candidates, so a member that's been partitioned or removed
+	// can't disrupt the group
+	if s.role == Leader || (s.leader != "" && time.Since(s.leaderContact) < s.config.ElectionTimeout) {
+		return
+	}
+	if msg.Term > s.term {
+		s.becomeFollower(msg.Term, "")
+	}
+	granted := false
+	upToDate := msg.LastLogTerm > s.lastTerm() ||
+		(msg.LastLogTerm == s.lastTerm() && msg.LastLogIndex >= s.lastIndex())
+	if msg.Term == s.term && (s.votedFor == "" || s.votedFor == msg.Candidate) && upToDate {
+		granted = true
+		if s.votedFor != msg.Candidate {
+			s.votedFor = msg.Candidate
+			s.persistState()
+		}
+		s.resetElectionDeadline()
+	}
+	s.send(msg.Candidate, voteReply{Term: s.term, From: s.config.ID, Granted: granted})
+}
+
+func (s *memberState) voteReply(msg voteReply) {
+	if msg.Term > s.term {
+		s.becomeFollower(msg.Term, "")
+		return
+	}
+	if s.role != Candidate || msg.Term != s.term || !msg.Granted {
+		return
+	}
+	s.votes[msg.From] = true
+	if s.quorum(func(id string) bool { return s.votes[id] }) {
+		s.becomeLeader()
+	}
+}
+
+func (s *memberState) becomeFollower(term uint64, leader string) {
+	if term > s.term {
+		s.term = term
+		s.votedFor = ""
+		s.persistState()
+	}
+	if s.role == Leader {
+		s.failPending()
+	}
+	s.role = Follower
+	s.leader = leader
+	s.votes = nil
+}
+
+func (s *memberState) becomeLeader() {
+	s.role = Leader
+	s.leader = s.config.ID
+	s.votes = nil
+	s.nextIndex = make(map[string]uint64)
+	s.matchIndex = make(map[string]uint64)
+	s.lastAck = make(map[string]time.Time)
+	for _, id := range s.peers() {
+		s.track(id)
+	}
+	// committing an entry of its own term commits the ones left by the previous leaders
+	s.appendLocal(Entry{Kind: EntryNoop})
+	s.broadcastAppend()
+	s.advanceCommit()
+}
+
+// track starts replicating to a member, from the end of the log backwards
+func (s *memberState) track(id string) {
+	s.nextIndex[id] = s.lastIndex() + 1
+	s.matchIndex[id] = 0
+	s.lastAck[id] = time.Now()
+}
+
+func (s *memberState) broadcastAppend() {
+	s.lastHeartbeat = time.Now()
+	for _, id := range s.peers() {
+		s.sendAppend(id)
+	}
+}
+
+// sendAppend sends the member the entries it's missing, or the snapshot if they've been compacted
+func (s *memberState) sendAppend(id string) {
+	next := s.nextIndex[id]
+	if next <= s.snapIndex {
+		snapshot, err := s.config.Store.LoadSnapshot()
+		if err != nil || snapshot == nil {
+			log.Printf("raft: member %s could not load its snapshot: %v\n", s.config.ID, err)
+			return
+		}
+		s.send(id, installSnapshot{Term: s.term, Leader: s.config.ID, Snapshot: *snapshot})
+		return
+	}
+	prevTerm, _ := s.termAt(next - 1)
+	s.send(id, appendEntries{
+		Term:      s.term,
+		Leader:    s.config.ID,
+		PrevIndex: next - 1,
+		PrevTerm:  prevTerm,
+		Entries:   s.entriesFrom(next),
+		Commit:    s.commitIndex,
+	})
+}
+
+func (s *memberState) appendEntries(msg appendEntries) {
+	if msg.Term < s.term {
+		s.send(msg.Leader, appendReply{Term: s.term, From: s.config.ID})
+		return
+	}
+	s.becomeFollower(msg.Term, msg.Leader)
+	if s.failed {
+		return
+	}
+	s.leaderContact = time.Now()
+	s.resetElectionDeadline()
+
+	if msg.PrevIndex > s.lastIndex() {
+		s.send(msg.Leader, appendReply{Term: s.term, From: s.config.ID, NextHint: s.lastIndex() + 1})
+		return
+	}
+	if msg.PrevIndex > s.snapIndex {
+		if term, _ := s.termAt(msg.PrevIndex); term != msg.PrevTerm {
+			// the leader retries from the first entry of the conflicting term rather than one entry at a time
+			hint := msg.PrevIndex
+			for hint > s.snapIndex+1 {
+				if previous, _ := s.termAt(hint - 1); previous != term {
+					break
+				}
+				hint--
+			}
+			s.send(msg.Leader, appendReply{Term: s.term, From: s.config.ID, NextHint: hint})
+			return
+		}
+	}
+
+	for i, e := range msg.Entries {
+		// the entries covered by the snapshot are committed, so they match
+		if e.Index <= s.snapIndex {
+			continue
+		}
+		if e.Index <= s.lastIndex() {
+			if term, _ := s.termAt(e.Index); term == e.Term {
+				continue
+			}
+			s.truncateFrom(e.Index)
+			if s.failed {
+				return
+			}
+		}
+		s.appendRaw(msg.Entries[i:])
+		break
+	}
+	// an entry that couldn't be stored mustn't be acknowledged, nor committed
+	if s.failed {
+		return
+	}
+
+	// the entries of the message are all in the log now, either appended or already there
+	match := min(msg.PrevIndex+uint64(len(msg.Entries)), s.lastIndex())
+	if commit := min(msg.Commit, match); commit > s.commitIndex {
+		s.commitIndex = commit
+		s.apply()
+	}
+	s.send(msg.Leader, appendReply{Term: s.term, From: s.config.ID, Success: true, Match: match})
+}
+
+func (s *memberState) appendReply(msg appendReply) {
+	if msg.Term > s.term {
+		s.becomeFollower(msg.Term, "")
+		return
+	}
+	if _, ok := s.nextIndex[msg.From]; !ok || s.role != Leader || msg.Term != s.term {
+		return
+	}
+	s.lastAck[msg.From] = time.Now()
+	if msg.Success {
+		s.matched(msg.From, msg.Match)
+		return
+	}
+	next := msg.NextHint
+	if next == 0 || next >= s.nextIndex[msg.From] {
+		next = s.nextIndex[msg.From] - 1
+	}
+	if next < 1 {
+		next = 1
+	}
+	s.nextIndex[msg.From] = next
+	s.sendAppend(msg.From)
+}
+
+// matched records the member's log matches ours up to the index
+func (s *memberState) matched(id string, index uint64) {
+	if index > s.matchIndex[id] {
+		s.matchIndex[id] = index
+	}
+	if index+1 > s.nextIndex[id] {
+		s.nextIndex[id] = index + 1
+	}
+	s.advanceCommit()
+	if s.role == Leader && s.nextIndex[id] <= s.lastIndex() {
+		s.sendAppend(id)
+	}
+}
+
+func (s *memberState) installSnapshot(msg installSnapshot) {
+	if msg.Term < s.term {
+		s.send(msg.Leader, snapshotReply{Term: s.term, From: s.config.ID})
+		return
+	}
+	s.becomeFollower(msg.Term, msg.Leader)
+	if s.failed {
+		return
+	}
+	s.leaderContact = time.Now()
+	s.resetElectionDeadline()
+
+	snapshot := msg.Snapshot
+	if snapshot.Index > s.commitIndex {
+		if err := s.config.StateMachine.Restore(snapshot.Data); err != nil {
+			log.Printf("raft: member %s could not restore the snapshot: %v\n", s.config.ID, err)
+			return
+		}
+		if s.storeFailed(s.config.Store.SaveSnapshot(snapshot)) {
+			return
+		}
+		// the entries following the snapshot are kept if they agree with it
+		if term, ok := s.termAt(snapshot.Index); ok && term == snapshot.Term {
+			s.log = compact(s.log, snapshot.Index)
+		} else {
+			if s.storeFailed(s.config.Store.TruncateFrom(snapshot.Index + 1)) {
+				return
+			}
+			s.log = nil
+		}
+		s.snapIndex, s.snapTerm, s.snapMembers = snapshot.Index, snapshot.Term, snapshot.Members
+		s.commitIndex, s.lastApplied = snapshot.Index, snapshot.Index
+		s.members = s.membersAt(s.lastIndex())
+	}
+	s.send(msg.Leader, snapshotReply{Term: s.term, From: s.config.ID, Index: snapshot.Index})
+}
+
+func (s *memberState) snapshotReply(msg snapshotReply) {
+	if msg.Term > s.term {
+		s.becomeFollower(msg.Term, "")
+		return
+	}
+	if _, ok := s.nextIndex[msg.From]; !ok || s.role != Leader || msg.Term != s.term {
+		return
+	}
+	s.lastAck[msg.From] = time.Now()
+	s.matched(msg.From, msg.Index)
+}
+
+func (s *memberState) call(msg call) {
+	if s.role != Leader {
+		// a leader we haven't heard from for a while is likely gone, the call is retried once there's a new one
+		recent := time.Since(s.leaderContact) < s.config.ElectionTimeout
+		if s.leader != "" && s.leader != s.config.ID && recent && msg.Hops < maxHops {
+			msg.Hops++
+			s.send(s.leader, msg)
+			return
+		}
+		s.reply(msg.ReplyTo, callResult{Err: errNoLeader})
+		return
+	}
+
+	entry := Entry{Kind: EntryCommand, Command: msg.Command}
+	if change, ok := msg.Command.(membershipChange); ok {
+		// one change at a time, and only once the leader has committed an entry of its term, so two
+		// configurations can never both have a majority
+		if term, _ := s.termAt(s.commitIndex); term != s.term {
+			s.reply(msg.ReplyTo, callResult{Err: errNoLeader})
+			return
+		}
+		if s.configPending() {
+			s.reply(msg.ReplyTo, callResult{Err: "raft: a membership change is in progress"})
+			return
+		}
+		entry = Entry{Kind: EntryConfig, Members: changeMembers(s.members, change)}
+	}
+	index := s.appendLocal(entry)
+	s.pending[index] = pendingCall{term: s.term, replyTo: msg.ReplyTo}
+	s.broadcastAppend()
+	s.advanceCommit()
+}
+
+// advanceCommit commits the latest entry of the current term replicated on a majority, and everything before it
+func (s *memberState) advanceCommit() {
+	if s.role != Leader {
+		return
+	}
+	for index := s.lastIndex(); index > s.commitIndex; index-- {
+		if term, _ := s.termAt(index); term != s.term {
+			return
+		}
+		replicated := func(id string) bool {
+			return id == s.config.ID || s.matchIndex[id] >= index
+		}
+		if s.quorum(replicated) {
+			s.commitIndex = index
+			s.apply()
+			return
+		}
+	}
+}
+
+// apply applies the committed entries to the state machine and answers their calls
+func (s *memberState) apply() {
+	removed := false
+	for s.lastApplied < s.commitIndex {
+		s.lastApplied++
+		e := s.entryAt(s.lastApplied)
+		var result interface{}
+		switch e.Kind {
+		case EntryCommand:
+			result = s.config.StateMachine.Apply(e.Command)
+		case EntryConfig:
+			if s.role == Leader && !contains(e.Members, s.config.ID) {
+				removed = true
+			}
+		}
+		if p, ok := s.pending[e.Index]; ok {
+			delete(s.pending, e.Index)
+			if p.term == e.Term {
+				s.reply(p.replyTo, callResult{Result: result})
+			} else {
+				s.reply(p.replyTo, callResult{Err: ErrLeadershipLost.Error()})
+			}
+		}
+	}
+	s.snapshot()
+	if removed {
+		// the leader has replicated its removal, the rest of the group elects a new leader
+		s.becomeFollower(s.term, "")
+	}
+}
+
+// snapshot compacts the log once enough entries have been applied
+func (s *memberState) snapshot() {
+	if s.lastApplied-s.snapIndex < s.config.SnapshotThreshold {
+		return
+	}
+	data, err := s.config.StateMachine.Snapshot()
+	if err != nil {
+		log.Printf("raft: member %s could not take a snapshot: %v\n", s.config.ID, err)
+		return
+	}
+	term, _ := s.termAt(s.lastApplied)
+	snapshot := Snapshot{
+		Index:   s.lastApplied,
+		Term:    term,
+		Members: s.membersAt(s.lastApplied),
+		Data:    data,
+	}
+	if s.storeFailed(s.config.Store.SaveSnapshot(snapshot)) {
+		return
+	}
+	s.log = compact(s.log, snapshot.Index)
+	s.snapIndex, s.snapTerm, s.snapMembers = snapshot.Index, snapshot.Term, snapshot.Members
+}
+
+// appendLocal appends an entry of the current term to the leader's log and returns its index
+func (s *memberState) appendLocal(e Entry) uint64 {
+	e.Index = s.lastIndex() + 1
+	e.Term = s.term
+	s.appendRaw([]Entry{e})
+	return e.Index
+}
+
+func (s *memberState) appendRaw(entries []Entry) {
+	entries = append([]Entry(nil), entries...)
+	if s.storeFailed(s.config.Store.Append(entries)) {
+		return
+	}
+	s.log = append(s.log, entries...)
+	for _, e := range entries {
+		if e.Kind == EntryConfig {
+			s.setMembers(e.Members)
+		}
+	}
+}
+
+func (s *memberState) truncateFrom(index uint64) {
+	if s.storeFailed(s.config.Store.TruncateFrom(index)) {
+		return
+	}
+	s.log = truncateFrom(s.log, index)
+	s.setMembers(s.membersAt(s.lastIndex()))
+}
+
+// setMembers switches to a configuration, a leader starts or stops replicating to the members
+func (s *memberState) setMembers(members []string) {
+	s.members = members
+	if s.role != Leader {
+		return
+	}
+	for _, id := range s.peers() {
+		if _, ok := s.nextIndex[id]; !ok {
+			s.track(id)
+		}
+	}
+	for id := range s.nextIndex {
+		if !s.isMember(id) {
+			delete(s.nextIndex, id)
+			delete(s.matchIndex, id)
+			delete(s.lastAck, id)
+		}
+	}
+}
+
+func (s *memberState) configPending() bool {
+	for _, e := range s.log {
+		if e.Kind == EntryConfig && e.Index > s.commitIndex {
+			return true
+		}
+	}
+	return false
+}
+
+// membersAt returns the configuration as of the index
+func (s *memberState) membersAt(index uint64) []string {
+	for i := len(s.log) - 1; i >= 0; i-- {
+		if s.log[i].Index <= index && s.log[i].Kind == EntryConfig {
+			return s.log[i].Members
+		}
+	}
+	if s.snapMembers != nil {
+		return s.snapMembers
+	}
+	return s.config.Peers
+}
+
+func (s *memberState) lastIndex() uint64 {
+	if len(s.log) == 0 {
+		return s.snapIndex
+	}
+	return s.log[len(s.log)-1].Index
+}
+
+func (s *memberState) lastTerm() uint64 {
+	if len(s.log) == 0 {
+		return s.snapTerm
+	}
+	return s.log[len(s.log)-1].Term
+}
+
+// termAt returns the term of the entry at the index, false if it's been compacted or doesn't exist
+func (s *memberState) termAt(index uint64) (uint64, bool) {
+	switch {
+	case index == s.snapIndex:
+		return s.snapTerm, true
+	case index < s.snapIndex || index > s.lastIndex():
+		return 0, false
+	default:
+		return s.entryAt(index).Term, true
+	}
+}
+
+func (s *memberState) entryAt(index uint64) Entry {
+	return s.log[index-s.snapIndex-1]
+}
+
+func (s *memberState) entriesFrom(index uint64) []Entry {
+	if index > s.lastIndex() {
+		return nil
+	}
+	start := index - s.snapIndex - 1
+	end := min(uint64(len(s.log)), start+maxBatch)
+	return append([]Entry(nil), s.log[start:end]...)
+}
+
+// quorum reports whether a majority of the members satisfy the condition
+func (s *memberState) quorum(condition func(id string) bool) bool {
+	count := 0
+	for _, id := range s.members {
+		if condition(id) {
+			count++
+		}
+	}
+	return len(s.members) > 0 && count > len(s.members)/2
+}
+
+func (s *memberState) isMember(id string) bool {
+	return contains(s.members, id)
+}
+
+// peers returns the other members
+func (s *memberState) peers() []string {
+	peers := make([]string, 0, len(s.members))
+	for _, id := range s.members {
+		if id != s.config.ID {
+			peers = append(peers, id)
+		}
+	}
+	return peers
+}
+
+func (s *memberState) resetElectionDeadline() {
+	timeout := s.config.ElectionTimeout + time.Duration(s.rand.Int63n(int64(s.config.ElectionTimeout)))
+	s.electionDeadline = time.Now().Add(timeout)
+}
+
+func (s *memberState) persistState() {
+	s.storeFailed(s.config.Store.SaveState(HardState{Term: s.term, VotedFor: s.votedFor}))
+}
+
+// storeFailed reports whether the store operation has failed, which stops the member
+func (s *memberState) storeFailed(err error) bool {
+	if err == nil {
+		return false
+	}
+	log.Printf("raft: member %s store failed: %v\n", s.config.ID, err)
+	s.failed = true
+	return true
+}
+
+func (s *memberState) failPending() {
+	for index, p := range s.pending {
+		s.reply(p.replyTo, callResult{Err: ErrLeadershipLost.Error()})
+		delete(s.pending, index)
+	}
+}
+
+func (s *memberState) send(to string, message interface{}) {
+	s.config.Transport.Send(to, message)
+}
+
+func (s *memberState) reply(replyTo *pid.ProtectedPID, result callResult) {
+	if replyTo != nil {
+		actor.Send(replyTo, result)
+	}
+}
+
+func (s *memberState) publish() {
+	s.m.setStatus(Status{
+		ID:          s.config.ID,
+		Role:        s.role,
+		Term:        s.term,
+		Leader:      s.leader,
+		Members:     s.members,
+		CommitIndex: s.commitIndex,
+		LastApplied: s.lastApplied,
+	})
+}
+
+// changeMembers returns the members after the change, sorted
+func changeMembers(members []string, change membershipChange) []string {
+	changed := make([]string, 0, len(members)+1)
+	for _, id := range members {
+		if id != change.ID {
+			changed = append(changed, id)
+		}
+	}
+	if !change.Remove {
+		changed = append(changed, change.ID)
+	}
+	sort.Strings(changed)
+	return changed
+}
+
+func contains(ids []string, id string) bool {
+	for _, i := range ids {
+		if i == id {
+			return true
+		}
+	}
+	return false
+}
+
+func min(a, b uint64) uint64 {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+// tick drives the election and heartbeat timers of the member
+func tick(self *pid.ProtectedPID, interval time.Duration, done chan struct{}) {
+	if interval <= 0 {
+		interval = time.Millisecond
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			actor.Send(self, cmdTick{})
+		case <-done:
+			return
+		}
+	}
+}
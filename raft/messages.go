@@ -0,0 +1,111 @@
+package raft
+
+import (
+	"github.com/hedisam/goactor/internal/pid"
+)
+
+const (
+	EntryCommand EntryKind = iota
+	// EntryConfig carries the members of the group. a member uses the latest one in its log, committed or not.
+	EntryConfig
+	// EntryNoop is appended by a new leader to commit the entries of the previous terms
+	EntryNoop
+)
+
+// errNoLeader is replied to a call made while there's no leader, Call retries until there's one
+const errNoLeader = "raft: no leader"
+
+type EntryKind int32
+
+// Entry is an entry of the replicated log
+type Entry struct {
+	Index   uint64
+	Term    uint64
+	Kind    EntryKind
+	Command interface{}
+	Members []string
+}
+
+// HardState is the state a member must persist before answering any request
+type HardState struct {
+	Term     uint64
+	VotedFor string
+}
+
+// Snapshot is the state machine as of an index of the log, along with the members as of that index
+type Snapshot struct {
+	Index   uint64
+	Term    uint64
+	Members []string
+	Data    []byte
+}
+
+// messages exchanged by the members
+
+type requestVote struct {
+	Term         uint64
+	Candidate    string
+	LastLogIndex uint64
+	LastLogTerm  uint64
+}
+
+type voteReply struct {
+	Term    uint64
+	From    string
+	Granted bool
+}
+
+type appendEntries struct {
+	Term      uint64
+	Leader    string
+	PrevIndex uint64
+	PrevTerm  uint64
+	Entries   []Entry
+	Commit    uint64
+}
+
+type appendReply struct {
+	Term    uint64
+	From    string
+	Success bool
+	// Match is the last index known to match the leader's log on success, NextHint where to retry from on failure
+	Match    uint64
+	NextHint uint64
+}
+
+type installSnapshot struct {
+	Term     uint64
+	Leader   string
+	Snapshot Snapshot
+}
+
+type snapshotReply struct {
+	Term  uint64
+	From  string
+	Index uint64
+}
+
+// call is a command sent to the group by a client, it's forwarded to the leader
+type call struct {
+	Command interface{}
+	ReplyTo *pid.ProtectedPID
+	// Hops counts the forwards, so a call doesn't bounce between members that disagree on the leader
+	Hops int
+}
+
+type callResult struct {
+	Result interface{}
+	Err    string
+}
+
+// membershipChange is the command adding or removing a member
+type membershipChange struct {
+	ID     string
+	Remove bool
+}
+
+// local commands
+
+type cmdTick struct{}
+
+type cmdStop struct{}
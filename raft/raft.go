@@ -0,0 +1,238 @@
+package raft
+
+import (
+	"errors"
+	"fmt"
+	"github.com/hedisam/goactor/actor"
+	"github.com/hedisam/goactor/codec"
+	"github.com/hedisam/goactor/internal/pid"
+	"github.com/hedisam/goactor/node"
+	"sync"
+	"time"
+)
+
+const (
+	Follower Role = iota
+	Candidate
+	Leader
+)
+
+const (
+	defaultElectionTimeout   = 300 * time.Millisecond
+	defaultHeartbeatInterval = 50 * time.Millisecond
+	defaultSnapshotThreshold = 1000
+)
+
+var (
+	// ErrTimeout is returned by a call that hasn't been answered in time. the command may still be applied.
+	ErrTimeout = errors.New("raft: call timed out")
+	// ErrLeadershipLost is returned by a call whose leader stepped down before committing the command.
+	// the command may or may not be applied by the next leader.
+	ErrLeadershipLost = errors.New("raft: leadership lost")
+)
+
+type Role int32
+
+func (r Role) String() string {
+	switch r {
+	case Follower:
+		return "follower"
+	case Candidate:
+		return "candidate"
+	case Leader:
+		return "leader"
+	default:
+		return fmt.Sprintf("role(%d)", int32(r))
+	}
+}
+
+// StateMachine is the user defined state replicated by the group. every member applies the same commands in the
+// same order to its own instance.
+type StateMachine interface {
+	// Apply applies a committed command, the result is returned to the caller if the command went through this member
+	Apply(command interface{}) interface{}
+	// Snapshot serializes the state, it's taken every SnapshotThreshold applied commands to compact the log
+	Snapshot() ([]byte, error)
+	// Restore replaces the state with a snapshot
+	Restore(data []byte) error
+}
+
+// Config describes a member of a group
+type Config struct {
+	// Group names the group, its members are registered locally under it
+	Group string
+	// ID identifies the member in the group. it's the node name when the members talk through a NodeTransport.
+	ID string
+	// Peers are the initial members of the group, the member itself included. they're ignored if the store
+	// already holds a membership. a member joining an existing group starts with no peers and is added through
+	// AddMember, it doesn't campaign until it's part of the group.
+	Peers        []string
+	StateMachine StateMachine
+	Transport    Transport
+	// Store persists the term, the vote, the log and the snapshots, a MemoryStore by default
+	Store Store
+	// ElectionTimeout is the minimum time a follower waits for the leader before campaigning, randomized up to
+	// twice as much. 300ms by default.
+	ElectionTimeout time.Duration
+	// HeartbeatInterval is how often the leader replicates to its followers, 50ms by default
+	HeartbeatInterval time.Duration
+	// SnapshotThreshold is the number of applied entries that triggers a snapshot, 1000 by default
+	SnapshotThreshold uint64
+}
+
+// Status is a member as of its last processed message
+type Status struct {
+	ID          string
+	Role        Role
+	Term        uint64
+	Leader      string
+	Members     []string
+	CommitIndex uint64
+	LastApplied uint64
+}
+
+// Member is a member of a group replicating a state machine with the raft consensus algorithm
+type Member struct {
+	config Config
+	self   *pid.ProtectedPID
+
+	mu     sync.RWMutex
+	status Status
+}
+
+func init() {
+	codec.Register(Entry{})
+	codec.Register(HardState{})
+	codec.Register(Snapshot{})
+	node.RegisterMessage(requestVote{})
+	node.RegisterMessage(voteReply{})
+	node.RegisterMessage(appendEntries{})
+	node.RegisterMessage(appendReply{})
+	node.RegisterMessage(installSnapshot{})
+	node.RegisterMessage(snapshotReply{})
+	node.RegisterMessage(call{})
+	node.RegisterMessage(callResult{})
+	node.RegisterMessage(membershipChange{})
+}
+
+// Start starts the member. commands must be registered with codec.Register, or node.RegisterMessage, to be
+// persisted by a FileStore or replicated through a NodeTransport.
+func Start(config Config) (*Member, error) {
+	switch {
+	case config.Group == "":
+		return nil, fmt.Errorf("group name is required")
+	case config.ID == "":
+		return nil, fmt.Errorf("member id is required")
+	case config.StateMachine == nil:
+		return nil, fmt.Errorf("state machine is required")
+	case config.Transport == nil:
+		return nil, fmt.Errorf("transport is required")
+	}
+	if config.Store == nil {
+		config.Store = NewMemoryStore()
+	}
+	if config.ElectionTimeout <= 0 {
+		config.ElectionTimeout = defaultElectionTimeout
+	}
+	if config.HeartbeatInterval <= 0 {
+		config.HeartbeatInterval = defaultHeartbeatInterval
+	}
+	if config.SnapshotThreshold == 0 {
+		config.SnapshotThreshold = defaultSnapshotThreshold
+	}
+
+	m := &Member{config: config}
+	state, err := newMemberState(m)
+	if err != nil {
+		return nil, err
+	}
+	m.self = actor.Spawn(member, state)
+	if err := actor.Register(memberName(config.Group, config.ID), m.self); err != nil {
+		actor.Send(m.self, cmdStop{})
+		return nil, err
+	}
+	return m, nil
+}
+
+// Call applies the command to the state machine through the leader, wherever it is, and returns its result.
+// it waits for a leader to be elected until the timeout.
+func (m *Member) Call(command interface{}, timeout time.Duration) (interface{}, error) {
+	deadline := time.Now().Add(timeout)
+	for {
+		remaining := time.Until(deadline)
+		if remaining <= 0 {
+			return nil, ErrTimeout
+		}
+		future := actor.NewFutureActor()
+		actor.Send(m.self, call{Command: command, ReplyTo: future.Self()})
+		response, err := future.RecvWithTimeout(remaining)
+		if err != nil {
+			return nil, ErrTimeout
+		}
+		result, ok := response.(callResult)
+		if !ok {
+			return nil, fmt.Errorf("raft: unexpected response: %+v", response)
+		}
+		switch result.Err {
+		case "":
+			return result.Result, nil
+		case errNoLeader:
+			// nothing has been appended, it's safe to try again once a leader is elected
+			time.Sleep(m.config.HeartbeatInterval)
+		case ErrLeadershipLost.Error():
+			return nil, ErrLeadershipLost
+		default:
+			return nil, errors.New(result.Err)
+		}
+	}
+}
+
+// AddMember adds the member to the group. members are added one at a time, the change is rejected while another
+// one is in progress.
+func (m *Member) AddMember(id string, timeout time.Duration) error {
+	_, err := m.Call(membershipChange{ID: id}, timeout)
+	return err
+}
+
+// RemoveMember removes the member from the group. a leader removing itself steps down once the change is committed.
+func (m *Member) RemoveMember(id string, timeout time.Duration) error {
+	_, err := m.Call(membershipChange{ID: id, Remove: true}, timeout)
+	return err
+}
+
+// Status returns the status of the member
+func (m *Member) Status() Status {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	status := m.status
+	status.Members = append([]string(nil), status.Members...)
+	return status
+}
+
+// Leader returns the id of the leader as far as the member knows, empty if it doesn't know of any
+func (m *Member) Leader() string {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.status.Leader
+}
+
+// PID returns the pid of the member actor
+func (m *Member) PID() *pid.ProtectedPID {
+	return m.self
+}
+
+// Stop stops the member, its store is left as is so it can be started again
+func (m *Member) Stop() {
+	actor.Unregister(memberName(m.config.Group, m.config.ID))
+	actor.Send(m.self, cmdStop{})
+}
+
+func (m *Member) setStatus(status Status) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.status = status
+}
+
+func memberName(group, id string) string {
+	return "goactor.raft." + group + "." + id
+}
@@ -0,0 +1,325 @@
+package raft
+
+import (
+	"encoding/json"
+	"errors"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+)
+
+const (
+	testElectionTimeout   = 100 * time.Millisecond
+	testHeartbeatInterval = 20 * time.Millisecond
+	testCallTimeout       = 3 * time.Second
+)
+
+// counter is a state machine adding the commands to its value
+type counter struct {
+	mu    sync.Mutex
+	value int
+}
+
+func (c *counter) Apply(command interface{}) interface{} {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.value += command.(int)
+	return c.value
+}
+
+func (c *counter) Snapshot() ([]byte, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return json.Marshal(c.value)
+}
+
+func (c *counter) Restore(data []byte) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return json.Unmarshal(data, &c.value)
+}
+
+func (c *counter) get() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.value
+}
+
+// failingStore is a memory store whose writes fail once it's been broken
+type failingStore struct {
+	*MemoryStore
+	mu     sync.Mutex
+	broken bool
+}
+
+func (s *failingStore) breakWrites() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.broken = true
+}
+
+func (s *failingStore) err() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.broken {
+		return errors.New("disk failure")
+	}
+	return nil
+}
+
+func (s *failingStore) Append(entries []Entry) error {
+	if err := s.err(); err != nil {
+		return err
+	}
+	return s.MemoryStore.Append(entries)
+}
+
+func (s *failingStore) TruncateFrom(index uint64) error {
+	if err := s.err(); err != nil {
+		return err
+	}
+	return s.MemoryStore.TruncateFrom(index)
+}
+
+type testGroup struct {
+	t        *testing.T
+	network  *Network
+	ids      []string
+	members  map[string]*Member
+	machines map[string]*counter
+	stores   map[string]*failingStore
+}
+
+func startGroup(t *testing.T, group string, size int, snapshotThreshold uint64) *testGroup {
+	g := &testGroup{
+		t:        t,
+		network:  NewNetwork(),
+		members:  make(map[string]*Member),
+		machines: make(map[string]*counter),
+		stores:   make(map[string]*failingStore),
+	}
+	for i := 0; i < size; i++ {
+		g.ids = append(g.ids, "m"+strconv.Itoa(i))
+	}
+	for _, id := range g.ids {
+		g.machines[id] = &counter{}
+		g.stores[id] = &failingStore{MemoryStore: NewMemoryStore()}
+		m, err := Start(Config{
+			Group:             group,
+			ID:                id,
+			Peers:             g.ids,
+			StateMachine:      g.machines[id],
+			Transport:         g.network.Transport(group, id),
+			Store:             g.stores[id],
+			ElectionTimeout:   testElectionTimeout,
+			HeartbeatInterval: testHeartbeatInterval,
+			SnapshotThreshold: snapshotThreshold,
+		})
+		if err != nil {
+			t.Fatalf("could not start member %s: %v", id, err)
+		}
+		g.members[id] = m
+	}
+	return g
+}
+
+func (g *testGroup) stop() {
+	for _, m := range g.members {
+		m.Stop()
+	}
+}
+
+// leader waits for one of the members to be the leader of a majority, the others excluded
+func (g *testGroup) leader(excluded ...string) string {
+	g.t.Helper()
+	deadline := time.Now().Add(testCallTimeout)
+	for time.Now().Before(deadline) {
+		votes := make(map[string]int)
+		for _, id := range g.ids {
+			if contains(excluded, id) {
+				continue
+			}
+			status := g.members[id].Status()
+			if status.Leader != "" && !contains(excluded, status.Leader) {
+				votes[status.Leader]++
+			}
+		}
+		for leader, n := range votes {
+			if n > len(g.ids)/2 && g.members[leader].Status().Role == Leader {
+				return leader
+			}
+		}
+		time.Sleep(testHeartbeatInterval)
+	}
+	g.t.Fatal("no leader elected")
+	return ""
+}
+
+func (g *testGroup) followers(leader string) []string {
+	var followers []string
+	for _, id := range g.ids {
+		if id != leader {
+			followers = append(followers, id)
+		}
+	}
+	return followers
+}
+
+func (g *testGroup) add(through string, n int) {
+	g.t.Helper()
+	for i := 0; i < n; i++ {
+		if _, err := g.members[through].Call(1, testCallTimeout); err != nil {
+			g.t.Fatalf("call through %s failed: %v", through, err)
+		}
+	}
+}
+
+// converge waits for the members to have applied the value
+func (g *testGroup) converge(value int, ids ...string) {
+	g.t.Helper()
+	if len(ids) == 0 {
+		ids = g.ids
+	}
+	deadline := time.Now().Add(testCallTimeout)
+	for {
+		converged := true
+		for _, id := range ids {
+			if g.machines[id].get() != value {
+				converged = false
+			}
+		}
+		if converged {
+			return
+		}
+		if time.Now().After(deadline) {
+			for _, id := range ids {
+				g.t.Logf("member %s: value %d, status %+v", id, g.machines[id].get(), g.members[id].Status())
+			}
+			g.t.Fatalf("members did not converge to %d", value)
+		}
+		time.Sleep(testHeartbeatInterval)
+	}
+}
+
+func TestReplication(t *testing.T) {
+	g := startGroup(t, "test.replication", 3, 0)
+	defer g.stop()
+	leader := g.leader()
+	g.add(leader, 5)
+	// calls to followers are forwarded to the leader
+	g.add(g.followers(leader)[0], 5)
+	g.converge(10)
+}
+
+func TestLeaderLoss(t *testing.T) {
+	g := startGroup(t, "test.leader.loss", 3, 0)
+	defer g.stop()
+	old := g.leader()
+	g.add(old, 3)
+	g.converge(3)
+	term := g.members[old].Status().Term
+
+	g.network.Isolate(old)
+	leader := g.leader(old)
+	if leader == old {
+		t.Fatalf("isolated leader %s is still leading", old)
+	}
+	if status := g.members[leader].Status(); status.Term <= term {
+		t.Fatalf("new leader %s has term %d, not above %d", leader, status.Term, term)
+	}
+	g.add(leader, 3)
+	g.converge(6, g.followers(old)...)
+
+	// the old leader steps down and catches up once it's back
+	g.network.Heal()
+	g.converge(6)
+	if status := g.members[old].Status(); status.Role == Leader && status.Term <= term {
+		t.Fatalf("old leader %s did not step down: %+v", old, status)
+	}
+}
+
+func TestPartitionAndHeal(t *testing.T) {
+	g := startGroup(t, "test.partition", 5, 0)
+	defer g.stop()
+	leader := g.leader()
+	g.add(leader, 2)
+	g.converge(2)
+
+	// the leader ends up on the minority side
+	followers := g.followers(leader)
+	minority := []string{leader, followers[0]}
+	majority := followers[1:]
+	g.network.Partition(minority, majority)
+
+	if _, err := g.members[leader].Call(1, 5*testElectionTimeout); err == nil {
+		t.Fatal("the minority side committed a command")
+	}
+	newLeader := g.leader(minority...)
+	g.add(newLeader, 3)
+	g.converge(5, majority...)
+	for _, id := range minority {
+		if value := g.machines[id].get(); value != 2 {
+			t.Fatalf("member %s on the minority side applied %d", id, value)
+		}
+	}
+
+	// the uncommitted entry of the minority is replaced by the majority's log
+	g.network.Heal()
+	g.converge(5)
+	g.add(g.leader(), 1)
+	g.converge(6)
+}
+
+func TestSnapshotCatchUp(t *testing.T) {
+	g := startGroup(t, "test.snapshot", 3, 5)
+	defer g.stop()
+	leader := g.leader()
+	lagging := g.followers(leader)[0]
+	g.network.Isolate(lagging)
+
+	g.add(leader, 20)
+	g.converge(20, g.followers(lagging)...)
+	if status := g.members[leader].Status(); status.LastApplied < 20 {
+		t.Fatalf("leader applied %d entries only", status.LastApplied)
+	}
+	snapshot, err := g.stores[leader].LoadSnapshot()
+	if err != nil || snapshot == nil {
+		t.Fatalf("leader has no snapshot: %v", err)
+	}
+
+	// the entries the lagging member misses have been compacted, it gets the snapshot instead
+	g.network.Heal()
+	g.converge(20)
+}
+
+func TestFollowerStoreFailure(t *testing.T) {
+	g := startGroup(t, "test.store.failure", 3, 0)
+	defer g.stop()
+	leader := g.leader()
+	g.add(leader, 1)
+	g.converge(1)
+
+	followers := g.followers(leader)
+	failing, healthy := followers[0], followers[1]
+	commit := g.members[leader].Status().CommitIndex
+	g.stores[failing].breakWrites()
+	g.network.Isolate(healthy)
+
+	// the failing follower can't store the entry, so it must not count towards the majority
+	if _, err := g.members[leader].Call(1, 5*testElectionTimeout); err == nil {
+		t.Fatal("a command committed without being stored by a majority")
+	}
+	if status := g.members[leader].Status(); status.CommitIndex != commit {
+		t.Fatalf("leader committed up to %d, only %d was stored by a majority", status.CommitIndex, commit)
+	}
+	if value := g.machines[failing].get(); value != 1 {
+		t.Fatalf("failing follower applied %d", value)
+	}
+
+	// the group goes on without the failed member, the command that failed may still be applied
+	g.network.Heal()
+	leader = g.leader(failing)
+	g.add(leader, 1)
+	g.converge(3, leader, healthy)
+}
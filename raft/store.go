@@ -0,0 +1,105 @@
+package raft
+
+import (
+	"sync"
+)
+
+// Store persists the state of a member. entries are appended in order, right after the last entry or the
+// snapshot, and are only ever removed by TruncateFrom and SaveSnapshot.
+type Store interface {
+	LoadState() (HardState, error)
+	SaveState(state HardState) error
+	// Entries returns the entries following the snapshot
+	Entries() ([]Entry, error)
+	Append(entries []Entry) error
+	// TruncateFrom removes the entries from the index onwards
+	TruncateFrom(index uint64) error
+	// LoadSnapshot returns the latest snapshot, nil if there's none
+	LoadSnapshot() (*Snapshot, error)
+	// SaveSnapshot saves the snapshot and removes the entries it covers
+	SaveSnapshot(snapshot Snapshot) error
+}
+
+// MemoryStore keeps the state in memory, it's lost along with the process
+type MemoryStore struct {
+	mu       sync.Mutex
+	state    HardState
+	entries  []Entry
+	snapshot *Snapshot
+}
+
+// NewMemoryStore returns an empty memory store
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{}
+}
+
+func (s *MemoryStore) LoadState() (HardState, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.state, nil
+}
+
+func (s *MemoryStore) SaveState(state HardState) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.state = state
+	return nil
+}
+
+func (s *MemoryStore) Entries() ([]Entry, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]Entry(nil), s.entries...), nil
+}
+
+func (s *MemoryStore) Append(entries []Entry) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.entries = append(s.entries, entries...)
+	return nil
+}
+
+func (s *MemoryStore) TruncateFrom(index uint64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.entries = truncateFrom(s.entries, index)
+	return nil
+}
+
+func (s *MemoryStore) LoadSnapshot() (*Snapshot, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.snapshot == nil {
+		return nil, nil
+	}
+	snapshot := *s.snapshot
+	return &snapshot, nil
+}
+
+func (s *MemoryStore) SaveSnapshot(snapshot Snapshot) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.snapshot = &snapshot
+	s.entries = compact(s.entries, snapshot.Index)
+	return nil
+}
+
+// truncateFrom returns the entries before the index
+func truncateFrom(entries []Entry, index uint64) []Entry {
+	for i, e := range entries {
+		if e.Index >= index {
+			return entries[:i:i]
+		}
+	}
+	return entries
+}
+
+// compact returns the entries after the index
+func compact(entries []Entry, index uint64) []Entry {
+	for i, e := range entries {
+		if e.Index > index {
+			return append([]Entry(nil), entries[i:]...)
+		}
+	}
+	return nil
+}
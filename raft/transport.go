@@ -0,0 +1,147 @@
+package raft
+
+import (
+	"github.com/hedisam/goactor/actor"
+	"github.com/hedisam/goactor/node"
+	"math/rand"
+	"sync"
+	"time"
+)
+
+// Transport carries the messages of a member to the other members of its group
+type Transport interface {
+	Send(to string, message interface{})
+}
+
+// NodeTransport connects members running on different nodes, a member's id is the name of its node
+type NodeTransport struct {
+	node  *node.Node
+	group string
+}
+
+// NewNodeTransport returns the transport of the group's member on the node
+func NewNodeTransport(n *node.Node, group string) *NodeTransport {
+	return &NodeTransport{node: n, group: group}
+}
+
+func (t *NodeTransport) Send(to string, message interface{}) {
+	if to == t.node.Name() {
+		actor.SendNamed(memberName(t.group, to), message)
+		return
+	}
+	_ = t.node.SendNamed(to, memberName(t.group, to), message)
+}
+
+// Network is a simulated network connecting members running in the same process. it can drop, delay and
+// partition their messages, which makes a group testable without any real network.
+type Network struct {
+	mu       sync.Mutex
+	rand     *rand.Rand
+	dropRate float64
+	minDelay time.Duration
+	maxDelay time.Duration
+	// sides of the partition by member, members on different sides can't talk. members on no side talk to everyone.
+	sides    map[string]int
+	isolated map[string]bool
+}
+
+// NewNetwork returns a network delivering every message right away
+func NewNetwork() *Network {
+	return &Network{
+		rand:     rand.New(rand.NewSource(time.Now().UnixNano())),
+		sides:    make(map[string]int),
+		isolated: make(map[string]bool),
+	}
+}
+
+// Transport returns the transport of a member of the group
+func (nw *Network) Transport(group, id string) Transport {
+	return &networkTransport{network: nw, group: group, from: id}
+}
+
+// Drop makes the network drop messages with the given probability, between 0 and 1
+func (nw *Network) Drop(rate float64) {
+	nw.mu.Lock()
+	defer nw.mu.Unlock()
+	nw.dropRate = rate
+}
+
+// Delay makes the network delay messages by a random duration between min and max, which reorders them
+func (nw *Network) Delay(min, max time.Duration) {
+	nw.mu.Lock()
+	defer nw.mu.Unlock()
+	nw.minDelay, nw.maxDelay = min, max
+}
+
+// Partition splits the members into sides that can't talk to each other, replacing the current partition
+func (nw *Network) Partition(sides ...[]string) {
+	nw.mu.Lock()
+	defer nw.mu.Unlock()
+	nw.sides = make(map[string]int)
+	for i, side := range sides {
+		for _, id := range side {
+			nw.sides[id] = i
+		}
+	}
+}
+
+// Isolate cuts the member off the rest of the network
+func (nw *Network) Isolate(id string) {
+	nw.mu.Lock()
+	defer nw.mu.Unlock()
+	nw.isolated[id] = true
+}
+
+// Heal removes the partition and reconnects the isolated members. dropping and delaying are left as is.
+func (nw *Network) Heal() {
+	nw.mu.Lock()
+	defer nw.mu.Unlock()
+	nw.sides = make(map[string]int)
+	nw.isolated = make(map[string]bool)
+}
+
+// route decides whether a message gets through and how long it takes
+func (nw *Network) route(from, to string) (delay time.Duration, ok bool) {
+	nw.mu.Lock()
+	defer nw.mu.Unlock()
+	if from == to {
+		return 0, true
+	}
+	if nw.isolated[from] || nw.isolated[to] {
+		return 0, false
+	}
+	fromSide, fromOK := nw.sides[from]
+	toSide, toOK := nw.sides[to]
+	if fromOK && toOK && fromSide != toSide {
+		return 0, false
+	}
+	if nw.dropRate > 0 && nw.rand.Float64() < nw.dropRate {
+		return 0, false
+	}
+	delay = nw.minDelay
+	if nw.maxDelay > nw.minDelay {
+		delay += time.Duration(nw.rand.Int63n(int64(nw.maxDelay - nw.minDelay)))
+	}
+	return delay, true
+}
+
+type networkTransport struct {
+	network *Network
+	group   string
+	from    string
+}
+
+func (t *networkTransport) Send(to string, message interface{}) {
+	delay, ok := t.network.route(t.from, to)
+	if !ok {
+		return
+	}
+	name := memberName(t.group, to)
+	if delay == 0 {
+		actor.SendNamed(name, message)
+		return
+	}
+	time.AfterFunc(delay, func() {
+		actor.SendNamed(name, message)
+	})
+}
@@ -0,0 +1,245 @@
+package router
+
+import (
+	"errors"
+	"fmt"
+	"github.com/hedisam/goactor/actor"
+	"github.com/hedisam/goactor/codec"
+	"github.com/hedisam/goactor/internal/pid"
+	"github.com/hedisam/goactor/supervisor/spec"
+	"github.com/hedisam/goactor/sysmsg"
+	"log"
+	"time"
+)
+
+const (
+	// a pool router spawns, supervises and owns its routees
+	PoolRouter Type = iota
+	// a group router routes to existing actors
+	GroupRouter
+)
+
+const (
+	defaultCallTimeout = 5 * time.Second
+	defaultMaxRestarts = 3
+	defaultPeriod      = 5
+)
+
+type Type int32
+
+// Config describes a router. use NewPool or NewGroup to create one.
+type Config struct {
+	Type     Type
+	Strategy Strategy
+	// Size, Routee and Args are used by pool routers to spawn the routees
+	Size   int
+	Routee actor.Func
+	Args   []interface{}
+	// MaxRestarts is the number of crashed routees a pool router replaces within Period seconds. the router
+	// shuts its routees down and terminates once it's exceeded, like a supervisor.
+	MaxRestarts int
+	Period      int
+	// Routees are the initial routees of a group router
+	Routees []*pid.ProtectedPID
+}
+
+// Broadcast wraps a message that should be delivered to all routees regardless of the routing strategy
+type Broadcast struct {
+	Message interface{}
+}
+
+// NewPool returns the config of a router that spawns and supervises size routees running fn
+func NewPool(strategy Strategy, size int, fn actor.Func, args ...interface{}) Config {
+	return Config{
+		Type:        PoolRouter,
+		Strategy:    strategy,
+		Size:        size,
+		Routee:      fn,
+		Args:        args,
+		MaxRestarts: defaultMaxRestarts,
+		Period:      defaultPeriod,
+	}
+}
+
+// SetRestarts sets the number of crashed routees a pool router replaces within period seconds
+func (config Config) SetRestarts(maxRestarts, period int) Config {
+	config.MaxRestarts = maxRestarts
+	config.Period = period
+	return config
+}
+
+// NewGroup returns the config of a router that routes to the given actors
+func NewGroup(strategy Strategy, routees ...*pid.ProtectedPID) Config {
+	return Config{
+		Type:     GroupRouter,
+		Strategy: strategy,
+		Routees:  routees,
+	}
+}
+
+// Start spawns the router. messages sent to the router's pid are routed to its routees.
+func Start(config Config) (*Ref, error) {
+	if err := config.check(); err != nil {
+		return nil, err
+	}
+	return &Ref{PPID: actor.Spawn(router, &config)}, nil
+}
+
+// Spec returns a worker spec so the router can be started under a supervisor
+func Spec(id string, config Config) spec.WorkerSpec {
+	return spec.NewWorkerSpec(id, router, &config)
+}
+
+func (config *Config) check() error {
+	if config.Strategy == nil {
+		return fmt.Errorf("router strategy could not be nil")
+	}
+	switch config.Type {
+	case PoolRouter:
+		if config.Size < 0 {
+			return fmt.Errorf("invalid pool size: %d", config.Size)
+		} else if config.Routee == nil {
+			return fmt.Errorf("pool routee's fn (actor.Func(actor.Actor)) could not be nil")
+		} else if config.Period < 1 {
+			return fmt.Errorf("invalid restarts period: %d", config.Period)
+		} else if config.MaxRestarts < 0 {
+			return fmt.Errorf("invalid max restarts: %d", config.MaxRestarts)
+		}
+	case GroupRouter:
+	default:
+		return fmt.Errorf("invalid router type: %d", config.Type)
+	}
+	return nil
+}
+
+// Ref is used to manage a running router
+type Ref struct {
+	PPID *pid.ProtectedPID
+}
+
+// Routees returns the router's current routees
+func (r *Ref) Routees() ([]*pid.ProtectedPID, error) {
+	result, err := r.call(getRoutees{})
+	if err != nil {
+		return nil, err
+	}
+	routees, ok := result.([]*pid.ProtectedPID)
+	if !ok {
+		return nil, fmt.Errorf("invalid response sent by router: %v", result)
+	}
+	return routees, nil
+}
+
+// Resize grows or shrinks a pool router to the given size
+func (r *Ref) Resize(size int) error {
+	_, err := r.call(resize{Size: size})
+	return err
+}
+
+// AddRoutee adds an existing actor to a group router
+func (r *Ref) AddRoutee(routee *pid.ProtectedPID) error {
+	_, err := r.call(addRoutee{Routee: routee})
+	return err
+}
+
+// RemoveRoutee removes an actor from a group router
+func (r *Ref) RemoveRoutee(routee *pid.ProtectedPID) error {
+	_, err := r.call(removeRoutee{Routee: routee})
+	return err
+}
+
+// Stop stops the router. a pool router shuts its routees down.
+func (r *Ref) Stop() error {
+	_, err := r.call(stop{})
+	return err
+}
+
+// call sends the request to the router and waits for the reply. the router is monitored, so the call fails with
+// actor.ErrTargetDown as soon as it's terminated.
+func (r *Ref) call(request interface{}) (interface{}, error) {
+	future := actor.NewFutureActor()
+	defer future.Dispose()
+	future.Send(r.PPID, call{Sender: future.Self(), Request: request})
+	result, err := future.RecvWithTimeout(defaultCallTimeout)
+	if err != nil {
+		return nil, err
+	}
+	if f, ok := result.(failure); ok {
+		return nil, errors.New(f.Err)
+	}
+	return result, nil
+}
+
+// the calls and their replies are registered with the codec, the router might be on another node
+
+type call struct {
+	Sender  *pid.ProtectedPID
+	Request interface{}
+}
+
+type ok struct{}
+
+// failure is the reply of a failed call
+type failure struct {
+	Err string
+}
+
+type getRoutees struct{}
+
+type resize struct {
+	Size int
+}
+
+type addRoutee struct {
+	Routee *pid.ProtectedPID
+}
+
+type removeRoutee struct {
+	Routee *pid.ProtectedPID
+}
+
+type stop struct{}
+
+func init() {
+	for _, message := range []interface{}{
+		call{}, ok{}, failure{}, getRoutees{}, resize{}, addRoutee{}, removeRoutee{}, stop{}, []*pid.ProtectedPID(nil),
+	} {
+		codec.Register(message)
+	}
+}
+
+func router(act *actor.Actor) {
+	config := act.Args()[0].(*Config)
+	state := newState(config, act)
+	if err := state.init(); err != nil {
+		log.Println("router:", err)
+		return
+	}
+
+	act.Receive(func(message interface{}) (loop bool) {
+		switch msg := message.(type) {
+		case call:
+			return state.handleCall(msg)
+		case Broadcast:
+			for _, routee := range state.routees {
+				actor.Send(routee, msg.Message)
+			}
+		case sysmsg.Exit:
+			state.handleExit(msg)
+		case sysmsg.Shutdown:
+			// our supervisor wants us to shutdown
+			state.shutdownRoutees()
+			panic(sysmsg.Exit{
+				Who:      pid.ExtractPID(act.Self()),
+				Parent:   msg.Parent,
+				Reason:   sysmsg.Reason{Type: sysmsg.Kill, Details: "shutdown cmd received from supervisor"},
+				Relation: sysmsg.Linked,
+			})
+		default:
+			for _, routee := range state.config.Strategy.Select(message, state.routees) {
+				actor.Send(routee, message)
+			}
+		}
+		return true
+	})
+}
@@ -0,0 +1,54 @@
+package router
+
+import (
+	"errors"
+	"github.com/hedisam/goactor/actor"
+	"github.com/hedisam/goactor/codec"
+	"testing"
+	"time"
+)
+
+func TestCallToDeadRouter(t *testing.T) {
+	ref, err := Start(NewGroup(NewRoundRobin()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := ref.Stop(); err != nil {
+		t.Fatal(err)
+	}
+
+	start := time.Now()
+	_, err = ref.Routees()
+	if !errors.Is(err, actor.ErrTargetDown) {
+		t.Fatalf("expected ErrTargetDown, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed >= defaultCallTimeout {
+		t.Fatalf("the call waited for the timeout: %v", elapsed)
+	}
+}
+
+func TestFailureReply(t *testing.T) {
+	ref, err := Start(NewGroup(NewRoundRobin()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ref.Stop()
+
+	err = ref.Resize(2)
+	if err == nil || err.Error() != "only pool routers can be resized" {
+		t.Fatalf("unexpected resize error: %v", err)
+	}
+
+	// the failure can cross nodes
+	data, err := codec.Gob.Encode(failure{Err: "only pool routers can be resized"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	decoded, err := codec.Unmarshal(codec.Gob, codec.Context{}, data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if decoded != (failure{Err: "only pool routers can be resized"}) {
+		t.Fatalf("unexpected decoded failure: %v", decoded)
+	}
+}
@@ -0,0 +1,198 @@
+package router
+
+import (
+	"fmt"
+	"github.com/hedisam/goactor/actor"
+	"github.com/hedisam/goactor/internal/pid"
+	"github.com/hedisam/goactor/supervisor/spec"
+	"github.com/hedisam/goactor/sysmsg"
+	"time"
+)
+
+type state struct {
+	config  *Config
+	routees []*pid.ProtectedPID
+	router  *actor.Actor
+	// restarts are the times the crashed routees have been replaced within the last period
+	restarts []time.Time
+}
+
+func newState(config *Config, router *actor.Actor) *state {
+	return &state{
+		config: config,
+		router: router,
+	}
+}
+
+func (state *state) init() error {
+	if err := state.config.check(); err != nil {
+		return err
+	}
+	switch state.config.Type {
+	case PoolRouter:
+		// pool routers supervise their routees
+		state.router.TrapExit(true)
+		for i := 0; i < state.config.Size; i++ {
+			state.spawnRoutee()
+		}
+	case GroupRouter:
+		for _, routee := range state.config.Routees {
+			state.addRoutee(routee)
+		}
+	}
+	return nil
+}
+
+func (state *state) spawnRoutee() {
+	routee := state.router.SpawnLink(state.config.Routee, state.config.Args...)
+	state.routees = append(state.routees, routee)
+}
+
+func (state *state) addRoutee(routee *pid.ProtectedPID) {
+	if state.indexOf(pid.ExtractPID(routee)) != -1 {
+		return
+	}
+	state.router.Monitor(routee)
+	state.routees = append(state.routees, routee)
+}
+
+// removeRoutee removes the routee from the routees list. it returns false if it wasn't a routee.
+func (state *state) removeRoutee(_pid pid.PID) bool {
+	i := state.indexOf(_pid)
+	if i == -1 {
+		return false
+	}
+	// keep the order so round robin and consistent hash strategies are not disturbed more than needed
+	routees := make([]*pid.ProtectedPID, 0, len(state.routees)-1)
+	routees = append(routees, state.routees[:i]...)
+	state.routees = append(routees, state.routees[i+1:]...)
+	return true
+}
+
+func (state *state) indexOf(_pid pid.PID) int {
+	for i, routee := range state.routees {
+		if pid.ExtractPID(routee) == _pid {
+			return i
+		}
+	}
+	return -1
+}
+
+func (state *state) handleExit(exit sysmsg.Exit) {
+	who, _ := exit.Who.(pid.PID)
+	if !state.removeRoutee(who) {
+		if exit.Relation == sysmsg.Linked {
+			// it's not one of our routees. behave like an actor that doesn't trap exits.
+			switch exit.Reason.Type {
+			case sysmsg.Kill, sysmsg.Panic:
+				state.shutdownRoutees()
+				panic(sysmsg.Exit{
+					Who:      pid.ExtractPID(state.router.Self()),
+					Parent:   exit.Who,
+					Reason:   exit.Reason,
+					Relation: sysmsg.Linked,
+				})
+			}
+		}
+		return
+	}
+	if state.config.Type != PoolRouter {
+		return
+	}
+	// replace pool routees that have crashed. the ones that returned normally are just removed.
+	state.router.Unlink(pid.NewProtectedPID(who))
+	if exit.Reason.Type != sysmsg.Normal {
+		state.restart()
+	}
+}
+
+// restart replaces a crashed routee, unless too many have crashed within the period
+func (state *state) restart() {
+	now := time.Now()
+	since := now.Add(-time.Duration(state.config.Period) * time.Second)
+	restarts := state.restarts[:0]
+	for _, t := range state.restarts {
+		if t.After(since) {
+			restarts = append(restarts, t)
+		}
+	}
+	state.restarts = restarts
+	if len(state.restarts) >= state.config.MaxRestarts {
+		state.shutdownRoutees()
+		panic(sysmsg.Exit{
+			Who:      pid.ExtractPID(state.router.Self()),
+			Reason:   sysmsg.Reason{Type: sysmsg.SupMaxRestart, Details: "pool router's routees reached the max restarts"},
+			Relation: sysmsg.Linked,
+		})
+	}
+	state.restarts = append(state.restarts, now)
+	state.spawnRoutee()
+}
+
+// shutdown terminates a pool routee the same way a supervisor terminates its children
+func (state *state) shutdown(routee *pid.ProtectedPID) {
+	state.router.Unlink(routee)
+	actor.Send(routee, sysmsg.Shutdown{
+		Parent:   pid.ExtractPID(state.router.Self()),
+		Shutdown: spec.ShutdownKill,
+	})
+	pid.ExtractPID(routee).ShutdownFn()()
+}
+
+func (state *state) shutdownRoutees() {
+	if state.config.Type != PoolRouter {
+		return
+	}
+	for _, routee := range state.routees {
+		state.shutdown(routee)
+	}
+	state.routees = nil
+}
+
+func (state *state) handleCall(call call) bool {
+	switch request := call.Request.(type) {
+	case getRoutees:
+		routees := make([]*pid.ProtectedPID, len(state.routees))
+		copy(routees, state.routees)
+		actor.Send(call.Sender, routees)
+	case resize:
+		if state.config.Type != PoolRouter {
+			actor.Send(call.Sender, failure{Err: "only pool routers can be resized"})
+			return true
+		} else if request.Size < 0 {
+			actor.Send(call.Sender, failure{Err: fmt.Sprintf("invalid pool size: %d", request.Size)})
+			return true
+		}
+		for len(state.routees) < request.Size {
+			state.spawnRoutee()
+		}
+		for len(state.routees) > request.Size {
+			last := state.routees[len(state.routees)-1]
+			state.routees = state.routees[:len(state.routees)-1]
+			state.shutdown(last)
+		}
+		state.config.Size = request.Size
+		actor.Send(call.Sender, ok{})
+	case addRoutee:
+		if state.config.Type != GroupRouter {
+			actor.Send(call.Sender, failure{Err: "routees can only be added to group routers"})
+			return true
+		}
+		state.addRoutee(request.Routee)
+		actor.Send(call.Sender, ok{})
+	case removeRoutee:
+		if state.config.Type != GroupRouter {
+			actor.Send(call.Sender, failure{Err: "routees can only be removed from group routers"})
+			return true
+		}
+		if state.removeRoutee(pid.ExtractPID(request.Routee)) {
+			state.router.Demonitor(request.Routee)
+		}
+		actor.Send(call.Sender, ok{})
+	case stop:
+		state.shutdownRoutees()
+		actor.Send(call.Sender, ok{})
+		return false
+	}
+	return true
+}
@@ -0,0 +1,185 @@
+package router
+
+import (
+	"fmt"
+	"github.com/hedisam/goactor/internal/pid"
+	"hash/fnv"
+	"math/rand"
+	"sort"
+	"strconv"
+	"sync/atomic"
+)
+
+const defaultVirtualNodes = 100
+
+// Strategy selects the routees a message is delivered to.
+// a strategy instance holds routing state and must not be shared between routers.
+type Strategy interface {
+	Select(message interface{}, routees []*pid.ProtectedPID) []*pid.ProtectedPID
+}
+
+// ConsistentHashable can be implemented by messages routed by a consistent hash strategy with no key function
+type ConsistentHashable interface {
+	HashKey() string
+}
+
+type roundRobin struct {
+	next uint64
+}
+
+// NewRoundRobin returns a strategy that delivers messages to the routees in turns
+func NewRoundRobin() Strategy {
+	return &roundRobin{}
+}
+
+func (s *roundRobin) Select(_ interface{}, routees []*pid.ProtectedPID) []*pid.ProtectedPID {
+	if len(routees) == 0 {
+		return nil
+	}
+	i := atomic.AddUint64(&s.next, 1) - 1
+	return routees[i%uint64(len(routees)) : i%uint64(len(routees))+1]
+}
+
+type random struct{}
+
+// NewRandom returns a strategy that delivers each message to a randomly picked routee
+func NewRandom() Strategy {
+	return random{}
+}
+
+func (random) Select(_ interface{}, routees []*pid.ProtectedPID) []*pid.ProtectedPID {
+	if len(routees) == 0 {
+		return nil
+	}
+	i := rand.Intn(len(routees))
+	return routees[i : i+1]
+}
+
+type smallestMailbox struct{}
+
+// NewSmallestMailbox returns a strategy that delivers each message to the routee with the fewest
+// messages waiting in its mailbox
+func NewSmallestMailbox() Strategy {
+	return smallestMailbox{}
+}
+
+func (smallestMailbox) Select(_ interface{}, routees []*pid.ProtectedPID) []*pid.ProtectedPID {
+	if len(routees) == 0 {
+		return nil
+	}
+	smallest, size := 0, -1
+	for i, routee := range routees {
+		l := pid.ExtractPID(routee).Mailbox().Len()
+		if size == -1 || l < size {
+			smallest, size = i, l
+		}
+		if size == 0 {
+			break
+		}
+	}
+	return routees[smallest : smallest+1]
+}
+
+type broadcast struct{}
+
+// NewBroadcast returns a strategy that delivers every message to all routees
+func NewBroadcast() Strategy {
+	return broadcast{}
+}
+
+func (broadcast) Select(_ interface{}, routees []*pid.ProtectedPID) []*pid.ProtectedPID {
+	return routees
+}
+
+type consistentHash struct {
+	key          func(message interface{}) string
+	virtualNodes int
+	// the ring is rebuilt whenever the routees change
+	members []pid.PID
+	hashes  []uint32
+	ring    map[uint32]*pid.ProtectedPID
+}
+
+// NewConsistentHash returns a strategy that delivers messages with the same key to the same routee, as long as
+// the routees don't change. when they do, only the keys of the added/removed routees are moved.
+// key extracts the key from a message. if nil, messages must implement ConsistentHashable.
+func NewConsistentHash(key func(message interface{}) string) Strategy {
+	return &consistentHash{
+		key:          key,
+		virtualNodes: defaultVirtualNodes,
+	}
+}
+
+func (s *consistentHash) Select(message interface{}, routees []*pid.ProtectedPID) []*pid.ProtectedPID {
+	if len(routees) == 0 {
+		return nil
+	}
+	var key string
+	switch {
+	case s.key != nil:
+		key = s.key(message)
+	default:
+		hashable, ok := message.(ConsistentHashable)
+		if !ok {
+			return nil
+		}
+		key = hashable.HashKey()
+	}
+	s.update(routees)
+
+	h := hash(key)
+	i := sort.Search(len(s.hashes), func(i int) bool { return s.hashes[i] >= h })
+	if i == len(s.hashes) {
+		i = 0
+	}
+	return []*pid.ProtectedPID{s.ring[s.hashes[i]]}
+}
+
+func (s *consistentHash) update(routees []*pid.ProtectedPID) {
+	if s.unchanged(routees) {
+		return
+	}
+	s.members = make([]pid.PID, len(routees))
+	s.hashes = make([]uint32, 0, len(routees)*s.virtualNodes)
+	s.ring = make(map[uint32]*pid.ProtectedPID, len(routees)*s.virtualNodes)
+	for i, routee := range routees {
+		s.members[i] = pid.ExtractPID(routee)
+		id := routeeKey(routee)
+		for v := 0; v < s.virtualNodes; v++ {
+			h := hash(id + "#" + strconv.Itoa(v))
+			if _, taken := s.ring[h]; taken {
+				continue
+			}
+			s.ring[h] = routee
+			s.hashes = append(s.hashes, h)
+		}
+	}
+	sort.Slice(s.hashes, func(i, j int) bool { return s.hashes[i] < s.hashes[j] })
+}
+
+func (s *consistentHash) unchanged(routees []*pid.ProtectedPID) bool {
+	if len(routees) != len(s.members) {
+		return false
+	}
+	for i, routee := range routees {
+		if pid.ExtractPID(routee) != s.members[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// routeeKey identifies a routee on the hash ring. it's the id the actor is known by on every node, so routers on
+// different nodes build the same ring out of the same routees.
+func routeeKey(routee *pid.ProtectedPID) string {
+	if id := pid.RefOf("", pid.ExtractPID(routee)).ID; id != "" {
+		return id
+	}
+	return fmt.Sprintf("%p", pid.ExtractPID(routee))
+}
+
+func hash(key string) uint32 {
+	h := fnv.New32a()
+	_, _ = h.Write([]byte(key))
+	return h.Sum32()
+}
@@ -0,0 +1,341 @@
+package saga
+
+import (
+	"errors"
+	"fmt"
+	"github.com/hedisam/goactor/actor"
+	"github.com/hedisam/goactor/codec"
+	"github.com/hedisam/goactor/durable"
+	"github.com/hedisam/goactor/internal/pid"
+	"time"
+)
+
+const (
+	defaultTimeout             = 5 * time.Second
+	defaultCompensationRetries = 3
+)
+
+var (
+	// ErrTimeout is the error of a step whose target hasn't replied in time
+	ErrTimeout = errors.New("saga: step timed out")
+	// ErrTargetDown is the error of a step whose target isn't running or terminates before replying
+	ErrTargetDown = errors.New("saga: step target is down")
+)
+
+// errStopped stops compensating when the saga's actor is shut down, it's resumed by the next one
+var errStopped = errors.New("saga: stopped")
+
+// Status is where a saga is at
+type Status int32
+
+const (
+	Running Status = iota
+	// Compensating means a step has failed and the steps are being compensated, the failed one included
+	Compensating
+	// Completed means every step has succeeded
+	Completed
+	// Compensated means a step has failed and every step has been compensated, the failed one included
+	Compensated
+	// Failed means a compensation has failed, the saga is stuck and needs a human
+	Failed
+)
+
+func (s Status) String() string {
+	switch s {
+	case Running:
+		return "running"
+	case Compensating:
+		return "compensating"
+	case Completed:
+		return "completed"
+	case Compensated:
+		return "compensated"
+	case Failed:
+		return "failed"
+	default:
+		return fmt.Sprintf("status(%d)", int32(s))
+	}
+}
+
+// Call is sent to the target of a step, which replies to ReplyTo with the result, an error meaning the step
+// has failed. the target replies with Reply, which sends the errors as a Failure.
+type Call struct {
+	Saga string
+	Step string
+	// Compensation is true if the call undoes the step
+	Compensation bool
+	ReplyTo      *pid.ProtectedPID
+	Message      interface{}
+}
+
+// Reply sends the result to the saga, an error fails the step
+func (c Call) Reply(result interface{}) {
+	if err, ok := result.(error); ok {
+		// an error can't be encoded, the saga may be on another node
+		result = Failure{Err: err.Error()}
+	}
+	actor.Send(c.ReplyTo, result)
+}
+
+// Failure is the reply of a step that has failed
+type Failure struct {
+	Err string
+}
+
+func (f Failure) Error() string {
+	return f.Err
+}
+
+// Progress is the state of a saga as it's stored
+type Progress struct {
+	Status Status
+	Data   interface{}
+	// Completed is the number of steps that have succeeded
+	Completed int
+	// InDoubt is true if the step following the completed ones has failed. it might have taken effect anyway,
+	// timing out for instance, so it's compensated first.
+	InDoubt bool
+	// Compensated is the number of steps that have been undone, starting from the last one
+	Compensated int
+	// Err is the error of the step that has failed
+	Err string
+}
+
+func init() {
+	codec.Register(Call{})
+	codec.Register(Failure{})
+	codec.Register(Progress{})
+}
+
+// Step is a step of a saga. its target is sent a Call with the action's message, and with the compensation's
+// if the step or a later one fails, so a step may be compensated without having taken effect. a call may be sent
+// more than once when a stored saga resumes, so the targets should handle them idempotently.
+type Step struct {
+	Name string
+	// Target is the actor handling the step, a pid or a name
+	Target interface{}
+	// Action returns the message of the step from the saga's data
+	Action func(data interface{}) interface{}
+	// Compensation returns the message undoing the step, nil means there's nothing to undo
+	Compensation func(data interface{}) interface{}
+	// Result returns the saga's data updated with the result of the action, nil keeps the data as it is
+	Result func(data interface{}, result interface{}) interface{}
+	// Timeout is how long the target has to reply, the definition's timeout if zero
+	Timeout time.Duration
+}
+
+// Definition describes a saga
+type Definition struct {
+	Name  string
+	Steps []Step
+	// Timeout is the default timeout of the steps, default is 5s
+	Timeout time.Duration
+	// CompensationRetries is the number of times a failed compensation is retried, default is 3
+	CompensationRetries int
+	// Store keeps the progress so a restarted saga resumes where it left off. it's optional.
+	Store durable.Store
+	// Done is called by the saga's actor once the saga has finished, but not when a stored saga that has already
+	// finished is started again. it's optional.
+	Done func(id string, progress Progress)
+}
+
+// Start spawns a saga with the id and its initial data
+func Start(definition Definition, id string, data interface{}) *pid.ProtectedPID {
+	return actor.Spawn(Func(definition), id, data)
+}
+
+// Func returns the actor func running a saga, to be spawned or used in a supervisor spec with the saga's id and
+// its initial data as its args. the actor returns once the saga has finished, so a transient restart fits it.
+// it panics if the progress can't be stored, so its supervisor can resume it.
+func Func(definition Definition) actor.Func {
+	if definition.Timeout <= 0 {
+		definition.Timeout = defaultTimeout
+	}
+	if definition.CompensationRetries <= 0 {
+		definition.CompensationRetries = defaultCompensationRetries
+	}
+	return func(a *actor.Actor) {
+		s, err := load(a, definition)
+		if err != nil {
+			panic(fmt.Errorf("saga: could not load %s: %v", s.id, err))
+		}
+		s.run()
+	}
+}
+
+type saga struct {
+	actor      *actor.Actor
+	definition Definition
+	id         string
+	progress   Progress
+	revision   uint64
+}
+
+func load(a *actor.Actor, definition Definition) (*saga, error) {
+	s := &saga{actor: a, definition: definition}
+	if len(a.Args()) == 0 {
+		return s, fmt.Errorf("no saga id")
+	}
+	id, ok := a.Args()[0].(string)
+	if !ok {
+		return s, fmt.Errorf("the first argument is not a saga id")
+	}
+	s.id = id
+	if len(a.Args()) > 1 {
+		s.progress.Data = a.Args()[1]
+	}
+	if definition.Store == nil {
+		return s, nil
+	}
+	stored, revision, err := definition.Store.Get(s.key())
+	if err != nil {
+		return s, err
+	}
+	s.revision = revision
+	if revision == 0 {
+		return s, nil
+	}
+	progress, ok := stored.(Progress)
+	if !ok {
+		return s, fmt.Errorf("invalid progress")
+	}
+	s.progress = progress
+	return s, nil
+}
+
+func (s *saga) run() {
+	if s.progress.Status == Completed || s.progress.Status == Compensated || s.progress.Status == Failed {
+		// it's finished before, Done has been called already
+		return
+	}
+	steps := s.definition.Steps
+	for s.progress.Status == Running && s.progress.Completed < len(steps) {
+		step := steps[s.progress.Completed]
+		result, err := s.call(step, step.Action(s.progress.Data), false)
+		if err != nil && s.stopped() {
+			// the step might have succeeded, the next actor calls it again
+			return
+		}
+		if err != nil {
+			s.progress.Status = Compensating
+			s.progress.InDoubt = true
+			s.progress.Err = fmt.Sprintf("step %s: %v", step.Name, err)
+		} else {
+			if step.Result != nil {
+				s.progress.Data = step.Result(s.progress.Data, result)
+			}
+			s.progress.Completed++
+		}
+		s.save()
+	}
+	if s.progress.Status == Running {
+		s.progress.Status = Completed
+		s.save()
+	}
+
+	attempted := s.progress.Completed
+	if s.progress.InDoubt {
+		attempted++
+	}
+	for s.progress.Status == Compensating && s.progress.Compensated < attempted {
+		step := steps[attempted-s.progress.Compensated-1]
+		err := s.compensate(step)
+		if err == errStopped {
+			return
+		}
+		if err != nil {
+			s.progress.Status = Failed
+			s.progress.Err = fmt.Sprintf("%s, compensating step %s: %v", s.progress.Err, step.Name, err)
+		} else {
+			s.progress.Compensated++
+		}
+		s.save()
+	}
+	if s.progress.Status == Compensating {
+		s.progress.Status = Compensated
+		s.save()
+	}
+
+	if s.definition.Done != nil {
+		s.definition.Done(s.id, s.progress)
+	}
+}
+
+// compensate undoes the step, retrying a failed compensation
+func (s *saga) compensate(step Step) error {
+	if step.Compensation == nil {
+		return nil
+	}
+	var err error
+	for i := 0; i <= s.definition.CompensationRetries; i++ {
+		if _, err = s.call(step, step.Compensation(s.progress.Data), true); err == nil {
+			return nil
+		}
+		select {
+		case <-time.After(time.Duration(i+1) * 100 * time.Millisecond):
+		case <-s.actor.Done():
+			return errStopped
+		}
+	}
+	return err
+}
+
+// stopped returns true if the saga's actor has been shut down
+func (s *saga) stopped() bool {
+	select {
+	case <-s.actor.Done():
+		return true
+	default:
+		return false
+	}
+}
+
+// call sends the message to the step's target and waits for its reply. it fails with ErrTargetDown as soon as the
+// target terminates.
+func (s *saga) call(step Step, message interface{}, compensation bool) (interface{}, error) {
+	timeout := step.Timeout
+	if timeout <= 0 {
+		timeout = s.definition.Timeout
+	}
+	target, ok := step.Target.(*pid.ProtectedPID)
+	if !ok {
+		target = actor.Resolve(step.Target)
+	}
+	if target == nil {
+		return nil, ErrTargetDown
+	}
+	future := actor.NewFutureActor()
+	defer future.Dispose()
+	c := Call{Saga: s.id, Step: step.Name, Compensation: compensation, ReplyTo: future.Self(), Message: message}
+	// the target is monitored, so the call fails as soon as it terminates
+	future.Send(target, c)
+	result, err := future.RecvWithTimeout(timeout)
+	switch err {
+	case nil:
+	case actor.ErrTimeout:
+		return nil, ErrTimeout
+	case actor.ErrTargetDown:
+		return nil, ErrTargetDown
+	default:
+		return nil, err
+	}
+	if err, ok := result.(error); ok {
+		return nil, err
+	}
+	return result, nil
+}
+
+func (s *saga) save() {
+	if s.definition.Store == nil {
+		return
+	}
+	revision, err := s.definition.Store.Upsert(s.key(), s.revision, s.progress)
+	if err != nil {
+		panic(fmt.Errorf("saga: could not store %s: %v", s.id, err))
+	}
+	s.revision = revision
+}
+
+func (s *saga) key() string {
+	return s.definition.Name + "/" + s.id
+}
@@ -0,0 +1,116 @@
+package saga
+
+import (
+	"errors"
+	"github.com/hedisam/goactor/actor"
+	"github.com/hedisam/goactor/internal/pid"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// spawnTarget spawns a step target replying to its calls with the result of fn, the calls' messages are recorded
+func spawnTarget(calls chan<- string, fn func(c Call) interface{}) *pid.ProtectedPID {
+	return actor.Spawn(func(a *actor.Actor) {
+		a.Receive(func(message interface{}) (loop bool) {
+			c, ok := message.(Call)
+			if !ok {
+				return false
+			}
+			calls <- c.Message.(string)
+			c.Reply(fn(c))
+			return true
+		})
+	})
+}
+
+// run runs the saga and returns its progress once it's finished
+func run(t *testing.T, definition Definition) Progress {
+	done := make(chan Progress, 1)
+	definition.Done = func(id string, progress Progress) {
+		done <- progress
+	}
+	Start(definition, "1", nil)
+	select {
+	case progress := <-done:
+		return progress
+	case <-time.After(time.Second):
+		t.Fatal("the saga has not finished")
+		return Progress{}
+	}
+}
+
+// received returns the recorded calls
+func received(calls chan string) []string {
+	var messages []string
+	for {
+		select {
+		case m := <-calls:
+			messages = append(messages, m)
+		default:
+			return messages
+		}
+	}
+}
+
+func message(m string) func(interface{}) interface{} {
+	return func(interface{}) interface{} {
+		return m
+	}
+}
+
+func TestDeadTarget(t *testing.T) {
+	calls := make(chan string, 10)
+	target := spawnTarget(calls, func(c Call) interface{} {
+		return "ok"
+	})
+	defer actor.Send(target, "stop")
+	dead := actor.Spawn(func(a *actor.Actor) {})
+
+	progress := run(t, Definition{
+		Name: "dead",
+		// a dead target fails the step right away, not after the timeout
+		Timeout: time.Minute,
+		Steps: []Step{
+			{Name: "reserve", Target: target, Action: message("reserve"), Compensation: message("release")},
+			{Name: "charge", Target: dead, Action: message("charge")},
+		},
+	})
+	if progress.Status != Compensated {
+		t.Fatalf("expected the saga to be compensated, got %v: %s", progress.Status, progress.Err)
+	}
+	if want := "step charge: " + ErrTargetDown.Error(); progress.Err != want {
+		t.Fatalf("expected the error %q, got %q", want, progress.Err)
+	}
+	if got, want := received(calls), []string{"reserve", "release"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("expected the calls %v, got %v", want, got)
+	}
+}
+
+func TestFailureReply(t *testing.T) {
+	calls := make(chan string, 10)
+	target := spawnTarget(calls, func(c Call) interface{} {
+		if c.Message == "charge" {
+			return errors.New("insufficient funds")
+		}
+		return "ok"
+	})
+	defer actor.Send(target, "stop")
+
+	progress := run(t, Definition{
+		Name: "failure",
+		Steps: []Step{
+			{Name: "reserve", Target: target, Action: message("reserve"), Compensation: message("release")},
+			{Name: "charge", Target: target, Action: message("charge"), Compensation: message("refund")},
+		},
+	})
+	if progress.Status != Compensated {
+		t.Fatalf("expected the saga to be compensated, got %v: %s", progress.Status, progress.Err)
+	}
+	if want := "step charge: insufficient funds"; progress.Err != want {
+		t.Fatalf("expected the error %q, got %q", want, progress.Err)
+	}
+	if got, want := received(calls), []string{"reserve", "charge", "refund", "release"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("expected the calls %v, got %v", want, got)
+	}
+}
@@ -0,0 +1,253 @@
+package sharding
+
+import (
+	"github.com/hedisam/goactor/actor"
+	"github.com/hedisam/goactor/internal/pid"
+	"github.com/hedisam/goactor/router"
+	"github.com/hedisam/goactor/sysmsg"
+	"log"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// maxHops bounds the forwarding of a message while the regions disagree on who owns its shard, typically right
+// after a node has joined or left. a message that has reached it is delivered by the region it's at.
+const maxHops = 3
+
+// local commands
+
+type cmdTick struct{}
+
+type cmdStop struct{}
+
+// entity is a local entity as seen by its region
+type entity struct {
+	pid      *pid.ProtectedPID
+	shard    int
+	lastSeen time.Time
+	// a passivating entity buffers its messages until it has terminated
+	passivating bool
+	buffer      []interface{}
+}
+
+type regionState struct {
+	r *Region
+	a *actor.Actor
+	// regions of the entity type by node, ours included. shards are allocated over them by consistent hashing,
+	// so a node joining or leaving only moves the shards it takes or gives back.
+	regions  map[string]*pid.ProtectedPID
+	routees  []*pid.ProtectedPID
+	strategy router.Strategy
+	entities map[string]*entity
+	byPID    map[pid.PID]string
+}
+
+// region routes the envelopes to the regions owning their shards and runs the entities of the local shards
+func region(a *actor.Actor) {
+	r := a.Args()[0].(*Region)
+	// entities are linked so they die along with a crashing region, trapping lets us see them terminate
+	a.TrapExit(true)
+	defer r.entities.Close()
+
+	s := &regionState{
+		r:        r,
+		a:        a,
+		regions:  map[string]*pid.ProtectedPID{r.nodeName(): a.Self()},
+		strategy: router.NewConsistentHash(func(message interface{}) string { return message.(string) }),
+		entities: make(map[string]*entity),
+		byPID:    make(map[pid.PID]string),
+	}
+	s.updateRoutees()
+
+	if r.node != nil {
+		r.node.MonitorNodes(a.Self())
+		defer r.node.DemonitorNodes(a.Self())
+		for _, peer := range r.node.Nodes() {
+			s.announce(peer)
+		}
+	}
+	if r.config.PassivateAfter > 0 {
+		done := make(chan struct{})
+		defer close(done)
+		go tick(a.Self(), r.config.PassivateAfter, done)
+	}
+
+	a.Receive(func(message interface{}) (loop bool) {
+		switch msg := message.(type) {
+		case Envelope:
+			s.route(msg)
+		case regionUp:
+			s.regionUp(msg)
+		case sysmsg.Exit:
+			s.exited(msg)
+		case sysmsg.NodeUp:
+			s.announce(msg.Node)
+		case sysmsg.NodeDown:
+			s.regionDown(msg.Node)
+		case cmdTick:
+			s.passivateIdle()
+		case cmdStop, sysmsg.Shutdown:
+			for _, e := range s.entities {
+				s.passivate(e)
+			}
+			return false
+		default:
+			log.Printf("sharding: region %s received unknown message: %+v\n", r.config.TypeName, message)
+		}
+		return true
+	})
+}
+
+// route forwards the envelope to the region owning its shard, or delivers it if that's us
+func (s *regionState) route(env Envelope) {
+	shard := ShardOf(env.EntityID, s.r.config.Shards)
+	owner := s.owner(shard)
+	if !s.local(owner) && env.Hops < maxHops {
+		env.Hops++
+		actor.Send(owner, env)
+		return
+	}
+	s.deliver(env.EntityID, shard, env.Message)
+}
+
+// deliver sends the message to the local entity, starting it if it's not running
+func (s *regionState) deliver(id string, shard int, message interface{}) {
+	e, ok := s.entities[id]
+	if !ok {
+		args := append([]interface{}{id}, s.r.config.Args...)
+		e = &entity{pid: s.a.SpawnLink(s.r.config.Entity, args...), shard: shard}
+		s.entities[id] = e
+		s.byPID[pid.ExtractPID(e.pid)] = id
+		_ = s.r.entities.Register(id, e.pid, shard)
+	}
+	if e.passivating {
+		e.buffer = append(e.buffer, message)
+		return
+	}
+	e.lastSeen = time.Now()
+	actor.Send(e.pid, message)
+}
+
+func (s *regionState) passivate(e *entity) {
+	if e.passivating {
+		return
+	}
+	e.passivating = true
+	actor.Send(e.pid, Passivate{})
+}
+
+func (s *regionState) passivateIdle() {
+	for _, e := range s.entities {
+		if time.Since(e.lastSeen) >= s.r.config.PassivateAfter {
+			s.passivate(e)
+		}
+	}
+}
+
+// rebalance passivates the entities of the shards that have moved to other regions. their next messages start
+// them on their new region.
+func (s *regionState) rebalance() {
+	for _, e := range s.entities {
+		if !s.local(s.owner(e.shard)) {
+			s.passivate(e)
+		}
+	}
+}
+
+// exited handles a terminated entity or a peer region that has gone away
+func (s *regionState) exited(msg sysmsg.Exit) {
+	p, ok := msg.Who.(pid.PID)
+	if !ok {
+		return
+	}
+	if id, ok := s.byPID[p]; ok {
+		e := s.entities[id]
+		delete(s.byPID, p)
+		delete(s.entities, id)
+		s.r.entities.Unregister(id, e.pid)
+		// the shard might have moved meanwhile, so the buffered messages are routed again
+		for _, message := range e.buffer {
+			s.route(Envelope{EntityID: id, Message: message})
+		}
+		return
+	}
+	for node, region := range s.regions {
+		if node != s.r.nodeName() && pid.ExtractPID(region) == p {
+			s.regionDown(node)
+		}
+	}
+}
+
+func (s *regionState) regionUp(msg regionUp) {
+	if msg.PID == nil || msg.Node == s.r.nodeName() {
+		return
+	}
+	known, ok := s.regions[msg.Node]
+	if ok && pid.ExtractPID(known) == pid.ExtractPID(msg.PID) {
+		return
+	}
+	s.regions[msg.Node] = msg.PID
+	s.a.Monitor(msg.PID)
+	if !ok {
+		// the region might have started before the nodes connected, in which case it doesn't know about us yet
+		s.announce(msg.Node)
+	}
+	s.updateRoutees()
+	s.rebalance()
+}
+
+func (s *regionState) regionDown(node string) {
+	if _, ok := s.regions[node]; !ok {
+		return
+	}
+	delete(s.regions, node)
+	s.updateRoutees()
+	s.rebalance()
+}
+
+// announce tells the region of the entity type on the peer node about us
+func (s *regionState) announce(peer string) {
+	name := regionName(s.r.config.TypeName, peer)
+	_ = s.r.node.SendNamed(peer, name, regionUp{Node: s.r.nodeName(), PID: s.a.Self()})
+}
+
+// updateRoutees orders the regions by node, so every region hashes the same list
+func (s *regionState) updateRoutees() {
+	nodes := make([]string, 0, len(s.regions))
+	for node := range s.regions {
+		nodes = append(nodes, node)
+	}
+	sort.Strings(nodes)
+	s.routees = make([]*pid.ProtectedPID, len(nodes))
+	for i, node := range nodes {
+		s.routees[i] = s.regions[node]
+	}
+}
+
+// owner returns the region owning the shard
+func (s *regionState) owner(shard int) *pid.ProtectedPID {
+	return s.strategy.Select(strconv.Itoa(shard), s.routees)[0]
+}
+
+func (s *regionState) local(region *pid.ProtectedPID) bool {
+	return pid.ExtractPID(region) == pid.ExtractPID(s.a.Self())
+}
+
+// tick makes the region look for idle entities twice per passivation timeout
+func tick(self *pid.ProtectedPID, timeout time.Duration, done chan struct{}) {
+	interval := timeout / 2
+	if interval <= 0 {
+		interval = timeout
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			actor.Send(self, cmdTick{})
+		case <-done:
+			return
+		}
+	}
+}
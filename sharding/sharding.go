@@ -0,0 +1,149 @@
+package sharding
+
+import (
+	"fmt"
+	"github.com/hedisam/goactor/actor"
+	"github.com/hedisam/goactor/internal/pid"
+	"github.com/hedisam/goactor/node"
+	"hash/fnv"
+	"runtime"
+	"sort"
+	"time"
+)
+
+const defaultShards = 100
+
+// Envelope carries a message to an entity. it's what regions exchange, it can also be sent to a region directly.
+type Envelope struct {
+	EntityID string
+	Message  interface{}
+	// Hops counts the times the message has been forwarded between regions
+	Hops int
+}
+
+// Passivate is sent to an entity that should stop, because it's been idle or its shard has moved to another node.
+// the entity is expected to return false from its handler. messages sent to it meanwhile are buffered by the
+// region and delivered to a fresh entity once it has terminated.
+type Passivate struct{}
+
+// regionUp announces a region to the regions of the other nodes
+type regionUp struct {
+	Node string
+	PID  *pid.ProtectedPID
+}
+
+func init() {
+	node.RegisterMessage(Envelope{})
+	node.RegisterMessage(regionUp{})
+}
+
+// Config describes a type of entities
+type Config struct {
+	// TypeName names the type of entities. the regions of the same type on different nodes share its shards.
+	TypeName string
+	// Entity is started for an entity on its first message, with the entity id as its first argument followed by Args
+	Entity actor.Func
+	Args   []interface{}
+	// Shards is the number of shards the entities are spread across, it must be the same on every node.
+	// 100 by default.
+	Shards int
+	// PassivateAfter is how long an entity can go without a message before it's passivated, zero means never
+	PassivateAfter time.Duration
+}
+
+// Region hosts the entities of a type on the local node. an entity id is hashed to a shard, each shard is owned
+// by one of the regions of the cluster. messages for entities of shards owned by other nodes are forwarded to
+// their regions, the entities of the local shards are started on their first message.
+type Region struct {
+	node     *node.Node
+	config   Config
+	self     *pid.ProtectedPID
+	entities *actor.Registry
+}
+
+// Start starts the region of the entity type on the node. a nil node starts a region that owns all the shards.
+func Start(n *node.Node, config Config) (*Region, error) {
+	if config.TypeName == "" {
+		return nil, fmt.Errorf("entity type name is required")
+	}
+	if config.Entity == nil {
+		return nil, fmt.Errorf("entity func is required")
+	}
+	if config.Shards <= 0 {
+		config.Shards = defaultShards
+	}
+	r := &Region{
+		node:     n,
+		config:   config,
+		entities: actor.NewRegistry(actor.UniqueKeys, runtime.NumCPU()),
+	}
+	r.self = actor.Spawn(region, r)
+	if err := actor.Register(regionName(config.TypeName, r.nodeName()), r.self); err != nil {
+		actor.Send(r.self, cmdStop{})
+		r.entities.Close()
+		return nil, err
+	}
+	return r, nil
+}
+
+// Send sends the message to the entity, wherever it lives
+func (r *Region) Send(entityID string, message interface{}) {
+	actor.Send(r.self, Envelope{EntityID: entityID, Message: message})
+}
+
+// PID returns the pid of the region actor
+func (r *Region) PID() *pid.ProtectedPID {
+	return r.self
+}
+
+// ShardOf returns the shard of the entity
+func (r *Region) ShardOf(entityID string) int {
+	return ShardOf(entityID, r.config.Shards)
+}
+
+// Entity returns the entity if it's running on the local node, nil otherwise
+func (r *Region) Entity(entityID string) *pid.ProtectedPID {
+	entries := r.entities.Lookup(entityID)
+	if len(entries) == 0 {
+		return nil
+	}
+	return entries[0].PID
+}
+
+// Entities returns the ids of the entities running on the local node, sorted
+func (r *Region) Entities() []string {
+	entries := r.entities.Select(func(actor.RegistryEntry) bool { return true })
+	ids := make([]string, 0, len(entries))
+	for _, e := range entries {
+		ids = append(ids, e.Key)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// Stop passivates the local entities and stops the region, its shards move to the other regions
+func (r *Region) Stop() {
+	actor.Unregister(regionName(r.config.TypeName, r.nodeName()))
+	actor.Send(r.self, cmdStop{})
+}
+
+func (r *Region) nodeName() string {
+	if r.node == nil {
+		return ""
+	}
+	return r.node.Name()
+}
+
+// ShardOf hashes the entity id to one of the shards
+func ShardOf(entityID string, shards int) int {
+	h := fnv.New32a()
+	_, _ = h.Write([]byte(entityID))
+	return int(h.Sum32() % uint32(shards))
+}
+
+func regionName(typeName, node string) string {
+	if node == "" {
+		return "goactor.sharding." + typeName
+	}
+	return "goactor.sharding." + typeName + "@" + node
+}
@@ -0,0 +1,289 @@
+package singleton
+
+import (
+	"github.com/hedisam/goactor/actor"
+	"github.com/hedisam/goactor/internal/pid"
+	"github.com/hedisam/goactor/supervisor/spec"
+	"github.com/hedisam/goactor/sysmsg"
+	"log"
+	"time"
+)
+
+type cmdStop struct{}
+
+// handshakeExpired tells the manager to stop waiting for the peers that haven't answered its announcement
+type handshakeExpired struct{}
+
+type member struct {
+	pid     *pid.ProtectedPID
+	started int64
+}
+
+type managerState struct {
+	m *Manager
+	a *actor.Actor
+	// managers by node, ours included
+	members map[string]member
+	// handshaking are the nodes whose managers haven't answered our announcement yet. the singleton isn't
+	// started until they all have, or the handshake has timed out, or we'd think we're the oldest node.
+	handshaking map[string]bool
+	// running are the singletons by the nodes running them as far as we know, ours included. there's more
+	// than one only until the managers agree on the oldest node, after a partition heals for instance.
+	running map[string]*pid.ProtectedPID
+	// host is the node the proxy routes to
+	host    string
+	hostPID *pid.ProtectedPID
+	// stopping are the nodes handing their singleton over, ours included. the proxy doesn't route to them.
+	stopping map[string]bool
+	// singleton is the singleton when it runs on our node
+	singleton *pid.ProtectedPID
+	leaving   bool
+}
+
+// manager starts the singleton when our node is the oldest one and stops it when it's not anymore
+func manager(a *actor.Actor) {
+	m := a.Args()[0].(*Manager)
+	// the singleton is linked so it dies along with a crashing manager, trapping lets us see it terminate
+	a.TrapExit(true)
+	defer close(m.done)
+	defer actor.Send(m.proxy, cmdStop{})
+	defer m.setHost("")
+
+	s := &managerState{
+		m:           m,
+		a:           a,
+		members:     map[string]member{m.node.Name(): {pid: a.Self(), started: m.started}},
+		handshaking: make(map[string]bool),
+		running:     make(map[string]*pid.ProtectedPID),
+		stopping:    make(map[string]bool),
+	}
+	m.node.MonitorNodes(a.Self())
+	defer m.node.DemonitorNodes(a.Self())
+	for _, peer := range m.node.Nodes() {
+		s.handshaking[peer] = true
+		s.announce(peer)
+	}
+	if len(s.handshaking) > 0 {
+		self := a.Self()
+		time.AfterFunc(handshakeTimeout, func() {
+			actor.Send(self, handshakeExpired{})
+		})
+	}
+	s.check()
+
+	a.Receive(func(message interface{}) (loop bool) {
+		switch msg := message.(type) {
+		case managerUp:
+			s.managerUp(msg)
+		case handshakeExpired:
+			// the nodes that haven't answered don't run a manager of the singleton, or can't be reached
+			s.handshaking = make(map[string]bool)
+			s.check()
+		case singletonStarted:
+			if _, ok := s.members[msg.Node]; ok && msg.Node != m.node.Name() {
+				s.running[msg.Node] = msg.PID
+				s.updateHost()
+				s.check()
+			}
+		case singletonStopping:
+			if _, ok := s.running[msg.Node]; ok && msg.Node != m.node.Name() {
+				s.stopping[msg.Node] = true
+				s.updateHost()
+			}
+		case singletonStopped:
+			if msg.Node != m.node.Name() {
+				delete(s.running, msg.Node)
+				delete(s.stopping, msg.Node)
+				s.updateHost()
+				s.check()
+			}
+		case sysmsg.Exit:
+			return s.exited(msg)
+		case sysmsg.NodeUp:
+			s.announce(msg.Node)
+		case sysmsg.NodeDown:
+			s.memberDown(msg.Node)
+		case cmdStop, sysmsg.Shutdown:
+			s.leaving = true
+			if s.singleton == nil {
+				return false
+			}
+			s.stop()
+		default:
+			log.Printf("singleton: %s manager received unknown message: %+v\n", m.config.Name, message)
+		}
+		return true
+	})
+}
+
+// check starts or stops the singleton depending on whether we're the oldest node
+func (s *managerState) check() {
+	if len(s.handshaking) > 0 {
+		return
+	}
+	oldest := s.oldest()
+	if s.singleton != nil {
+		if oldest != s.m.node.Name() {
+			s.stop()
+		}
+		return
+	}
+	if oldest == s.m.node.Name() && len(s.running) == 0 {
+		s.singleton = s.a.SpawnLink(s.m.config.Singleton, s.m.config.Args...)
+		s.running[s.m.node.Name()] = s.singleton
+		s.updateHost()
+		s.broadcast(singletonStarted{Node: s.m.node.Name(), PID: s.singleton})
+	}
+}
+
+// oldest returns the node of the oldest manager, a leaving manager doesn't count
+func (s *managerState) oldest() string {
+	var oldest string
+	for node := range s.members {
+		if node == s.m.node.Name() && s.leaving {
+			continue
+		}
+		if oldest == "" || s.older(node, oldest) {
+			oldest = node
+		}
+	}
+	return oldest
+}
+
+// older reports whether the manager of node a has started before the one of b, the node names break the ties
+func (s *managerState) older(a, b string) bool {
+	ma, mb := s.members[a], s.members[b]
+	if ma.started != mb.started {
+		return ma.started < mb.started
+	}
+	return a < b
+}
+
+// stop shuts down the local singleton the same way a supervisor shuts down its children, its Exit completes
+// the handover
+func (s *managerState) stop() {
+	if s.stopping[s.m.node.Name()] {
+		return
+	}
+	s.stopping[s.m.node.Name()] = true
+	s.updateHost()
+	s.broadcast(singletonStopping{Node: s.m.node.Name()})
+	actor.Send(s.singleton, sysmsg.Shutdown{
+		Parent:   pid.ExtractPID(s.a.Self()),
+		Shutdown: spec.ShutdownKill,
+	})
+	pid.ExtractPID(s.singleton).ShutdownFn()()
+}
+
+func (s *managerState) exited(msg sysmsg.Exit) bool {
+	p, ok := msg.Who.(pid.PID)
+	if !ok {
+		return true
+	}
+	if s.singleton != nil && pid.ExtractPID(s.singleton) == p {
+		if !s.stopping[s.m.node.Name()] {
+			log.Printf("singleton: %s terminated: %+v\n", s.m.config.Name, msg.Reason)
+		}
+		s.singleton = nil
+		delete(s.stopping, s.m.node.Name())
+		delete(s.running, s.m.node.Name())
+		s.updateHost()
+		s.broadcast(singletonStopped{Node: s.m.node.Name()})
+		if s.leaving {
+			return false
+		}
+		s.check()
+		return true
+	}
+	for node, mb := range s.members {
+		if node != s.m.node.Name() && pid.ExtractPID(mb.pid) == p {
+			s.memberDown(node)
+		}
+	}
+	return true
+}
+
+func (s *managerState) managerUp(msg managerUp) {
+	if msg.PID == nil || msg.Node == s.m.node.Name() {
+		return
+	}
+	known, ok := s.members[msg.Node]
+	if ok && pid.ExtractPID(known.pid) == pid.ExtractPID(msg.PID) {
+		return
+	}
+	delete(s.handshaking, msg.Node)
+	s.members[msg.Node] = member{pid: msg.PID, started: msg.Started}
+	s.a.Monitor(msg.PID)
+	if !ok {
+		// the manager might have started before the nodes connected, in which case it doesn't know about us yet
+		s.announce(msg.Node)
+	}
+	if msg.Singleton != nil {
+		s.running[msg.Node] = msg.Singleton
+		s.stopping[msg.Node] = msg.Stopping
+		s.updateHost()
+	}
+	s.check()
+}
+
+// memberDown forgets a manager that has stopped or whose node has gone down, along with its singleton
+func (s *managerState) memberDown(node string) {
+	if s.handshaking[node] {
+		delete(s.handshaking, node)
+		s.check()
+	}
+	if _, ok := s.members[node]; !ok {
+		return
+	}
+	delete(s.members, node)
+	delete(s.running, node)
+	delete(s.stopping, node)
+	s.updateHost()
+	s.check()
+}
+
+// updateHost points the proxy to the singleton of the oldest node running one. a singleton being stopped is
+// left out, the proxy buffers the messages until its successor has started.
+func (s *managerState) updateHost() {
+	var host string
+	for node := range s.running {
+		if s.stopping[node] {
+			continue
+		}
+		if host == "" || s.older(node, host) {
+			host = node
+		}
+	}
+	if host == s.host && samePID(s.running[host], s.hostPID) {
+		return
+	}
+	s.host, s.hostPID = host, s.running[host]
+	s.m.setHost(host)
+	actor.Send(s.m.proxy, hostChanged{pid: s.hostPID})
+}
+
+func (s *managerState) announce(peer string) {
+	up := managerUp{
+		Node:      s.m.node.Name(),
+		PID:       s.a.Self(),
+		Started:   s.m.started,
+		Singleton: s.singleton,
+		Stopping:  s.stopping[s.m.node.Name()],
+	}
+	_ = s.m.node.SendNamed(peer, managerName(s.m.config.Name, peer), up)
+}
+
+func (s *managerState) broadcast(message interface{}) {
+	for node := range s.members {
+		if node != s.m.node.Name() {
+			_ = s.m.node.SendNamed(node, managerName(s.m.config.Name, node), message)
+		}
+	}
+}
+
+func samePID(a, b *pid.ProtectedPID) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return pid.ExtractPID(a) == pid.ExtractPID(b)
+}
@@ -0,0 +1,46 @@
+package singleton
+
+import (
+	"github.com/hedisam/goactor/actor"
+	"github.com/hedisam/goactor/internal/pid"
+	"log"
+)
+
+// hostChanged tells the proxy where the singleton runs, a nil pid means it's moving
+type hostChanged struct {
+	pid *pid.ProtectedPID
+}
+
+// proxy routes the messages to the singleton, buffering them while there's none
+func proxy(a *actor.Actor) {
+	m := a.Args()[0].(*Manager)
+	var singleton *pid.ProtectedPID
+	var buffer []interface{}
+
+	a.Receive(func(message interface{}) (loop bool) {
+		switch msg := message.(type) {
+		case hostChanged:
+			singleton = msg.pid
+			if singleton == nil {
+				return true
+			}
+			for _, buffered := range buffer {
+				actor.Send(singleton, buffered)
+			}
+			buffer = nil
+		case cmdStop:
+			return false
+		default:
+			if singleton != nil {
+				actor.Send(singleton, message)
+				return true
+			}
+			if len(buffer) == m.config.BufferSize {
+				log.Printf("singleton: %s proxy buffer is full, dropping the oldest message\n", m.config.Name)
+				buffer = buffer[1:]
+			}
+			buffer = append(buffer, message)
+		}
+		return true
+	})
+}
@@ -0,0 +1,143 @@
+package singleton
+
+import (
+	"fmt"
+	"github.com/hedisam/goactor/actor"
+	"github.com/hedisam/goactor/internal/pid"
+	"github.com/hedisam/goactor/node"
+	"sync"
+	"time"
+)
+
+const (
+	defaultBufferSize = 1000
+	// stopTimeout is how long Stop waits for the singleton to hand over
+	stopTimeout = 5 * time.Second
+	// handshakeTimeout is how long a starting manager waits for the managers of the connected nodes to answer
+	// its announcement before deciding whether to run the singleton
+	handshakeTimeout = 2 * time.Second
+)
+
+// messages exchanged by the managers of the nodes
+
+// managerUp announces a manager to the managers of the other nodes, along with the singleton it's running
+type managerUp struct {
+	Node string
+	PID  *pid.ProtectedPID
+	// Started orders the managers, the oldest one runs the singleton
+	Started   int64
+	Singleton *pid.ProtectedPID
+	Stopping  bool
+}
+
+type singletonStarted struct {
+	Node string
+	PID  *pid.ProtectedPID
+}
+
+// singletonStopping tells the proxies to hold the messages, the singleton is being handed over
+type singletonStopping struct {
+	Node string
+}
+
+type singletonStopped struct {
+	Node string
+}
+
+func init() {
+	node.RegisterMessage(managerUp{})
+	node.RegisterMessage(singletonStarted{})
+	node.RegisterMessage(singletonStopping{})
+	node.RegisterMessage(singletonStopped{})
+}
+
+// Config describes a singleton
+type Config struct {
+	// Name names the singleton, the managers of the same name on different nodes run one singleton between them
+	Name      string
+	Singleton actor.Func
+	Args      []interface{}
+	// BufferSize is the number of messages the proxy buffers while the singleton is moving, 1000 by default.
+	// the oldest ones are dropped when it's full.
+	BufferSize int
+}
+
+// Manager runs the singleton when its node is the oldest one among the nodes running a manager of the same name.
+// when the node hosting the singleton goes down or stops its manager, the singleton is started on the next
+// oldest node. a node only starts it once the previous one has stopped it, or is gone.
+type Manager struct {
+	node    *node.Node
+	config  Config
+	started int64
+	self    *pid.ProtectedPID
+	proxy   *pid.ProtectedPID
+	done    chan struct{}
+
+	mu   sync.RWMutex
+	host string
+}
+
+// Start starts the manager of the singleton on the node
+func Start(n *node.Node, config Config) (*Manager, error) {
+	if config.Name == "" {
+		return nil, fmt.Errorf("singleton name is required")
+	}
+	if config.Singleton == nil {
+		return nil, fmt.Errorf("singleton func is required")
+	}
+	if config.BufferSize <= 0 {
+		config.BufferSize = defaultBufferSize
+	}
+	m := &Manager{
+		node:    n,
+		config:  config,
+		started: time.Now().UnixNano(),
+		done:    make(chan struct{}),
+	}
+	m.proxy = actor.Spawn(proxy, m)
+	m.self = actor.Spawn(manager, m)
+	if err := actor.Register(managerName(config.Name, n.Name()), m.self); err != nil {
+		actor.Send(m.self, cmdStop{})
+		return nil, err
+	}
+	return m, nil
+}
+
+// Send sends the message to the singleton through the proxy, wherever the singleton runs
+func (m *Manager) Send(message interface{}) {
+	actor.Send(m.proxy, message)
+}
+
+// Proxy returns the proxy of the singleton. messages sent to it are routed to the singleton, and buffered while
+// it's moving between nodes. the ones already on their way to a singleton that's being stopped are lost.
+func (m *Manager) Proxy() *pid.ProtectedPID {
+	return m.proxy
+}
+
+// Host returns the node running the singleton, empty if it's not running anywhere we know of
+func (m *Manager) Host() string {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.host
+}
+
+// Stop stops the manager. if it's running the singleton, the singleton is stopped first so the next oldest node
+// takes it over.
+func (m *Manager) Stop() {
+	actor.Unregister(managerName(m.config.Name, m.node.Name()))
+	actor.Send(m.self, cmdStop{})
+	select {
+	case <-m.done:
+	case <-time.After(stopTimeout):
+	}
+}
+
+func (m *Manager) setHost(host string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.host = host
+}
+
+func managerName(name, node string) string {
+	return "goactor.singleton." + name + "@" + node
+}
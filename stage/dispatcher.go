@@ -0,0 +1,237 @@
+package stage
+
+import (
+	"fmt"
+	"github.com/hedisam/goactor/internal/pid"
+	"log"
+)
+
+// Subscriber is a consumer subscribed to a producer
+type Subscriber struct {
+	Ref string
+	PID *pid.ProtectedPID
+	// Partition is the partition the consumer asked for, for the partition dispatcher
+	Partition interface{}
+}
+
+// Dispatcher decides which of a producer's consumers get its events. it's only used by the producer's actor.
+type Dispatcher interface {
+	Subscribe(s Subscriber) error
+	Cancel(ref string)
+	// Ask adds the demand of the consumer and returns how many more events the producer should produce
+	Ask(ref string, demand int) int
+	// Dispatch sends the events to the consumers as far as their demand goes, returning the events left over,
+	// which the producer buffers until the next demand
+	Dispatch(events []interface{}, send func(s Subscriber, events []interface{})) []interface{}
+}
+
+type demandSubscriber struct {
+	Subscriber
+	demand int
+}
+
+// demandDispatcher sends each event to one consumer, the one with the highest demand
+type demandDispatcher struct {
+	subscribers []*demandSubscriber
+}
+
+// NewDemandDispatcher returns a dispatcher sending each event to the consumer with the highest demand.
+// it's the default dispatcher.
+func NewDemandDispatcher() Dispatcher {
+	return &demandDispatcher{}
+}
+
+func (d *demandDispatcher) Subscribe(s Subscriber) error {
+	d.subscribers = append(d.subscribers, &demandSubscriber{Subscriber: s})
+	return nil
+}
+
+func (d *demandDispatcher) Cancel(ref string) {
+	for i, s := range d.subscribers {
+		if s.Ref == ref {
+			d.subscribers = append(d.subscribers[:i], d.subscribers[i+1:]...)
+			return
+		}
+	}
+}
+
+func (d *demandDispatcher) Ask(ref string, demand int) int {
+	for _, s := range d.subscribers {
+		if s.Ref == ref {
+			s.demand += demand
+			return demand
+		}
+	}
+	return 0
+}
+
+func (d *demandDispatcher) Dispatch(events []interface{}, send func(s Subscriber, events []interface{})) []interface{} {
+	for len(events) > 0 {
+		var best *demandSubscriber
+		for _, s := range d.subscribers {
+			if s.demand > 0 && (best == nil || s.demand > best.demand) {
+				best = s
+			}
+		}
+		if best == nil {
+			break
+		}
+		n := min(best.demand, len(events))
+		send(best.Subscriber, events[:n])
+		best.demand -= n
+		events = events[n:]
+	}
+	return events
+}
+
+// broadcastDispatcher sends every event to every consumer, as fast as the slowest one
+type broadcastDispatcher struct {
+	subscribers []*demandSubscriber
+}
+
+// NewBroadcastDispatcher returns a dispatcher sending every event to every consumer. events are dispatched as
+// far as the lowest demand goes.
+func NewBroadcastDispatcher() Dispatcher {
+	return &broadcastDispatcher{}
+}
+
+func (d *broadcastDispatcher) Subscribe(s Subscriber) error {
+	d.subscribers = append(d.subscribers, &demandSubscriber{Subscriber: s})
+	return nil
+}
+
+func (d *broadcastDispatcher) Cancel(ref string) {
+	for i, s := range d.subscribers {
+		if s.Ref == ref {
+			d.subscribers = append(d.subscribers[:i], d.subscribers[i+1:]...)
+			return
+		}
+	}
+}
+
+func (d *broadcastDispatcher) Ask(ref string, demand int) int {
+	before := d.demand()
+	for _, s := range d.subscribers {
+		if s.Ref == ref {
+			s.demand += demand
+		}
+	}
+	return d.demand() - before
+}
+
+func (d *broadcastDispatcher) Dispatch(events []interface{}, send func(s Subscriber, events []interface{})) []interface{} {
+	n := min(d.demand(), len(events))
+	if n == 0 {
+		return events
+	}
+	for _, s := range d.subscribers {
+		send(s.Subscriber, events[:n])
+		s.demand -= n
+	}
+	return events[n:]
+}
+
+// demand returns the lowest demand of the consumers
+func (d *broadcastDispatcher) demand() int {
+	if len(d.subscribers) == 0 {
+		return 0
+	}
+	lowest := d.subscribers[0].demand
+	for _, s := range d.subscribers[1:] {
+		lowest = min(lowest, s.demand)
+	}
+	return lowest
+}
+
+// partitionDispatcher sends each event to the consumer of its partition
+type partitionDispatcher struct {
+	partitions map[interface{}]*demandSubscriber
+	partition  func(event interface{}) interface{}
+}
+
+// NewPartitionDispatcher returns a dispatcher sending each event to the consumer of the partition the function
+// returns for it. each partition has one consumer, which tells the partition it's after when it subscribes.
+// events of a partition without demand wait for it in the producer's buffer, in order. partitions must be
+// comparable, an event whose partition is a slice or a map for instance is dropped.
+func NewPartitionDispatcher(partition func(event interface{}) interface{}) Dispatcher {
+	return &partitionDispatcher{partitions: make(map[interface{}]*demandSubscriber), partition: partition}
+}
+
+func (d *partitionDispatcher) Subscribe(s Subscriber) error {
+	if s.Partition == nil {
+		return fmt.Errorf("stage: no partition to subscribe to")
+	}
+	if err := checkPartition(s.Partition); err != nil {
+		return err
+	}
+	if _, ok := d.partitions[s.Partition]; ok {
+		return fmt.Errorf("stage: partition %v already has a consumer", s.Partition)
+	}
+	d.partitions[s.Partition] = &demandSubscriber{Subscriber: s}
+	return nil
+}
+
+func (d *partitionDispatcher) Cancel(ref string) {
+	for partition, s := range d.partitions {
+		if s.Ref == ref {
+			delete(d.partitions, partition)
+			return
+		}
+	}
+}
+
+func (d *partitionDispatcher) Ask(ref string, demand int) int {
+	for _, s := range d.partitions {
+		if s.Ref == ref {
+			s.demand += demand
+			return demand
+		}
+	}
+	return 0
+}
+
+func (d *partitionDispatcher) Dispatch(events []interface{}, send func(s Subscriber, events []interface{})) []interface{} {
+	var left []interface{}
+	batches := make(map[*demandSubscriber][]interface{})
+	var order []*demandSubscriber
+	for _, event := range events {
+		partition := d.partition(event)
+		if err := checkPartition(partition); err != nil {
+			log.Printf("stage: dropped event %v: %v\n", event, err)
+			continue
+		}
+		s := d.partitions[partition]
+		if s == nil || s.demand == 0 {
+			left = append(left, event)
+			continue
+		}
+		if _, ok := batches[s]; !ok {
+			order = append(order, s)
+		}
+		batches[s] = append(batches[s], event)
+		s.demand--
+	}
+	for _, s := range order {
+		send(s.Subscriber, batches[s])
+	}
+	return left
+}
+
+// checkPartition returns an error if the partition can't be a map key
+func checkPartition(partition interface{}) (err error) {
+	defer func() {
+		// a comparable type might still hold an uncomparable value, in an interface field
+		if recover() != nil {
+			err = fmt.Errorf("stage: partition %v of type %T is not comparable", partition, partition)
+		}
+	}()
+	_ = map[interface{}]struct{}{partition: {}}
+	return nil
+}
+
+func min(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,38 @@
+package stage
+
+import (
+	"reflect"
+	"testing"
+)
+
+// key is comparable, its value might not be
+type key struct {
+	Value interface{}
+}
+
+func TestPartitionDispatcherUncomparablePartitions(t *testing.T) {
+	d := NewPartitionDispatcher(func(event interface{}) interface{} {
+		return event.(key)
+	})
+	for _, partition := range []interface{}{[]int{1}, map[string]int{}, key{Value: []int{1}}} {
+		if err := d.Subscribe(Subscriber{Ref: "invalid", Partition: partition}); err == nil {
+			t.Fatalf("subscribed to the partition %v", partition)
+		}
+	}
+	if err := d.Subscribe(Subscriber{Ref: "valid", Partition: key{Value: 1}}); err != nil {
+		t.Fatal(err)
+	}
+	d.Ask("valid", 10)
+
+	var sent []interface{}
+	left := d.Dispatch([]interface{}{key{Value: 1}, key{Value: []int{1}}, key{Value: 2}},
+		func(s Subscriber, events []interface{}) {
+			sent = append(sent, events...)
+		})
+	if want := []interface{}{key{Value: 1}}; !reflect.DeepEqual(sent, want) {
+		t.Fatalf("expected %v to be sent, got %v", want, sent)
+	}
+	if want := []interface{}{key{Value: 2}}; !reflect.DeepEqual(left, want) {
+		t.Fatalf("expected %v to be left, got %v", want, left)
+	}
+}
@@ -0,0 +1,289 @@
+package stage
+
+import (
+	"fmt"
+	"github.com/hedisam/goactor/actor"
+	"github.com/hedisam/goactor/internal/pid"
+	"github.com/hedisam/goactor/sysmsg"
+	"github.com/rs/xid"
+	"log"
+)
+
+// upstream is a subscription of the stage to a producer
+type upstream struct {
+	ref          string
+	producer     *pid.ProtectedPID
+	subscription Subscription
+	// pending is the demand asked for and not received yet
+	pending int
+}
+
+type stage struct {
+	context *Context
+	// dispatcher is nil for consumers
+	dispatcher Dispatcher
+	bufferSize int
+	buffer     []interface{}
+	// consumers subscribed to us by their refs
+	consumers map[string]*pid.ProtectedPID
+	// producers we're subscribed to by their refs
+	producers map[string]*upstream
+	// refs of the producers we've held off asking until our buffer drains
+	deferred map[string]bool
+
+	demand func(c *Context, demand int)
+	events func(c *Context, events []interface{})
+	handle func(c *Context, message interface{}) (loop bool)
+	// cancelledFn overrides the cancel modes if it's set
+	cancelledFn func(c *Context, subscription Subscription, reason string) (loop bool)
+}
+
+func (s *stage) run(subscriptions []Subscription) {
+	for _, subscription := range subscriptions {
+		if err := s.subscribe(subscription); err != nil {
+			panic(err)
+		}
+	}
+	s.context.Receive(s.receive)
+}
+
+func (s *stage) receive(message interface{}) (loop bool) {
+	switch msg := message.(type) {
+	case subscribe:
+		s.subscribed(msg)
+	case ask:
+		s.asked(msg)
+	case events:
+		s.received(msg)
+	case cancel:
+		if _, ok := s.consumers[msg.Ref]; ok {
+			s.cancelConsumer(msg.Ref, true)
+			return true
+		}
+		if up, ok := s.producers[msg.Ref]; ok {
+			return s.cancelled(up, msg.Reason)
+		}
+	case subscribeTo:
+		if err := s.subscribe(msg.Subscription); err != nil {
+			log.Println(err)
+		}
+	case sysmsg.Exit:
+		if msg.Relation != sysmsg.Monitored || !s.isPeer(msg.Who) {
+			return s.handleOther(message)
+		}
+		for ref, consumer := range s.consumers {
+			if pid.ExtractPID(consumer) == msg.Who {
+				s.cancelConsumer(ref, false)
+			}
+		}
+		for _, up := range s.producers {
+			if pid.ExtractPID(up.producer) != msg.Who {
+				continue
+			}
+			reason := ""
+			if msg.Reason.Type != sysmsg.Normal {
+				reason = fmt.Sprintf("producer exited: %v", msg.Reason.Details)
+			}
+			if !s.cancelled(up, reason) {
+				return false
+			}
+		}
+	default:
+		return s.handleOther(message)
+	}
+	return true
+}
+
+func (s *stage) handleOther(message interface{}) bool {
+	if s.handle == nil {
+		return true
+	}
+	return s.handle(s.context, message)
+}
+
+// isPeer returns true if the actor is one of our consumers or producers
+func (s *stage) isPeer(who interface{}) bool {
+	for _, consumer := range s.consumers {
+		if pid.ExtractPID(consumer) == who {
+			return true
+		}
+	}
+	for _, up := range s.producers {
+		if pid.ExtractPID(up.producer) == who {
+			return true
+		}
+	}
+	return false
+}
+
+// subscribe subscribes the stage to a producer
+func (s *stage) subscribe(subscription Subscription) error {
+	if subscription.MaxDemand <= 0 {
+		subscription.MaxDemand = defaultMaxDemand
+	}
+	if subscription.MinDemand <= 0 || subscription.MinDemand >= subscription.MaxDemand {
+		subscription.MinDemand = subscription.MaxDemand * 3 / 4
+	}
+	producer := whereIs(subscription.To)
+	if producer == nil {
+		return fmt.Errorf("stage: producer %v not found", subscription.To)
+	}
+	up := &upstream{
+		ref:          xid.New().String(),
+		producer:     producer,
+		subscription: subscription,
+		pending:      subscription.MaxDemand,
+	}
+	s.producers[up.ref] = up
+	s.context.Monitor(producer)
+	actor.Send(producer, subscribe{Ref: up.ref, Consumer: s.context.Self(), Partition: subscription.Partition})
+	actor.Send(producer, ask{Ref: up.ref, Demand: up.pending})
+	return nil
+}
+
+// subscribed handles the subscription of a consumer
+func (s *stage) subscribed(msg subscribe) {
+	if s.dispatcher == nil {
+		actor.Send(msg.Consumer, cancel{Ref: msg.Ref, Reason: "not a producer"})
+		return
+	}
+	err := s.dispatcher.Subscribe(Subscriber{Ref: msg.Ref, PID: msg.Consumer, Partition: msg.Partition})
+	if err != nil {
+		actor.Send(msg.Consumer, cancel{Ref: msg.Ref, Reason: err.Error()})
+		return
+	}
+	s.consumers[msg.Ref] = msg.Consumer
+	s.context.Monitor(msg.Consumer)
+}
+
+// asked handles the demand of a consumer, the buffer goes first
+func (s *stage) asked(msg ask) {
+	if _, ok := s.consumers[msg.Ref]; !ok {
+		return
+	}
+	demand := s.dispatcher.Ask(msg.Ref, msg.Demand)
+	buffered := len(s.buffer)
+	s.buffer = s.dispatcher.Dispatch(s.buffer, s.send)
+	demand -= buffered - len(s.buffer)
+	if demand > 0 && s.demand != nil {
+		s.demand(s.context, demand)
+	}
+	s.askDeferred()
+}
+
+// received handles the events of a producer, asking for more once they've been handled
+func (s *stage) received(msg events) {
+	up, ok := s.producers[msg.Ref]
+	if !ok {
+		return
+	}
+	up.pending -= len(msg.Events)
+	if s.events != nil {
+		s.events(s.context, msg.Events)
+	}
+	s.askMore(up)
+}
+
+func (s *stage) askMore(up *upstream) {
+	if up.pending > up.subscription.MinDemand {
+		return
+	}
+	if s.dispatcher != nil && len(s.buffer) >= s.bufferSize/2 {
+		s.deferred[up.ref] = true
+		return
+	}
+	demand := up.subscription.MaxDemand - up.pending
+	up.pending = up.subscription.MaxDemand
+	actor.Send(up.producer, ask{Ref: up.ref, Demand: demand})
+}
+
+func (s *stage) askDeferred() {
+	if len(s.deferred) == 0 || len(s.buffer) >= s.bufferSize/2 {
+		return
+	}
+	for ref := range s.deferred {
+		delete(s.deferred, ref)
+		if up, ok := s.producers[ref]; ok {
+			s.askMore(up)
+		}
+	}
+}
+
+// cancelConsumer cancels the subscription of a consumer, demonitoring it if it's still there
+func (s *stage) cancelConsumer(ref string, demonitor bool) {
+	consumer := s.consumers[ref]
+	delete(s.consumers, ref)
+	s.dispatcher.Cancel(ref)
+	if demonitor {
+		s.context.Demonitor(consumer)
+	}
+}
+
+// cancelled handles the cancellation of a subscription to a producer, an empty reason meaning the producer has
+// gone normally. it returns false if the stage should stop normally, and panics if it should crash.
+func (s *stage) cancelled(up *upstream, reason string) bool {
+	delete(s.producers, up.ref)
+	delete(s.deferred, up.ref)
+	s.context.Demonitor(up.producer)
+	if s.cancelledFn != nil {
+		return s.cancelledFn(s.context, up.subscription, reason)
+	}
+	switch up.subscription.Cancel {
+	case CancelTemporary:
+		return true
+	case CancelTransient:
+		if reason == "" {
+			return true
+		}
+	}
+	if reason == "" {
+		return false
+	}
+	panic(fmt.Errorf("stage: subscription to %v cancelled: %s", up.subscription.To, reason))
+}
+
+// cancel cancels the subscriptions to the producer
+func (s *stage) cancel(producer *pid.ProtectedPID) {
+	for ref, up := range s.producers {
+		if pid.ExtractPID(up.producer) != pid.ExtractPID(producer) {
+			continue
+		}
+		delete(s.producers, ref)
+		delete(s.deferred, ref)
+		s.context.Demonitor(up.producer)
+		actor.Send(up.producer, cancel{Ref: ref})
+	}
+}
+
+// dispatch dispatches the events after the buffered ones, dropping the oldest beyond the buffer size
+func (s *stage) dispatch(events []interface{}) {
+	if s.dispatcher == nil {
+		log.Println("stage: consumers can't dispatch events")
+		return
+	}
+	s.buffer = s.dispatcher.Dispatch(append(s.buffer, events...), s.send)
+	if dropped := len(s.buffer) - s.bufferSize; dropped > 0 {
+		log.Printf("stage: buffer is full, dropped %d events\n", dropped)
+		s.buffer = append([]interface{}(nil), s.buffer[dropped:]...)
+	}
+}
+
+func (s *stage) send(subscriber Subscriber, batch []interface{}) {
+	// the batch might be a part of the buffer, which is appended to later
+	batch = append([]interface{}(nil), batch...)
+	actor.Send(subscriber.PID, events{Ref: subscriber.Ref, Events: batch})
+}
+
+// whereIs returns the pid of a producer given as a pid or a name
+func whereIs(to interface{}) *pid.ProtectedPID {
+	switch to := to.(type) {
+	case *pid.ProtectedPID:
+		return to
+	case string:
+		return actor.WhereIs(to)
+	case actor.Name:
+		return actor.WhereIsName(to)
+	default:
+		return nil
+	}
+}
@@ -0,0 +1,196 @@
+package stage
+
+import (
+	"github.com/hedisam/goactor/actor"
+	"github.com/hedisam/goactor/codec"
+	"github.com/hedisam/goactor/internal/pid"
+)
+
+const (
+	defaultMaxDemand  = 1000
+	defaultBufferSize = 10000
+)
+
+const (
+	// CancelPermanent stops the consumer when the subscription is cancelled, so its supervisor restarts it
+	// and it subscribes again
+	CancelPermanent int32 = iota
+	// CancelTransient stops the consumer unless the producer has exited normally
+	CancelTransient
+	// CancelTemporary keeps the consumer running
+	CancelTemporary
+)
+
+// Subscription describes how a consumer subscribes to a producer
+type Subscription struct {
+	// To is the producer, a pid or a name
+	To interface{}
+	// MaxDemand is the most events the consumer has asked for and not received at any time, default is 1000
+	MaxDemand int
+	// MinDemand is the demand left at which the consumer asks for more, default is three quarters of MaxDemand
+	MinDemand int
+	// Partition is the partition the consumer is after, for producers with a partition dispatcher
+	Partition interface{}
+	// Cancel decides what the consumer does when the subscription is cancelled, default is CancelPermanent
+	Cancel int32
+}
+
+// Producer describes a stage producing events on demand
+type Producer struct {
+	// Demand is called when the consumers ask for events the buffer can't satisfy, with the number of events
+	// asked for. the events are dispatched through the context, fewer of them or none at all is fine, the
+	// demand is kept and satisfied by the next events dispatched.
+	Demand func(c *Context, demand int)
+	// Handle handles the other messages of the stage, it's optional. returning false stops the stage.
+	Handle func(c *Context, message interface{}) (loop bool)
+	// Dispatcher returns a new dispatcher every time the producer starts, NewDemandDispatcher if nil
+	Dispatcher func() Dispatcher
+	// BufferSize is the most events kept while there's no demand, the oldest are dropped beyond it.
+	// default is 10000.
+	BufferSize int
+}
+
+// ProducerConsumer describes a stage consuming the events of its producers and producing events for its own
+// consumers. it only asks its producers for more while its buffer is less than half full.
+type ProducerConsumer struct {
+	SubscribeTo []Subscription
+	// Events handles the events received from a producer, dispatching events through the context
+	Events func(c *Context, events []interface{})
+	// Handle handles the other messages of the stage, it's optional. returning false stops the stage.
+	Handle func(c *Context, message interface{}) (loop bool)
+	// Cancelled is called instead of applying the cancel mode when a subscription is cancelled by its producer,
+	// an empty reason meaning the producer has exited normally. it's optional. returning false stops the stage.
+	Cancelled  func(c *Context, subscription Subscription, reason string) (loop bool)
+	Dispatcher func() Dispatcher
+	BufferSize int
+}
+
+// Consumer describes a stage consuming the events of its producers
+type Consumer struct {
+	SubscribeTo []Subscription
+	// Events handles the events received from a producer. more events are asked for once it's returned.
+	Events func(c *Context, events []interface{})
+	// Handle handles the other messages of the stage, it's optional. returning false stops the stage.
+	Handle func(c *Context, message interface{}) (loop bool)
+	// Cancelled is called instead of applying the cancel mode when a subscription is cancelled by its producer,
+	// an empty reason meaning the producer has exited normally. it's optional. returning false stops the stage.
+	Cancelled func(c *Context, subscription Subscription, reason string) (loop bool)
+}
+
+// Context is passed to a stage's callbacks, it's the stage's actor
+type Context struct {
+	*actor.Actor
+	stage *stage
+}
+
+// Dispatch dispatches the events to the stage's consumers, buffering the ones there's no demand for.
+// consumers can't dispatch.
+func (c *Context) Dispatch(events ...interface{}) {
+	c.stage.dispatch(events)
+}
+
+// Buffered returns the number of events waiting for demand
+func (c *Context) Buffered() int {
+	return len(c.stage.buffer)
+}
+
+// Subscribe subscribes the stage to a producer, it fails if the producer can't be found
+func (c *Context) Subscribe(subscription Subscription) error {
+	return c.stage.subscribe(subscription)
+}
+
+// Cancel cancels the stage's subscriptions to the producer, a pid or a name, whatever their cancel mode
+func (c *Context) Cancel(producer interface{}) {
+	if ppid := whereIs(producer); ppid != nil {
+		c.stage.cancel(ppid)
+	}
+}
+
+// Subscribe subscribes the consumer, or the producer consumer, to a producer
+func Subscribe(consumer *pid.ProtectedPID, subscription Subscription) {
+	actor.Send(consumer, subscribeTo{Subscription: subscription})
+}
+
+// NewProducer returns the actor func of a producer stage
+func NewProducer(config Producer) actor.Func {
+	return func(a *actor.Actor) {
+		s := newStage(a, dispatcherOrDefault(config.Dispatcher), config.BufferSize)
+		s.demand, s.handle = config.Demand, config.Handle
+		s.run(nil)
+	}
+}
+
+// NewProducerConsumer returns the actor func of a producer consumer stage
+func NewProducerConsumer(config ProducerConsumer) actor.Func {
+	return func(a *actor.Actor) {
+		s := newStage(a, dispatcherOrDefault(config.Dispatcher), config.BufferSize)
+		s.events, s.handle, s.cancelledFn = config.Events, config.Handle, config.Cancelled
+		s.run(config.SubscribeTo)
+	}
+}
+
+// NewConsumer returns the actor func of a consumer stage
+func NewConsumer(config Consumer) actor.Func {
+	return func(a *actor.Actor) {
+		s := newStage(a, nil, 0)
+		s.events, s.handle, s.cancelledFn = config.Events, config.Handle, config.Cancelled
+		s.run(config.SubscribeTo)
+	}
+}
+
+func newStage(a *actor.Actor, dispatcher Dispatcher, bufferSize int) *stage {
+	if bufferSize <= 0 {
+		bufferSize = defaultBufferSize
+	}
+	s := &stage{
+		bufferSize: bufferSize,
+		consumers:  make(map[string]*pid.ProtectedPID),
+		producers:  make(map[string]*upstream),
+		deferred:   make(map[string]bool),
+	}
+	s.context = &Context{Actor: a, stage: s}
+	s.dispatcher = dispatcher
+	return s
+}
+
+func dispatcherOrDefault(newDispatcher func() Dispatcher) Dispatcher {
+	if newDispatcher == nil {
+		return NewDemandDispatcher()
+	}
+	return newDispatcher()
+}
+
+// wire messages, exported fields so stages can subscribe across nodes
+
+type subscribe struct {
+	Ref       string
+	Consumer  *pid.ProtectedPID
+	Partition interface{}
+}
+
+type ask struct {
+	Ref    string
+	Demand int
+}
+
+type events struct {
+	Ref    string
+	Events []interface{}
+}
+
+type cancel struct {
+	Ref    string
+	Reason string
+}
+
+// subscribeTo tells a consumer to subscribe to a producer
+type subscribeTo struct {
+	Subscription Subscription
+}
+
+func init() {
+	codec.Register(subscribe{})
+	codec.Register(ask{})
+	codec.Register(events{})
+	codec.Register(cancel{})
+}
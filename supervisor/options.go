@@ -18,6 +18,17 @@ const (
 	RestForOneStrategy
 )
 
+const (
+	// children are registered in the default registry under their spec id
+	LocalNaming Naming = iota
+
+	// children are registered in a registry owned by the supervisor, see spec.SupRef's Names
+	ScopedNaming
+
+	// children are not registered
+	NoNaming
+)
+
 const (
 	defaultMaxRestarts int = 3
 	defaultPeriod      int = 5
@@ -25,11 +36,15 @@ const (
 
 type Strategy int32
 
+// Naming decides how children are registered. a child spec's Via overrides it.
+type Naming int32
+
 type Options struct {
 	Strategy    Strategy
 	MaxRestarts int
 	Period      int
 	Name        string
+	Naming      Naming
 }
 
 func OneForOneStrategyOption() Options {
@@ -59,6 +74,11 @@ func (opt Options) SetName(name string) Options {
 	return opt
 }
 
+func (opt Options) SetNaming(naming Naming) Options {
+	opt.Naming = naming
+	return opt
+}
+
 func (opt *Options) checkOptions() error {
 	if opt.Name == "" {
 		return fmt.Errorf("invalid supervisor Name: %s", opt.Name)
@@ -68,6 +88,8 @@ func (opt *Options) checkOptions() error {
 		return fmt.Errorf("invalid max seconds: %d", opt.Period)
 	} else if opt.MaxRestarts < 0 {
 		return fmt.Errorf("invalid max restarts: %d", opt.MaxRestarts)
+	} else if opt.Naming < LocalNaming || opt.Naming > NoNaming {
+		return fmt.Errorf("invalid Naming: %d", opt.Naming)
 	}
 
 	return nil
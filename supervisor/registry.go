@@ -57,6 +57,11 @@ func (r *registry) put(_pid pid.PID, id string) {
 	r.timeTracer[id] = append(restarts, time.Now().Unix())
 }
 
+// failed records a restart that failed to spawn the actor
+func (r *registry) failed(id string) {
+	r.timeTracer[id] = append(r.timeTracer[id], time.Now().Unix())
+}
+
 // dead declares an actor dead by its pid
 func (r *registry) dead(_pid pid.PID) {
 	id, found := r.aliveActors[_pid]
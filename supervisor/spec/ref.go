@@ -7,6 +7,8 @@ import (
 
 type SupRef struct {
 	PPID *pid.ProtectedPID
+	// Names resolves the supervisor's children by their id when the supervisor uses scoped naming, nil otherwise
+	Names actor.Resolver
 }
 
 func (r *SupRef) CountChildren() (count CountChildren, err error) {
@@ -60,7 +62,7 @@ func (r *SupRef) WithChildren() (childrenInfo WithChildren, err error) {
 
 func (r *SupRef) call(request interface{}) (interface{}, error) {
 	future := actor.NewFutureActor()
-	future.Send(r.PPID, request)
+	future.Send(r.PPID, Call{Sender: future.Self(), Request: request})
 	result, err := future.Recv()
 	if err != nil {
 		return nil, err
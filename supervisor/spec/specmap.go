@@ -2,6 +2,7 @@ package spec
 
 import (
 	"fmt"
+	"github.com/hedisam/goactor/actor"
 )
 
 type SpecsMap map[string]Spec
@@ -55,6 +56,17 @@ func (sm SpecsMap) SupervisorChildren(name string) []Spec {
 	}
 }
 
+func (sm SpecsMap) Via(name string) actor.Resolver {
+	switch spec := sm[name].(type) {
+	case WorkerSpec:
+		return spec.Via
+	case SupervisorSpec:
+		return spec.Via
+	default:
+		return nil
+	}
+}
+
 func (sm SpecsMap) Type(name string) ChildType {
 	switch spec := sm[name].(type) {
 	case SupervisorSpec:
@@ -92,9 +104,15 @@ func ToMap(specs ...Spec) (specsMap SpecsMap, err error) {
 			} else if spc.Shutdown < ShutdownInfinity {
 				err = fmt.Errorf("invalid childspec's shutdown value: %v, id %s", spc.Shutdown, spc.Id)
 				return
-			} else if spc.Start.ActorFunc == nil {
+			} else if spc.Start.Node == "" && spc.Start.ActorFunc == nil {
 				err = fmt.Errorf("childspec's fn (actor.Func(actor.Actor)) could not be nil, id %s", spc.Id)
 				return
+			} else if spc.Start.Node != "" && spc.Start.FuncName == "" {
+				err = fmt.Errorf("remote childspec's function name could not be empty, id %s", spc.Id)
+				return
+			} else if spc.Start.Node != "" && spc.Start.Journal != nil {
+				err = fmt.Errorf("remote childspec could not have a mailbox journal, id %s", spc.Id)
+				return
 			}
 			specsMap[spc.Id] = s.ChildSpec()
 		case SupervisorSpec:
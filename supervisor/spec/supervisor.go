@@ -1,6 +1,7 @@
 package spec
 
 import (
+	"github.com/hedisam/goactor/actor"
 	"github.com/rs/xid"
 )
 
@@ -12,6 +13,9 @@ type SupervisorSpec struct {
 	StartLink StartLink
 	Restart   int32
 	Shutdown  int32
+	// Via is the resolver the supervisor is registered through under its Id.
+	// nil means the parent supervisor's naming option decides.
+	Via       actor.Resolver
 }
 
 func NewSupervisorSpec(start StartLink, childSpecs ...Spec) SupervisorSpec {
@@ -43,6 +47,11 @@ func (sup SupervisorSpec) SetShutdown(shutdown int32) SupervisorSpec {
 	return sup
 }
 
+func (sup SupervisorSpec) SetVia(via actor.Resolver) SupervisorSpec {
+	sup.Via = via
+	return sup
+}
+
 func (sup SupervisorSpec) Type() ChildType {
 	return TypeSupervisor
 }
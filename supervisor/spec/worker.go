@@ -9,11 +9,21 @@ type WorkerSpec struct {
 	Start     WorkerStartSpec
 	Restart   int32
 	Shutdown  int32
+	// Via is the resolver the worker is registered through under its Id.
+	// nil means the supervisor's naming option decides.
+	Via       actor.Resolver
 }
 
 type WorkerStartSpec struct {
 	ActorFunc actor.Func
 	Args      []interface{}
+	// Node is the node the worker is spawned on, empty for the local node.
+	// remote workers are spawned by FuncName from the node's spawn table, see actor.RegisterFunc.
+	Node     string
+	FuncName string
+	// Journal journals the mailbox of a local worker, the messages a crashed worker has left unhandled are fed to
+	// the restarted one first. nil means a plain mailbox, which remote workers are limited to.
+	Journal actor.MailboxJournal
 }
 
 func NewWorkerSpec(name string, fn actor.Func, args ...interface{}) WorkerSpec {
@@ -25,6 +35,14 @@ func NewWorkerSpec(name string, fn actor.Func, args ...interface{}) WorkerSpec {
 	}
 }
 
+// NewRemoteWorkerSpec returns the spec of a worker spawned on another node by its registered function name
+func NewRemoteWorkerSpec(name string, node string, funcName string, args ...interface{}) WorkerSpec {
+	return WorkerSpec{
+		Id:    name,
+		Start: WorkerStartSpec{Args: args, Node: node, FuncName: funcName},
+	}
+}
+
 func (w WorkerSpec) ChildSpec() Spec {
 	return w
 }
@@ -39,6 +57,17 @@ func (w WorkerSpec) SetShutdown(shutdown int32) WorkerSpec {
 	return w
 }
 
+func (w WorkerSpec) SetVia(via actor.Resolver) WorkerSpec {
+	w.Via = via
+	return w
+}
+
+// SetJournal makes every incarnation of the worker use the journal for its mailbox
+func (w WorkerSpec) SetJournal(journal actor.MailboxJournal) WorkerSpec {
+	w.Start.Journal = journal
+	return w
+}
+
 func (w WorkerSpec) Type() ChildType {
 	return TypeWorker
 }
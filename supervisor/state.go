@@ -7,24 +7,43 @@ import (
 	"github.com/hedisam/goactor/supervisor/spec"
 	"github.com/hedisam/goactor/sysmsg"
 	"log"
+	"time"
 )
 
 type state struct {
 	specs      spec.SpecsMap
 	options    *Options
 	registry   *registry
+	// names is the supervisor's own registry when using scoped naming
+	names      *actor.Registry
 	supervisor *actor.Actor
 }
 
-func newState(specs spec.SpecsMap, options *Options, supervisor *actor.Actor) *state {
+func newState(specs spec.SpecsMap, options *Options, names *actor.Registry, supervisor *actor.Actor) *state {
 	return &state{
 		specs: specs,
 		options: options,
 		registry: newRegistry(options),
+		names: names,
 		supervisor: supervisor,
 	}
 }
 
+// resolver returns the resolver the child is registered through
+func (state *state) resolver(name string) actor.Resolver {
+	if via := state.specs.Via(name); via != nil {
+		return via
+	}
+	switch state.options.Naming {
+	case ScopedNaming:
+		return state.names
+	case NoNaming:
+		return actor.Unregistered
+	default:
+		return actor.DefaultRegistry()
+	}
+}
+
 func (state *state) shutdown(name string, _pid pid.PID) {
 	// note: do not call deadAndUnlink if we're supposed to receive shutdown feedback
 	state.deadAndUnlink(_pid)
@@ -66,7 +85,20 @@ func (state *state) spawn(name string) error {
 	switch state.specs.Type(name) {
 	case spec.TypeWorker:
 		start := state.specs.WorkerStartSpec(name)
-		ppid = state.supervisor.SpawnLink(start.ActorFunc, start.Args...)
+		if start.Journal != nil {
+			ppid = state.supervisor.SpawnLinkJournaled(start.Journal, start.ActorFunc, start.Args...)
+			break
+		}
+		if start.Node == "" {
+			ppid = state.supervisor.SpawnLink(start.ActorFunc, start.Args...)
+			break
+		}
+		var err error
+		ppid, err = state.supervisor.SpawnLinkOn(start.Node, start.FuncName, start.Args...)
+		if err != nil {
+			log.Println("supervisor: could not spawn remote child:", err)
+			return err
+		}
 	case spec.TypeSupervisor:
 		startLink := state.specs.SupervisorStartLink(name)
 		supRef, err := startLink(state.specs.SupervisorChildren(name)...)
@@ -84,11 +116,26 @@ func (state *state) spawn(name string) error {
 
 	// register locally
 	state.registry.put(_pid, name)
-	// register globally
-	actor.Register(name, ppid)
+	// register through the child's name resolver
+	if err := state.resolver(name).RegisterName(name, ppid); err != nil {
+		log.Println("supervisor: could not register child:", err)
+	}
 	return nil
 }
 
+// restart spawns the child again, trying later if it can't be spawned. the failed attempts count as restarts,
+// so a child that keeps failing to spawn takes the supervisor down once it's reached the max restarts.
+func (state *state) restart(name string) {
+	if err := state.spawn(name); err == nil {
+		return
+	}
+	state.registry.failed(name)
+	self := state.supervisor.Self()
+	time.AfterFunc(respawnInterval, func() {
+		actor.Send(self, respawn{id: name})
+	})
+}
+
 func (state *state) init() (err error) {
 	for id := range state.specs {
 		err = state.spawn(id)
@@ -111,7 +158,7 @@ func (state *state) handleOneForAll(name string) {
 		} else {
 			state.shutdown(id, _pid)
 		}
-		_ = state.spawn(id)
+		state.restart(id)
 	}
 }
 
@@ -119,7 +166,7 @@ func (state *state) handleOneForOne(name string, _pid pid.PID) {
 	// we need to unlink the terminated actor and declare it dead
 	state.deadAndUnlink(_pid)
 	// re-spawn
-	_ = state.spawn(name)
+	state.restart(name)
 }
 
 func (state *state) handleRestForOne(name string) {
@@ -127,6 +174,14 @@ func (state *state) handleRestForOne(name string) {
 }
 
 func (state *state) deadAndUnlink(_pid pid.PID) {
+	// release the child's name right away so its next incarnation can take it, the registry would only
+	// remove it after being notified of the termination
+	if name, dead, found := state.registry.id(_pid); found && !dead {
+		resolver := state.resolver(name)
+		if registered := resolver.WhereIsName(name); registered != nil && pid.ExtractPID(registered) == _pid {
+			resolver.UnregisterName(name)
+		}
+	}
 	state.registry.dead(_pid)
 	state.supervisor.Unlink(pid.NewProtectedPID(_pid))
 }
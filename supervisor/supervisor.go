@@ -6,10 +6,17 @@ import (
 	"github.com/hedisam/goactor/supervisor/spec"
 	"github.com/hedisam/goactor/sysmsg"
 	"log"
+	"time"
 )
 
+// respawnInterval is how long the supervisor waits before trying again to spawn a child it couldn't restart
+const respawnInterval = 500 * time.Millisecond
+
 type initMsg struct {sender *pid.ProtectedPID}
 
+// respawn tells the supervisor to try again to restart the child
+type respawn struct {id string}
+
 func Start(options Options, specs ...spec.Spec) (*spec.SupRef, error) {
 	specsMap, err := spec.ToMap(specs...)
 	if err != nil {
@@ -19,8 +26,14 @@ func Start(options Options, specs ...spec.Spec) (*spec.SupRef, error) {
 	err = options.checkOptions()
 	if err != nil {return nil, err}
 
+	// a scoped naming supervisor registers its children in its own registry
+	var names *actor.Registry
+	if options.Naming == ScopedNaming {
+		names = actor.NewRegistry(actor.UniqueKeys, 1)
+	}
+
 	// spawn supervisor actor passing spec data and options as arguments
-	suPID := actor.Spawn(supervisor, specsMap, &options)
+	suPID := actor.Spawn(supervisor, specsMap, &options, names)
 	// declare the new spawned actor as a supervisor actor
 	setActorType := pid.ExtractPID(suPID).ActorTypeFn()
 	setActorType(actor.SupervisorActor)
@@ -32,7 +45,11 @@ func Start(options Options, specs ...spec.Spec) (*spec.SupRef, error) {
 	if err != nil {return nil, err}
 	if initErr != nil {return nil, initErr.(error)}
 
-	return &spec.SupRef{PPID: suPID}, nil
+	ref := &spec.SupRef{PPID: suPID}
+	if names != nil {
+		ref.Names = names
+	}
+	return ref, nil
 }
 
 func supervisor(supervisor *actor.Actor) {
@@ -41,7 +58,11 @@ func supervisor(supervisor *actor.Actor) {
 
 	specs := supervisor.Args()[0].(spec.SpecsMap)
 	options := supervisor.Args()[1].(*Options)
-	state := newState(specs, options, supervisor)
+	names := supervisor.Args()[2].(*actor.Registry)
+	if names != nil {
+		defer names.Close()
+	}
+	state := newState(specs, options, names, supervisor)
 
 	supervisor.Receive(func(message interface{}) (loop bool) {
 		switch msg := message.(type) {
@@ -50,7 +71,9 @@ func supervisor(supervisor *actor.Actor) {
 			actor.Send(msg.sender, err)
 		case sysmsg.Exit:
 			switch msg.Reason.Type {
-			case sysmsg.Panic, sysmsg.SupMaxRestart:
+			// a remote child is gone as well when the connection to its node is lost, or if it had terminated
+			// before being linked
+			case sysmsg.Panic, sysmsg.SupMaxRestart, sysmsg.NoConnection, sysmsg.NoProc:
 				name, dead, found := state.registry.id(msg.Who.(pid.PID))
 				if dead || !found {
 					return true
@@ -76,6 +99,13 @@ func supervisor(supervisor *actor.Actor) {
 					state.deadAndUnlink(msg.Who.(pid.PID))
 				}
 			}
+		case respawn:
+			if _, exists := state.specs[msg.id]; !exists {
+				return true
+			}
+			if _, alive := state.registry.alivePID(msg.id); !alive {
+				state.restart(msg.id)
+			}
 		case sysmsg.Shutdown:
 			// parent supervisor wants us to shutdown
 			state.shutdownSupervisor(sysmsg.Reason{
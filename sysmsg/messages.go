@@ -51,3 +51,32 @@ type Timeout struct {
 }
 
 func (t Timeout) systemMessage() {}
+
+// NodeUp is sent to the node monitors when a connection to the node is established
+type NodeUp struct {
+	Node string
+}
+
+func (n NodeUp) systemMessage() {}
+
+// NodeDown is sent to the node monitors when the connection to the node is lost or can't be established
+type NodeDown struct {
+	Node   string
+	Reason string
+}
+
+func (n NodeDown) systemMessage() {}
+
+// MemberJoined is sent to the cluster subscribers when discovery finds a new member node
+type MemberJoined struct {
+	Node string
+}
+
+func (m MemberJoined) systemMessage() {}
+
+// MemberLeft is sent to the cluster subscribers when a member node is no longer discovered
+type MemberLeft struct {
+	Node string
+}
+
+func (m MemberLeft) systemMessage() {}
@@ -15,6 +15,10 @@ const (
 	Panic         = "panic"
 	Normal        = "normal"
 	SupMaxRestart = "sup_reached_max_restarts"
+	// NoProc is the reason sent to monitors/linkers of an actor that had already terminated
+	NoProc        = "noproc"
+	// NoConnection is the reason sent to remote monitors/linkers when the connection to the actor's node is lost
+	NoConnection  = "noconnection"
 )
 
 type Relation string
@@ -0,0 +1,159 @@
+package throttle
+
+import (
+	"errors"
+	"fmt"
+	"github.com/hedisam/goactor/actor"
+	"github.com/hedisam/goactor/internal/pid"
+	"sync"
+	"time"
+)
+
+const (
+	defaultInterval   = time.Second
+	defaultQueueLimit = 1000
+)
+
+// ErrRejected is returned by Send when the queue is full and the overflow policy is Reject
+var ErrRejected = errors.New("throttle: queue is full")
+
+// Algorithm decides how bursts are handled
+type Algorithm int32
+
+const (
+	// TokenBucket lets bursts of up to Burst messages through at once, as long as the average stays at the rate
+	TokenBucket Algorithm = iota
+	// LeakyBucket forwards the messages at a steady pace, one every Interval/Rate
+	LeakyBucket
+)
+
+// Overflow decides what happens to a message when the queue is full
+type Overflow int32
+
+const (
+	// DropNewest drops the message
+	DropNewest Overflow = iota
+	// DropOldest drops the oldest queued message to make room for the message
+	DropOldest
+	// Reject makes Send fail with ErrRejected. messages sent to the throttler's pid directly are dropped.
+	Reject
+)
+
+// Config describes a throttler
+type Config struct {
+	// Target is the actor the messages are forwarded to, a pid or a name
+	Target interface{}
+	// Rate is the number of messages forwarded per Interval
+	Rate     int
+	Interval time.Duration
+	// Burst is the capacity of the token bucket, default is Rate. it's 1 for the leaky bucket.
+	Burst     int
+	Algorithm Algorithm
+	// QueueLimit is the most messages waiting to be forwarded, default is 1000
+	QueueLimit int
+	Overflow   Overflow
+}
+
+// Metrics are the throttler's counters since it's started
+type Metrics struct {
+	Forwarded uint64
+	// Delayed is the number of messages that had to wait in the queue
+	Delayed  uint64
+	Dropped  uint64
+	Rejected uint64
+	// Queued is the number of messages waiting to be forwarded
+	Queued   int
+	Rate     int
+	Interval time.Duration
+}
+
+// Throttler forwards the messages sent to it to its target, at most Rate of them per Interval
+type Throttler struct {
+	config Config
+	self   *pid.ProtectedPID
+
+	mu      sync.Mutex
+	metrics Metrics
+	// sent through Send and not queued by the actor yet
+	inFlight int
+}
+
+// cmdTick tells the throttler a token might be available
+type cmdTick struct{}
+
+type cmdStop struct{}
+
+type setRate struct {
+	rate     int
+	interval time.Duration
+}
+
+// throttled is a message sent through Send
+type throttled struct {
+	message interface{}
+}
+
+// Start spawns the throttler
+func Start(config Config) (*Throttler, error) {
+	if config.Target == nil {
+		return nil, fmt.Errorf("throttle: target is required")
+	}
+	if config.Rate <= 0 {
+		return nil, fmt.Errorf("throttle: rate must be positive")
+	}
+	if config.Interval <= 0 {
+		config.Interval = defaultInterval
+	}
+	if config.QueueLimit <= 0 {
+		config.QueueLimit = defaultQueueLimit
+	}
+	t := &Throttler{config: config}
+	t.metrics.Rate, t.metrics.Interval = config.Rate, config.Interval
+	t.self = actor.Spawn(throttler, t)
+	return t, nil
+}
+
+// Send queues the message to be forwarded. it fails with ErrRejected if the queue is full and the overflow
+// policy is Reject, a dropped message isn't an error.
+func (t *Throttler) Send(message interface{}) error {
+	t.mu.Lock()
+	if t.config.Overflow != DropOldest && t.metrics.Queued+t.inFlight >= t.config.QueueLimit {
+		defer t.mu.Unlock()
+		if t.config.Overflow == Reject {
+			t.metrics.Rejected++
+			return ErrRejected
+		}
+		t.metrics.Dropped++
+		return nil
+	}
+	t.inFlight++
+	t.mu.Unlock()
+	actor.Send(t.self, throttled{message: message})
+	return nil
+}
+
+// PID returns the throttler's pid. messages sent to it directly are throttled as well.
+func (t *Throttler) PID() *pid.ProtectedPID {
+	return t.self
+}
+
+// SetRate changes the rate, a zero interval keeps the current one
+func (t *Throttler) SetRate(rate int, interval time.Duration) error {
+	if rate <= 0 {
+		return fmt.Errorf("throttle: rate must be positive")
+	}
+	actor.Send(t.self, setRate{rate: rate, interval: interval})
+	return nil
+}
+
+// Metrics returns the current metrics
+func (t *Throttler) Metrics() Metrics {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.metrics
+}
+
+// Stop stops the throttler, the queued messages are dropped
+func (t *Throttler) Stop() {
+	actor.Send(t.self, cmdStop{})
+}
@@ -0,0 +1,160 @@
+package throttle
+
+import (
+	"github.com/hedisam/goactor/actor"
+	"github.com/hedisam/goactor/internal/pid"
+	"time"
+)
+
+// bucket is a token bucket, the leaky bucket being a token bucket that holds a single token
+type bucket struct {
+	rate     int
+	interval time.Duration
+	capacity float64
+	tokens   float64
+	last     time.Time
+}
+
+func newBucket(config Config) *bucket {
+	b := &bucket{last: time.Now()}
+	b.set(config.Algorithm, config.Burst, config.Rate, config.Interval)
+	b.tokens = b.capacity
+	return b
+}
+
+func (b *bucket) set(algorithm Algorithm, burst, rate int, interval time.Duration) {
+	b.refill()
+	b.rate, b.interval = rate, interval
+	switch {
+	case algorithm == LeakyBucket:
+		b.capacity = 1
+	case burst > 0:
+		b.capacity = float64(burst)
+	default:
+		b.capacity = float64(rate)
+	}
+	if b.tokens > b.capacity {
+		b.tokens = b.capacity
+	}
+}
+
+func (b *bucket) refill() {
+	now := time.Now()
+	if b.interval > 0 {
+		b.tokens += float64(now.Sub(b.last)) * float64(b.rate) / float64(b.interval)
+		if b.tokens > b.capacity {
+			b.tokens = b.capacity
+		}
+	}
+	b.last = now
+}
+
+// take takes a token if there's one
+func (b *bucket) take() bool {
+	b.refill()
+	if b.tokens < 1 {
+		return false
+	}
+	b.tokens--
+	return true
+}
+
+// wait returns how long until the next token
+func (b *bucket) wait() time.Duration {
+	missing := 1 - b.tokens
+	if missing <= 0 {
+		return 0
+	}
+	return time.Duration(missing * float64(b.interval) / float64(b.rate))
+}
+
+func throttler(a *actor.Actor) {
+	t := a.Args()[0].(*Throttler)
+	b := newBucket(t.config)
+	var queue []interface{}
+	// a tick is on its way
+	scheduled := false
+
+	forward := func() {
+		forwarded := 0
+		for len(queue) > 0 && b.take() {
+			send(t.config.Target, queue[0])
+			queue[0] = nil
+			queue = queue[1:]
+			forwarded++
+		}
+		if forwarded > 0 {
+			t.mu.Lock()
+			t.metrics.Forwarded += uint64(forwarded)
+			t.metrics.Queued = len(queue)
+			t.mu.Unlock()
+		}
+		if len(queue) > 0 && !scheduled {
+			scheduled = true
+			self := a.Self()
+			time.AfterFunc(b.wait(), func() {
+				actor.Send(self, cmdTick{})
+			})
+		}
+	}
+
+	enqueue := func(message interface{}, viaSend bool) {
+		t.mu.Lock()
+		if viaSend {
+			t.inFlight--
+		}
+		if len(queue) == 0 && b.take() {
+			t.metrics.Forwarded++
+			t.mu.Unlock()
+			send(t.config.Target, message)
+			return
+		}
+		if len(queue) >= t.config.QueueLimit {
+			t.metrics.Dropped++
+			if t.config.Overflow != DropOldest {
+				t.mu.Unlock()
+				return
+			}
+			queue[0] = nil
+			queue = queue[1:]
+		}
+		queue = append(queue, message)
+		t.metrics.Delayed++
+		t.metrics.Queued = len(queue)
+		t.mu.Unlock()
+		forward()
+	}
+
+	a.Receive(func(message interface{}) (loop bool) {
+		switch msg := message.(type) {
+		case throttled:
+			enqueue(msg.message, true)
+		case cmdTick:
+			scheduled = false
+			forward()
+		case setRate:
+			interval := msg.interval
+			if interval <= 0 {
+				interval = b.interval
+			}
+			b.set(t.config.Algorithm, t.config.Burst, msg.rate, interval)
+			t.mu.Lock()
+			t.metrics.Rate, t.metrics.Interval = msg.rate, interval
+			t.mu.Unlock()
+			forward()
+		case cmdStop:
+			return false
+		default:
+			enqueue(message, false)
+		}
+		return true
+	})
+}
+
+func send(target interface{}, message interface{}) {
+	if ppid, ok := target.(*pid.ProtectedPID); ok {
+		actor.Send(ppid, message)
+		return
+	}
+	actor.SendNamed(target, message)
+}